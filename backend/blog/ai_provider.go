@@ -0,0 +1,351 @@
+package blog
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AIProviderOpenAICompatible = "openai-compatible"
+	AIProviderAnthropic        = "anthropic"
+	AIProviderOllama           = "ollama"
+	AIProviderGemini           = "gemini"
+)
+
+// AIStatusError is a non-2xx answer from the provider. RetryAfter is set
+// when the provider asked for a delay (usually with 429).
+type AIStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *AIStatusError) Error() string {
+	return fmt.Sprintf("ai provider returned status %d", e.StatusCode)
+}
+
+// AIConfigError means the provider cannot be used as configured; retrying
+// will not help until the configuration changes.
+type AIConfigError struct {
+	Message string
+}
+
+func (e *AIConfigError) Error() string {
+	return e.Message
+}
+
+func parseAIRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// defaultAIMaxOutputTokens bounds the completion for APIs that require an
+// explicit limit (Anthropic); summaries are far shorter than this.
+const defaultAIMaxOutputTokens = 1024
+
+// AIRequest is one summarization call: Prompt is the system instruction,
+// Title and Content the (already clamped) article.
+type AIRequest struct {
+	Prompt  string
+	Title   string
+	Content string
+	// MaxTokens raises the output limit for APIs that require one; 0 uses
+	// defaultAIMaxOutputTokens.
+	MaxTokens int
+}
+
+type AIUsage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+type AIResult struct {
+	Text  string
+	Usage AIUsage
+}
+
+// AIProvider turns an article into a summary using one vendor's wire format.
+type AIProvider interface {
+	Name() string
+	Summarize(ctx context.Context, req AIRequest) (AIResult, error)
+}
+
+// NewAIProvider builds the adapter selected by AI_SUMMARY_PROVIDER. An empty
+// baseURL falls back to the vendor's public endpoint where there is one.
+func NewAIProvider(name, baseURL, apiKey, model string, client *http.Client) (AIProvider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if model == "" {
+		return nil, &AIConfigError{Message: "ai summary provider is not fully configured: model is empty"}
+	}
+
+	switch strings.TrimSpace(name) {
+	case "", AIProviderOpenAICompatible:
+		if baseURL == "" || apiKey == "" {
+			return nil, &AIConfigError{Message: "ai summary provider is not fully configured"}
+		}
+		return &openAICompatibleProvider{baseURL: baseURL, apiKey: apiKey, model: model, client: client}, nil
+	case AIProviderAnthropic:
+		if apiKey == "" {
+			return nil, &AIConfigError{Message: "ai summary provider is not fully configured: api key is empty"}
+		}
+		if baseURL == "" {
+			baseURL = "https://api.anthropic.com"
+		}
+		return &anthropicProvider{baseURL: strings.TrimSuffix(baseURL, "/v1"), apiKey: apiKey, model: model, client: client}, nil
+	case AIProviderOllama:
+		if baseURL == "" {
+			baseURL = "http://localhost:11434"
+		}
+		return &ollamaProvider{baseURL: strings.TrimSuffix(baseURL, "/api"), apiKey: apiKey, model: model, client: client}, nil
+	case AIProviderGemini:
+		if apiKey == "" {
+			return nil, &AIConfigError{Message: "ai summary provider is not fully configured: api key is empty"}
+		}
+		if baseURL == "" {
+			baseURL = "https://generativelanguage.googleapis.com"
+		}
+		return &geminiProvider{baseURL: strings.TrimSuffix(baseURL, "/v1beta"), apiKey: apiKey, model: model, client: client}, nil
+	}
+	return nil, &AIConfigError{Message: fmt.Sprintf("unsupported ai summary provider: %s", name)}
+}
+
+// postJSON sends body as JSON and decodes a 2xx response into out.
+func postJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		statusErr := &AIStatusError{StatusCode: resp.StatusCode}
+		if d, ok := parseAIRetryAfter(resp.Header.Get("Retry-After")); ok {
+			statusErr.RetryAfter = d
+		}
+		return statusErr
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type openAICompatibleProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func (p *openAICompatibleProvider) Name() string { return AIProviderOpenAICompatible }
+
+func (p *openAICompatibleProvider) Summarize(ctx context.Context, req AIRequest) (AIResult, error) {
+	body := map[string]any{
+		"model": p.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": req.Prompt},
+			{"role": "user", "content": buildAISummaryInput(req.Title, req.Content)},
+		},
+	}
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + p.apiKey}
+	if err := postJSON(ctx, p.client, p.baseURL+"/chat/completions", headers, body, &result); err != nil {
+		return AIResult{}, err
+	}
+	if len(result.Choices) == 0 {
+		return AIResult{}, fmt.Errorf("ai provider returned no choices")
+	}
+	return AIResult{
+		Text:  strings.TrimSpace(result.Choices[0].Message.Content),
+		Usage: AIUsage{InputTokens: result.Usage.PromptTokens, OutputTokens: result.Usage.CompletionTokens},
+	}, nil
+}
+
+// anthropicProvider speaks the Anthropic Messages API.
+type anthropicProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func (p *anthropicProvider) Name() string { return AIProviderAnthropic }
+
+func (p *anthropicProvider) Summarize(ctx context.Context, req AIRequest) (AIResult, error) {
+	body := map[string]any{
+		"model":      p.model,
+		"max_tokens": cmp.Or(req.MaxTokens, defaultAIMaxOutputTokens),
+		"system":     req.Prompt,
+		"messages": []map[string]string{
+			{"role": "user", "content": buildAISummaryInput(req.Title, req.Content)},
+		},
+	}
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": "2023-06-01",
+	}
+	if err := postJSON(ctx, p.client, p.baseURL+"/v1/messages", headers, body, &result); err != nil {
+		return AIResult{}, err
+	}
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return AIResult{}, fmt.Errorf("ai provider returned no text content")
+	}
+	return AIResult{
+		Text:  strings.TrimSpace(text.String()),
+		Usage: AIUsage{InputTokens: result.Usage.InputTokens, OutputTokens: result.Usage.OutputTokens},
+	}, nil
+}
+
+// ollamaProvider speaks Ollama's native /api/chat. The API key is optional
+// and only sent when a reverse proxy in front of Ollama asks for one.
+type ollamaProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func (p *ollamaProvider) Name() string { return AIProviderOllama }
+
+func (p *ollamaProvider) Summarize(ctx context.Context, req AIRequest) (AIResult, error) {
+	body := map[string]any{
+		"model":  p.model,
+		"stream": false,
+		"messages": []map[string]string{
+			{"role": "system", "content": req.Prompt},
+			{"role": "user", "content": buildAISummaryInput(req.Title, req.Content)},
+		},
+	}
+	var result struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		PromptEvalCount int `json:"prompt_eval_count"`
+		EvalCount       int `json:"eval_count"`
+	}
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	if err := postJSON(ctx, p.client, p.baseURL+"/api/chat", headers, body, &result); err != nil {
+		return AIResult{}, err
+	}
+	text := strings.TrimSpace(result.Message.Content)
+	if text == "" {
+		return AIResult{}, fmt.Errorf("ai provider returned no text content")
+	}
+	return AIResult{
+		Text:  text,
+		Usage: AIUsage{InputTokens: result.PromptEvalCount, OutputTokens: result.EvalCount},
+	}, nil
+}
+
+// geminiProvider speaks the Gemini generateContent API.
+type geminiProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func (p *geminiProvider) Name() string { return AIProviderGemini }
+
+func (p *geminiProvider) Summarize(ctx context.Context, req AIRequest) (AIResult, error) {
+	type part struct {
+		Text string `json:"text"`
+	}
+	type content struct {
+		Role  string `json:"role,omitempty"`
+		Parts []part `json:"parts"`
+	}
+	body := map[string]any{
+		"systemInstruction": content{Parts: []part{{Text: req.Prompt}}},
+		"contents": []content{
+			{Role: "user", Parts: []part{{Text: buildAISummaryInput(req.Title, req.Content)}}},
+		},
+	}
+	var result struct {
+		Candidates []struct {
+			Content content `json:"content"`
+		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+	}
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:generateContent", p.baseURL, url.PathEscape(p.model))
+	headers := map[string]string{"x-goog-api-key": p.apiKey}
+	if err := postJSON(ctx, p.client, endpoint, headers, body, &result); err != nil {
+		return AIResult{}, err
+	}
+	if len(result.Candidates) == 0 {
+		return AIResult{}, fmt.Errorf("ai provider returned no candidates")
+	}
+	var text strings.Builder
+	for _, p := range result.Candidates[0].Content.Parts {
+		text.WriteString(p.Text)
+	}
+	if text.Len() == 0 {
+		return AIResult{}, fmt.Errorf("ai provider returned no text content")
+	}
+	return AIResult{
+		Text:  strings.TrimSpace(text.String()),
+		Usage: AIUsage{InputTokens: result.UsageMetadata.PromptTokenCount, OutputTokens: result.UsageMetadata.CandidatesTokenCount},
+	}, nil
+}
@@ -0,0 +1,178 @@
+package blog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type capturedAIRequest struct {
+	path    string
+	headers http.Header
+	body    map[string]any
+}
+
+func newAIStandIn(t *testing.T, response string) (*httptest.Server, chan capturedAIRequest) {
+	t.Helper()
+	captured := make(chan capturedAIRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		captured <- capturedAIRequest{path: r.URL.Path, headers: r.Header.Clone(), body: body}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, captured
+}
+
+var testAIRequest = AIRequest{Prompt: "be brief", Title: "My Article", Content: "Body text"}
+
+func TestOpenAICompatibleProvider(t *testing.T) {
+	server, captured := newAIStandIn(t, `{"choices":[{"message":{"content":" summary "}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`)
+
+	provider, err := NewAIProvider(AIProviderOpenAICompatible, server.URL+"/v1", "key", "gpt", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := provider.Summarize(context.Background(), testAIRequest)
+	if err != nil {
+		t.Fatalf("summarize failed: %v", err)
+	}
+	if result.Text != "summary" || result.Usage != (AIUsage{InputTokens: 12, OutputTokens: 3}) {
+		t.Fatalf("unexpected result %#v", result)
+	}
+
+	req := <-captured
+	if req.path != "/v1/chat/completions" {
+		t.Fatalf("unexpected path %q", req.path)
+	}
+	if got := req.headers.Get("Authorization"); got != "Bearer key" {
+		t.Fatalf("unexpected auth header %q", got)
+	}
+	messages := req.body["messages"].([]any)
+	if messages[0].(map[string]any)["content"] != "be brief" {
+		t.Fatalf("expected prompt as system message, got %v", messages[0])
+	}
+	if !strings.Contains(messages[1].(map[string]any)["content"].(string), "Title: My Article") {
+		t.Fatalf("expected title in user message, got %v", messages[1])
+	}
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	server, captured := newAIStandIn(t, `{"content":[{"type":"text","text":"Short "},{"type":"text","text":"summary"}],"usage":{"input_tokens":40,"output_tokens":6}}`)
+
+	provider, err := NewAIProvider(AIProviderAnthropic, server.URL, "key", "claude-model", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := provider.Summarize(context.Background(), testAIRequest)
+	if err != nil {
+		t.Fatalf("summarize failed: %v", err)
+	}
+	if result.Text != "Short summary" || result.Usage != (AIUsage{InputTokens: 40, OutputTokens: 6}) {
+		t.Fatalf("unexpected result %#v", result)
+	}
+
+	req := <-captured
+	if req.path != "/v1/messages" {
+		t.Fatalf("unexpected path %q", req.path)
+	}
+	if req.headers.Get("x-api-key") != "key" || req.headers.Get("anthropic-version") == "" {
+		t.Fatalf("missing anthropic headers: %v", req.headers)
+	}
+	if req.body["system"] != "be brief" || req.body["max_tokens"] == nil {
+		t.Fatalf("expected system prompt and max_tokens, got %v", req.body)
+	}
+	messages := req.body["messages"].([]any)
+	if len(messages) != 1 || messages[0].(map[string]any)["role"] != "user" {
+		t.Fatalf("expected a single user message, got %v", messages)
+	}
+}
+
+func TestOllamaProvider(t *testing.T) {
+	server, captured := newAIStandIn(t, `{"message":{"role":"assistant","content":"local summary"},"done":true,"prompt_eval_count":30,"eval_count":5}`)
+
+	provider, err := NewAIProvider(AIProviderOllama, server.URL, "", "llama", nil)
+	if err != nil {
+		t.Fatalf("expected ollama to work without an api key, got %v", err)
+	}
+	result, err := provider.Summarize(context.Background(), testAIRequest)
+	if err != nil {
+		t.Fatalf("summarize failed: %v", err)
+	}
+	if result.Text != "local summary" || result.Usage != (AIUsage{InputTokens: 30, OutputTokens: 5}) {
+		t.Fatalf("unexpected result %#v", result)
+	}
+
+	req := <-captured
+	if req.path != "/api/chat" {
+		t.Fatalf("unexpected path %q", req.path)
+	}
+	if req.body["stream"] != false {
+		t.Fatalf("expected non-streaming request, got %v", req.body["stream"])
+	}
+	if req.headers.Get("Authorization") != "" {
+		t.Fatalf("expected no auth header without a key")
+	}
+}
+
+func TestGeminiProvider(t *testing.T) {
+	server, captured := newAIStandIn(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"gemini summary"}]}}],"usageMetadata":{"promptTokenCount":50,"candidatesTokenCount":8}}`)
+
+	provider, err := NewAIProvider(AIProviderGemini, server.URL, "key", "gemini-flash", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := provider.Summarize(context.Background(), testAIRequest)
+	if err != nil {
+		t.Fatalf("summarize failed: %v", err)
+	}
+	if result.Text != "gemini summary" || result.Usage != (AIUsage{InputTokens: 50, OutputTokens: 8}) {
+		t.Fatalf("unexpected result %#v", result)
+	}
+
+	req := <-captured
+	if req.path != "/v1beta/models/gemini-flash:generateContent" {
+		t.Fatalf("unexpected path %q", req.path)
+	}
+	if req.headers.Get("x-goog-api-key") != "key" {
+		t.Fatalf("missing gemini api key header")
+	}
+	system := req.body["systemInstruction"].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"]
+	if system != "be brief" {
+		t.Fatalf("expected prompt as system instruction, got %v", system)
+	}
+}
+
+func TestAIProviderReportsHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	provider, err := NewAIProvider(AIProviderAnthropic, server.URL, "key", "m", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := provider.Summarize(context.Background(), testAIRequest); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
+
+func TestNewAIProviderValidatesConfig(t *testing.T) {
+	if _, err := NewAIProvider("mystery", "http://x", "k", "m", nil); err == nil {
+		t.Fatalf("expected unknown provider to be rejected")
+	}
+	if _, err := NewAIProvider(AIProviderOpenAICompatible, "", "k", "m", nil); err == nil {
+		t.Fatalf("expected openai-compatible without base url to be rejected")
+	}
+	if _, err := NewAIProvider(AIProviderGemini, "", "", "m", nil); err == nil {
+		t.Fatalf("expected gemini without api key to be rejected")
+	}
+}
@@ -0,0 +1,225 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxAskQuestionRunes bounds a reader's question.
+	maxAskQuestionRunes = 300
+	// askSourceLimit is how many posts are quoted in the prompt.
+	askSourceLimit = 4
+	// askPassageRunes bounds the text quoted from one post.
+	askPassageRunes = 1200
+)
+
+var (
+	ErrAskDisabled        = errors.New("question answering is disabled")
+	ErrAskInvalidQuestion = errors.New("question is empty or too long")
+)
+
+var policyAskAnswer = cachePolicy{
+	Prefix: "ask", Version: 1, TTLSeconds: 3600,
+}
+
+var askPrompts = map[string]string{
+	"zh-CN": `你是这个博客的问答助手，只能依据下面编号的文章片段回答读者的问题。` +
+		`用简体中文回答，简洁明了，在用到某个片段的句子后标注它的编号，如 [1]。` +
+		`如果片段中没有答案，直接说明博客中没有相关内容，不要编造。`,
+	"en": `You answer readers' questions about this blog using only the numbered post excerpts below. ` +
+		`Answer in English, concisely, and cite the excerpt each statement comes from by its number, like [1]. ` +
+		`If the excerpts do not contain the answer, say that the blog does not cover it; never make things up.`,
+}
+
+// WithAIAskEnabled turns on question answering over the published posts.
+func WithAIAskEnabled(enabled bool) ServiceOption {
+	return func(s *Service) { s.aiAsk = enabled }
+}
+
+// AskContext answers a question from the published posts. The posts that
+// match it best are quoted to the AI provider as numbered sources, and the
+// answer cites them by number. Answers are cached by question.
+func (s *Service) AskContext(ctx context.Context, question string) (*AskAnswer, error) {
+	question = strings.Join(strings.Fields(question), " ")
+	if question == "" || len([]rune(question)) > maxAskQuestionRunes {
+		return nil, ErrAskInvalidQuestion
+	}
+	if s.aiQueue == nil || !s.aiEnabled || !s.aiAsk {
+		return nil, ErrAskDisabled
+	}
+
+	locale := normalizeSummaryLocale(s.locale)
+	cacheKey := contentHash(locale + "\x00" + strings.ToLower(question))
+	var cached AskAnswer
+	if s.cache.readJSON(policyAskAnswer, cacheKey, &cached) {
+		cached.Cached = true
+		return &cached, nil
+	}
+
+	mode := SearchModeKeyword
+	if s.aiQueue.EmbeddingModel() != "" {
+		mode = SearchModeHybrid
+	}
+	found, err := s.SearchPostsModeContext(ctx, askSearchQuery(question), mode, false, askSourceLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &AskAnswer{Question: question, Sources: []AskSource{}}
+	terms := askTerms(question)
+	var excerpts strings.Builder
+	for _, item := range found.Items {
+		note, err := s.getCachedNote(ctx, item.NoteID)
+		if err != nil {
+			continue
+		}
+		content, err := s.getPostContent(ctx, note)
+		if err != nil {
+			continue
+		}
+		passage := askPassage(extractSummaryText(sanitizeContentForSummary(content)), terms)
+		if passage == "" {
+			continue
+		}
+		source := AskSource{
+			Index:  len(answer.Sources) + 1,
+			NoteID: item.NoteID,
+			Title:  item.Title,
+			URL:    "/post/" + item.NoteID,
+		}
+		answer.Sources = append(answer.Sources, source)
+		fmt.Fprintf(&excerpts, "[%d] %s\n%s\n\n", source.Index, source.Title, passage)
+	}
+
+	if len(answer.Sources) == 0 {
+		answer.Answer = askNoSources[locale]
+	} else {
+		result, err := s.aiQueue.Answer(ctx, askPrompts[locale], "Question: "+question+"\n\n"+excerpts.String())
+		if err != nil {
+			return nil, err
+		}
+		answer.Answer = strings.TrimSpace(result.Text)
+		answer.Sources = citedSources(answer.Answer, answer.Sources)
+	}
+	answer.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	s.cache.writeJSON(policyAskAnswer, cacheKey, answer)
+	return answer, nil
+}
+
+var askNoSources = map[string]string{
+	"zh-CN": "博客中没有找到与这个问题相关的文章。",
+	"en":    "No posts on this blog seem to cover that question.",
+}
+
+// Answer makes one provider call outside the job queue, for an answer a
+// reader is waiting for. It shares the queue's client, timeouts, usage
+// records and monthly budget.
+func (q *AISummaryQueue) Answer(ctx context.Context, prompt, content string) (AIResult, error) {
+	if q.providerErr != nil {
+		return AIResult{}, q.providerErr
+	}
+	if q.overBudget() {
+		return AIResult{}, &AIConfigError{Message: "ai monthly budget reached"}
+	}
+	job := &ClaimedJob{AISummaryJob: AISummaryJob{Type: "ask"}}
+	return q.summarize(ctx, job, AIRequest{Prompt: prompt, Content: clampSummaryInput(content, q.maxInputRunes)})
+}
+
+// askSearchQuery keeps the words of a question that say what it is about;
+// short words such as "is" or "do" would match almost every post.
+func askSearchQuery(question string) string {
+	var words []string
+	for _, word := range strings.Fields(normalizeSearchText(question)) {
+		if len([]rune(word)) > 2 || strings.IndexFunc(word, isHan) >= 0 {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return question
+	}
+	return strings.Join(words, " ")
+}
+
+// askTerms returns the terms passages are scored by.
+func askTerms(question string) []string {
+	set := relatedTerms(question, "")
+	terms := make([]string, 0, len(set))
+	for term := range set {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// askPassage picks the paragraphs of text that share the most terms with
+// the question, keeping them in document order within askPassageRunes.
+func askPassage(text string, terms []string) string {
+	var paragraphs []string
+	for _, p := range strings.Split(text, "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	if len(paragraphs) == 0 {
+		return ""
+	}
+
+	scores := make([]int, len(paragraphs))
+	for i, p := range paragraphs {
+		norm := normalizeSearchText(p)
+		for _, term := range terms {
+			if strings.Contains(norm, term) {
+				scores[i]++
+			}
+		}
+	}
+	order := make([]int, len(paragraphs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	chosen := make([]bool, len(paragraphs))
+	size := 0
+	for _, i := range order {
+		n := len([]rune(paragraphs[i]))
+		if size > 0 && (scores[i] == 0 || size+n > askPassageRunes) {
+			continue
+		}
+		chosen[i] = true
+		size += n
+	}
+	var b strings.Builder
+	for i, p := range paragraphs {
+		if chosen[i] {
+			b.WriteString(p)
+			b.WriteByte('\n')
+		}
+	}
+	return clampSummaryInput(b.String(), askPassageRunes)
+}
+
+// citedSources keeps the sources the answer cites; when it cites none, all
+// of them are returned so the reader can still check the answer.
+func citedSources(answer string, sources []AskSource) []AskSource {
+	var cited []AskSource
+	for _, source := range sources {
+		if strings.Contains(answer, fmt.Sprintf("[%d]", source.Index)) {
+			cited = append(cited, source)
+		}
+	}
+	if len(cited) == 0 {
+		return sources
+	}
+	return cited
+}
+
+func isHan(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
@@ -0,0 +1,118 @@
+package blog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestAskPassagePicksMatchingParagraphs(t *testing.T) {
+	text := "An introduction about nothing.\nGoroutines are cheap threads.\nUnrelated closing words."
+	passage := askPassage(text, askTerms("How cheap are goroutines?"))
+	if passage != "Goroutines are cheap threads." {
+		t.Fatalf("unexpected passage %q", passage)
+	}
+	if got := askSearchQuery("How do I use goroutines?"); got != "how use goroutines" {
+		t.Fatalf("unexpected search query %q", got)
+	}
+}
+
+func TestAskAnswersFromPostsWithCitations(t *testing.T) {
+	fake := etapitest.New()
+	for _, n := range []struct{ id, title, content string }{
+		{"goroutines", "Goroutines", "<p>Goroutines are cheap threads managed by the Go runtime.</p>"},
+		{"garden", "Tomato garden", "<p>Tomatoes need sun.</p>"},
+	} {
+		fake.AddNote(etapi.Note{
+			NoteID:       n.id,
+			Title:        n.title,
+			DateModified: "2026-04-13T12:00:00Z",
+			Attributes:   []etapi.Attribute{etapitest.Label("blog", "true")},
+		}, n.content)
+	}
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+
+	var calls atomic.Int32
+	aiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		// Searching queues the posts' AI summaries too; they report no usage.
+		if !strings.HasPrefix(body.Messages[0].Content, "You answer readers") {
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"A summary."}}]}`))
+			return
+		}
+		calls.Add(1)
+		if !strings.Contains(body.Messages[1].Content, "[1] Goroutines\nGoroutines are cheap threads") ||
+			strings.Contains(body.Messages[1].Content, "Tomatoes") {
+			t.Errorf("unexpected excerpts %q", body.Messages[1].Content)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"They are cheap threads [1]."}}],"usage":{"prompt_tokens":40,"completion_tokens":6}}`))
+	}))
+	defer aiServer.Close()
+
+	dir := t.TempDir()
+	cache, err := NewFileStore(filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatalf("new cache: %v", err)
+	}
+	store := newTestSummaryStore(t, filepath.Join(dir, "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", aiServer.URL, "token", "model", "prompt", 1, 1, 30000, 2000)
+	defer queue.Close()
+	service := NewService(etapi.NewClient(trilium.URL, "token"), cache, WithLocale("en"),
+		WithSummaryStore(store), WithAISummaryQueue(queue), WithAISummaryEnabled(true), WithAIAskEnabled(true))
+
+	answer, err := service.AskContext(t.Context(), "What are goroutines?")
+	if err != nil {
+		t.Fatalf("ask failed: %v", err)
+	}
+	if answer.Answer != "They are cheap threads [1]." || answer.Cached {
+		t.Fatalf("unexpected answer %#v", answer)
+	}
+	if len(answer.Sources) != 1 || answer.Sources[0].NoteID != "goroutines" || answer.Sources[0].URL != "/post/goroutines" {
+		t.Fatalf("expected the cited post as the source, got %#v", answer.Sources)
+	}
+
+	again, err := service.AskContext(t.Context(), "  what are   goroutines? ")
+	if err != nil {
+		t.Fatalf("ask failed: %v", err)
+	}
+	if !again.Cached || calls.Load() != 1 {
+		t.Fatalf("expected the repeated question to be answered from the cache, got %#v after %d calls", again, calls.Load())
+	}
+
+	report, err := queue.UsageReport("day", 1)
+	if err != nil || len(report.Items) != 1 || report.Items[0].InputTokens != 40 {
+		t.Fatalf("expected the answer to be recorded as usage, got %#v (%v)", report, err)
+	}
+}
+
+func TestAskRejectsBadQuestionsAndDisabledService(t *testing.T) {
+	blogServer := newBlogTestServer(t, "note-1", "<p>Hello.</p>")
+	defer blogServer.Close()
+	service := NewService(etapi.NewClient(blogServer.URL, "token"), &NoopStore{})
+
+	if _, err := service.AskContext(t.Context(), "   "); !errors.Is(err, ErrAskInvalidQuestion) {
+		t.Fatalf("expected an empty question to be rejected, got %v", err)
+	}
+	if _, err := service.AskContext(t.Context(), strings.Repeat("why ", 100)); !errors.Is(err, ErrAskInvalidQuestion) {
+		t.Fatalf("expected a long question to be rejected, got %v", err)
+	}
+	if _, err := service.AskContext(t.Context(), "hello?"); !errors.Is(err, ErrAskDisabled) {
+		t.Fatalf("expected question answering to be disabled, got %v", err)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/harveyTon/trilium-blog/backend/etapi"
 	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+	"github.com/harveyTon/trilium-blog/backend/pkg/metrics"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -22,18 +24,18 @@ type cachePolicy struct {
 
 var (
 	policyNotesList = cachePolicy{
-		Prefix: "notes", Version: 1, TTLSeconds: 90,
+		Prefix: "notes", Version: 2, TTLSeconds: 90,
 		Preload: true, RefreshAhead: true, RefreshAtRatio: 0.3,
 	}
 	policyNote = cachePolicy{
-		Prefix: "note", Version: 1, TTLSeconds: 300,
+		Prefix: "note", Version: 2, TTLSeconds: 300,
 	}
 	policyNoteContent = cachePolicy{
 		Prefix: "note-content", Version: 1, TTLSeconds: 1800,
 		Preload: true,
 	}
 	policyAttachmentMeta = cachePolicy{
-		Prefix: "attachment-meta", Version: 1, TTLSeconds: 3600,
+		Prefix: "attachment-meta", Version: 2, TTLSeconds: 3600,
 	}
 	policyAttachmentData = cachePolicy{
 		Prefix: "attachment-data", Version: 1, TTLSeconds: 3600,
@@ -59,8 +61,9 @@ type CacheTypeStats struct {
 }
 
 type CacheStats struct {
-	RedisConnected bool             `json:"redisConnected"`
-	Types          []CacheTypeStats `json:"types"`
+	RedisConnected bool                 `json:"redisConnected"`
+	Types          []CacheTypeStats     `json:"types"`
+	Trilium        *etapi.BreakerStatus `json:"trilium,omitempty"`
 }
 
 var allPolicies = []cachePolicy{
@@ -69,6 +72,10 @@ var allPolicies = []cachePolicy{
 	policyNoteContent,
 	policyAttachmentMeta,
 	policyAttachmentData,
+	policyPostContent,
+	policyIncludeDeps,
+	policyAskAnswer,
+	policyRelatedPosts,
 }
 
 func (c *cacheLayer) stats() CacheStats {
@@ -256,9 +263,11 @@ func (c *cacheLayer) get(policy cachePolicy, suffix string) (string, bool) {
 	}
 	val, err := c.store.Get(policy.key(suffix))
 	if err != nil {
+		metrics.ObserveCacheLookup(policy.Prefix, false)
 		logger.Debug(fmt.Sprintf("cache miss: %s", policy.key(suffix)))
 		return "", false
 	}
+	metrics.ObserveCacheLookup(policy.Prefix, true)
 	logger.Debug(fmt.Sprintf("cache hit: %s", policy.key(suffix)))
 	return val, true
 }
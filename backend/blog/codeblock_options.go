@@ -0,0 +1,173 @@
+package blog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+// codeDirective starts the comment on the first line of a code block that
+// sets its options, e.g.
+//
+//	// @code title="main.go" highlight=3-5,8 start=10 collapsed
+const codeDirective = "@code"
+
+// maxHighlightLines bounds how many lines one block can highlight.
+const maxHighlightLines = 1000
+
+// codeCommentMarkers are the comment delimiters a directive may use, by
+// language family.
+var codeCommentMarkers = [][2]string{
+	{"<!--", "-->"},
+	{"/*", "*/"},
+	{"//", ""},
+	{"--", ""},
+	{"#", ""},
+	{";", ""},
+	{"%", ""},
+}
+
+// codeBlockDefaults returns the options of a post's code blocks before their
+// directives: line numbers unless #codeLineNumbers=false, expanded unless
+// #codeCollapsed=true.
+func codeBlockDefaults(attrs []etapi.Attribute) CodeBlock {
+	block := CodeBlock{ShowLineNumbers: true}
+	if v, err := strconv.ParseBool(labelValue(attrs, "codeLineNumbers")); err == nil {
+		block.ShowLineNumbers = v
+	}
+	if v, err := strconv.ParseBool(labelValue(attrs, "codeCollapsed")); err == nil {
+		block.Collapsed = v
+	}
+	return block
+}
+
+// parseCodeDirective applies the directive on the first line of code, if
+// any, to block and returns the code without that line.
+func parseCodeDirective(code string, block *CodeBlock) string {
+	firstLine, rest, _ := strings.Cut(strings.TrimLeft(code, "\r\n"), "\n")
+	args, ok := codeDirectiveArgs(strings.TrimSpace(firstLine))
+	if !ok {
+		return code
+	}
+	for key, value := range parseDirectiveArgs(args) {
+		switch strings.ToLower(key) {
+		case "title":
+			block.Title = value
+		case "highlight", "hl":
+			block.HighlightLines = parseLineRanges(value)
+		case "start":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				block.StartLine = n
+			}
+		case "collapsed":
+			if v, err := strconv.ParseBool(value); err == nil {
+				block.Collapsed = v
+			}
+		case "linenumbers":
+			if v, err := strconv.ParseBool(value); err == nil {
+				block.ShowLineNumbers = v
+			}
+		}
+	}
+	return rest
+}
+
+// codeDirectiveArgs returns what follows @code in a comment line.
+func codeDirectiveArgs(line string) (string, bool) {
+	for _, marker := range codeCommentMarkers {
+		body, ok := strings.CutPrefix(line, marker[0])
+		if !ok {
+			continue
+		}
+		if marker[1] != "" {
+			if body, ok = strings.CutSuffix(strings.TrimSpace(body), marker[1]); !ok {
+				continue
+			}
+		}
+		body = strings.TrimSpace(body)
+		args, ok := strings.CutPrefix(body, codeDirective)
+		if !ok || (args != "" && args[0] != ' ' && args[0] != '\t') {
+			return "", false
+		}
+		return args, true
+	}
+	return "", false
+}
+
+// parseDirectiveArgs splits `key=value key="quoted value" flag` pairs; a
+// flag without a value is "true".
+func parseDirectiveArgs(args string) map[string]string {
+	result := make(map[string]string)
+	for {
+		args = strings.TrimSpace(args)
+		if args == "" {
+			return result
+		}
+		end := strings.IndexAny(args, " \t=")
+		if end < 0 {
+			result[args] = "true"
+			return result
+		}
+		key := args[:end]
+		if args[end] != '=' {
+			result[key] = "true"
+			args = args[end:]
+			continue
+		}
+		args = args[end+1:]
+		var value string
+		if strings.HasPrefix(args, `"`) {
+			closing := strings.Index(args[1:], `"`)
+			if closing < 0 {
+				value, args = args[1:], ""
+			} else {
+				value, args = args[1:closing+1], args[closing+2:]
+			}
+		} else {
+			value, args, _ = strings.Cut(args, " ")
+		}
+		result[key] = value
+	}
+}
+
+// parseLineRanges parses "3-5,8" into sorted, unique line numbers.
+func parseLineRanges(spec string) []int {
+	seen := make(map[int]bool)
+	var lines []int
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || start <= 0 {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(to)); err != nil || end < start {
+				continue
+			}
+		}
+		for n := start; n <= end && len(lines) < maxHighlightLines; n++ {
+			if !seen[n] {
+				seen[n] = true
+				lines = append(lines, n)
+			}
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// lineRanges groups sorted line numbers into the ranges Chroma expects.
+func lineRanges(lines []int) [][2]int {
+	var ranges [][2]int
+	for _, n := range lines {
+		if last := len(ranges) - 1; last >= 0 && ranges[last][1] == n-1 {
+			ranges[last][1] = n
+			continue
+		}
+		ranges = append(ranges, [2]int{n, n})
+	}
+	return ranges
+}
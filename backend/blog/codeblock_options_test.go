@@ -0,0 +1,117 @@
+package blog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestParseCodeDirective(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want CodeBlock
+		rest string
+	}{
+		{
+			name: "slash comment",
+			code: "// @code title=\"main.go\" highlight=3-4,1 start=10 collapsed\npackage main\n",
+			want: CodeBlock{ShowLineNumbers: true, Title: "main.go", HighlightLines: []int{1, 3, 4}, StartLine: 10, Collapsed: true},
+			rest: "package main\n",
+		},
+		{
+			name: "hash comment",
+			code: "# @code lineNumbers=false hl=2\nprint(1)",
+			want: CodeBlock{ShowLineNumbers: false, HighlightLines: []int{2}},
+			rest: "print(1)",
+		},
+		{
+			name: "block comment",
+			code: "<!-- @code title=\"Page title\" -->\n<p>hi</p>",
+			want: CodeBlock{ShowLineNumbers: true, Title: "Page title"},
+			rest: "<p>hi</p>",
+		},
+		{
+			name: "not a directive",
+			code: "// @codec title=x\nfoo()",
+			want: CodeBlock{ShowLineNumbers: true},
+			rest: "// @codec title=x\nfoo()",
+		},
+		{
+			name: "invalid values are ignored",
+			code: "-- @code start=-3 collapsed=maybe highlight=5-2,x\nselect 1",
+			want: CodeBlock{ShowLineNumbers: true},
+			rest: "select 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := CodeBlock{ShowLineNumbers: true}
+			rest := parseCodeDirective(tt.code, &block)
+			if rest != tt.rest {
+				t.Fatalf("expected rest %q, got %q", tt.rest, rest)
+			}
+			if !reflect.DeepEqual(block, tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, block)
+			}
+		})
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	got := lineRanges([]int{1, 2, 3, 5, 7, 8})
+	want := [][2]int{{1, 3}, {5, 5}, {7, 8}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestGetPostAppliesCodeBlockOptions(t *testing.T) {
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:     "post",
+		Title:      "Post",
+		Attributes: []etapi.Attribute{etapitest.Label("blog", "true"), etapitest.Label("codeLineNumbers", "false")},
+	}, `<pre><code class="language-text-x-go">// @code title="demo.go" highlight=2 start=5
+package main
+func main() {}</code></pre><pre><code class="language-text-x-go"># @code lineNumbers=true
+x := 1</code></pre>`)
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+	service := NewService(etapi.NewClient(trilium.URL, "token"), &NoopStore{}, WithServerHighlight(true))
+
+	post, err := service.GetPostContext(t.Context(), "post")
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if len(post.CodeBlocks) != 2 {
+		t.Fatalf("expected two code blocks, got %+v", post.CodeBlocks)
+	}
+	first, second := post.CodeBlocks[0], post.CodeBlocks[1]
+	if first.ShowLineNumbers || first.Title != "demo.go" || first.StartLine != 5 || !reflect.DeepEqual(first.HighlightLines, []int{2}) {
+		t.Fatalf("unexpected first block %+v", first)
+	}
+	if !second.ShowLineNumbers {
+		t.Fatalf("expected the directive to override the note label, got %+v", second)
+	}
+	if strings.Contains(post.ContentHTML, "@code") {
+		t.Fatalf("expected directives to be removed, got %s", post.ContentHTML)
+	}
+	if !strings.Contains(post.ContentHTML, `<span class="ln">1</span>`) {
+		t.Fatalf("expected line numbers on the second block, got %s", post.ContentHTML)
+	}
+}
+
+func TestHighlightCodeAppliesStartAndHighlightedLines(t *testing.T) {
+	svc := NewService(nil, &NoopStore{})
+	html, err := svc.highlightCode("a\nb\nc\n", "plaintext", CodeBlock{ShowLineNumbers: true, StartLine: 10, HighlightLines: []int{11}})
+	if err != nil {
+		t.Fatalf("highlight failed: %v", err)
+	}
+	if !strings.Contains(html, `<span class="ln">10</span>`) || strings.Count(html, `class="line hl"`) != 1 {
+		t.Fatalf("unexpected highlighted html %s", html)
+	}
+}
@@ -0,0 +1,216 @@
+package blog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Embedder turns text into a vector for finding related posts.
+type Embedder interface {
+	Name() string
+	Model() string
+	Embed(ctx context.Context, input string) ([]float32, AIUsage, error)
+}
+
+// NewOpenAIEmbedder builds an Embedder for an OpenAI-compatible /embeddings
+// endpoint.
+func NewOpenAIEmbedder(baseURL, apiKey, model string, client *http.Client) (Embedder, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" || model == "" {
+		return nil, &AIConfigError{Message: "ai embedding provider is not fully configured"}
+	}
+	return &openAIEmbedder{baseURL: baseURL, apiKey: apiKey, model: model, client: client}, nil
+}
+
+type openAIEmbedder struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func (e *openAIEmbedder) Name() string  { return AIProviderOpenAICompatible }
+func (e *openAIEmbedder) Model() string { return e.model }
+
+func (e *openAIEmbedder) Embed(ctx context.Context, input string) ([]float32, AIUsage, error) {
+	body := map[string]any{
+		"model": e.model,
+		"input": input,
+	}
+	var result struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	headers := map[string]string{}
+	if e.apiKey != "" {
+		headers["Authorization"] = "Bearer " + e.apiKey
+	}
+	if err := postJSON(ctx, e.client, e.baseURL+"/embeddings", headers, body, &result); err != nil {
+		return nil, AIUsage{}, err
+	}
+	usage := AIUsage{InputTokens: result.Usage.PromptTokens}
+	if len(result.Data) == 0 || len(result.Data[0].Embedding) == 0 {
+		return nil, usage, fmt.Errorf("ai provider returned no embedding")
+	}
+	return result.Data[0].Embedding, usage, nil
+}
+
+// StoredEmbedding is the vector of one note for one model.
+type StoredEmbedding struct {
+	NoteID     string
+	Model      string
+	SourceHash string
+	Vector     []float32
+	UpdatedAt  string
+}
+
+// EmbeddingStore keeps one vector per note and model.
+type EmbeddingStore interface {
+	SaveEmbedding(item StoredEmbedding) error
+	ListEmbeddings(model string) (map[string]StoredEmbedding, error)
+}
+
+// EmbeddingStoreDB is an EmbeddingStore in its own SQLite file, so that
+// vectors can be dropped without touching the summaries.
+type EmbeddingStoreDB struct {
+	db *sql.DB
+}
+
+func NewEmbeddingStoreDB(path string) (*EmbeddingStoreDB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	store := &EmbeddingStoreDB{db: db}
+	if err := store.init(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *EmbeddingStoreDB) init() error {
+	for _, pragma := range []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA synchronous = NORMAL",
+		"PRAGMA busy_timeout = 5000",
+	} {
+		if _, err := s.db.Exec(pragma); err != nil {
+			return err
+		}
+	}
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS embeddings (
+			note_id TEXT NOT NULL,
+			model TEXT NOT NULL,
+			source_hash TEXT NOT NULL DEFAULT '',
+			vector BLOB NOT NULL,
+			updated_at TEXT NOT NULL,
+			PRIMARY KEY (note_id, model)
+		)
+	`)
+	return err
+}
+
+func (s *EmbeddingStoreDB) SaveEmbedding(item StoredEmbedding) error {
+	if item.UpdatedAt == "" {
+		item.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO embeddings (note_id, model, source_hash, vector, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(note_id, model) DO UPDATE SET
+			source_hash = excluded.source_hash,
+			vector = excluded.vector,
+			updated_at = excluded.updated_at
+	`, item.NoteID, item.Model, item.SourceHash, encodeEmbedding(item.Vector), item.UpdatedAt)
+	return err
+}
+
+// ListEmbeddings returns the vectors of model by note ID.
+func (s *EmbeddingStoreDB) ListEmbeddings(model string) (map[string]StoredEmbedding, error) {
+	rows, err := s.db.Query(`
+		SELECT note_id, model, source_hash, vector, updated_at
+		FROM embeddings
+		WHERE model = ?
+	`, model)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[string]StoredEmbedding)
+	for rows.Next() {
+		var item StoredEmbedding
+		var blob []byte
+		if err := rows.Scan(&item.NoteID, &item.Model, &item.SourceHash, &blob, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		item.Vector = decodeEmbedding(blob)
+		items[item.NoteID] = item
+	}
+	return items, rows.Err()
+}
+
+func (s *EmbeddingStoreDB) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// encodeEmbedding packs a vector as little-endian float32s.
+func encodeEmbedding(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeEmbedding(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vec
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0
+// when their lengths differ or either is zero.
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,105 @@
+package blog
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Chroma styles used when no theme, or an unknown one, is configured.
+const (
+	DefaultHighlightTheme     = "github"
+	DefaultHighlightDarkTheme = "github-dark"
+)
+
+// highlightCSSPath serves the stylesheet for server-highlighted code.
+const highlightCSSPath = "/api/highlight.css"
+
+// WithServerHighlight renders code blocks as Chroma-highlighted HTML with
+// CSS classes, instead of leaving highlighting to the browser.
+func WithServerHighlight(enabled bool) ServiceOption {
+	return func(s *Service) { s.serverHighlight = enabled }
+}
+
+// WithHighlightThemes sets the Chroma styles of the highlight stylesheet;
+// dark applies while the page has the "dark" class.
+func WithHighlightThemes(light, dark string) ServiceOption {
+	return func(s *Service) {
+		s.highlightTheme = light
+		s.highlightDarkTheme = dark
+	}
+}
+
+// ValidHighlightTheme reports whether name is a Chroma style.
+func ValidHighlightTheme(name string) bool {
+	_, ok := styles.Registry[strings.ToLower(name)]
+	return ok
+}
+
+func highlightStyle(name, fallback string) *chroma.Style {
+	if style, ok := styles.Registry[strings.ToLower(name)]; ok {
+		return style
+	}
+	return styles.Get(fallback)
+}
+
+// highlightCode renders code as a Chroma <pre> block using CSS classes.
+func (s *Service) highlightCode(code, languageID string, block CodeBlock) (string, error) {
+	lexer := lexers.Get(languageID)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", err
+	}
+	options := []chromahtml.Option{
+		chromahtml.WithClasses(true),
+		chromahtml.WithLineNumbers(block.ShowLineNumbers),
+		chromahtml.HighlightLines(lineRanges(block.HighlightLines)),
+	}
+	if block.StartLine > 0 {
+		options = append(options, chromahtml.BaseLineNumber(block.StartLine))
+	}
+	formatter := chromahtml.New(options...)
+	var sb strings.Builder
+	if err := formatter.Format(&sb, highlightStyle(s.highlightTheme, DefaultHighlightTheme), tokens); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// HighlightCSS returns the stylesheet for server-highlighted code: the
+// light theme, and the dark theme scoped to html.dark.
+func (s *Service) HighlightCSS() string {
+	s.highlightCSSOnce.Do(func() {
+		s.highlightCSS = buildHighlightCSS(
+			highlightStyle(s.highlightTheme, DefaultHighlightTheme),
+			highlightStyle(s.highlightDarkTheme, DefaultHighlightDarkTheme),
+		)
+	})
+	return s.highlightCSS
+}
+
+func buildHighlightCSS(light, dark *chroma.Style) string {
+	formatter := chromahtml.New(chromahtml.WithClasses(true))
+	var sb, darkCSS strings.Builder
+	_ = formatter.WriteCSS(&sb, light)
+	_ = formatter.WriteCSS(&darkCSS, dark)
+	// Chroma writes one "/* Name */ selector { ... }" rule per line.
+	for _, line := range strings.Split(darkCSS.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		if comment, rule, ok := strings.Cut(line, "*/ "); ok {
+			line = comment + "*/ html.dark " + rule
+		} else {
+			line = "html.dark " + line
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package blog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProcessContentHighlightsOnServer(t *testing.T) {
+	svc := NewService(nil, &NoopStore{}, WithServerHighlight(true))
+
+	processed, codeBlocks := svc.processContent(`<p>Intro</p><pre><code class="language-text-x-go">package main
+
+func main() {}</code></pre>`)
+	if len(codeBlocks) != 1 || !codeBlocks[0].Highlighted || codeBlocks[0].LanguageID != "go" {
+		t.Fatalf("expected one highlighted go block, got %+v", codeBlocks)
+	}
+	for _, want := range []string{`class="chroma"`, `<span class="ln">1</span>`, `<span class="kn">package</span>`} {
+		if !strings.Contains(processed, want) {
+			t.Fatalf("expected %q in %s", want, processed)
+		}
+	}
+	if strings.Count(processed, "<pre") != 1 {
+		t.Fatalf("expected the original block to be replaced, got %s", processed)
+	}
+	if site := svc.GetSite(); site.HighlightCSS != "/api/highlight.css" {
+		t.Fatalf("expected the stylesheet in the site info, got %q", site.HighlightCSS)
+	}
+}
+
+func TestProcessContentLeavesHighlightingToBrowserByDefault(t *testing.T) {
+	svc := NewService(nil, &NoopStore{})
+
+	processed, codeBlocks := svc.processContent(`<pre><code class="language-go">package main</code></pre>`)
+	if len(codeBlocks) != 1 || codeBlocks[0].Highlighted || strings.Contains(processed, "chroma") {
+		t.Fatalf("expected a plain code block, got %s %+v", processed, codeBlocks)
+	}
+	if site := svc.GetSite(); site.HighlightCSS != "" {
+		t.Fatalf("expected no stylesheet, got %q", site.HighlightCSS)
+	}
+}
+
+func TestHighlightCSSScopesDarkTheme(t *testing.T) {
+	svc := NewService(nil, &NoopStore{}, WithHighlightThemes("monokailight", "no-such-style"))
+
+	css := svc.HighlightCSS()
+	light := highlightStyle("monokailight", DefaultHighlightTheme)
+	dark := highlightStyle("no-such-style", DefaultHighlightDarkTheme)
+	if light.Name != "monokailight" || dark.Name != DefaultHighlightDarkTheme {
+		t.Fatalf("unexpected styles %q and %q", light.Name, dark.Name)
+	}
+	if !strings.Contains(css, "/* PreWrapper */ .chroma {") || !strings.Contains(css, "/* PreWrapper */ html.dark .chroma {") {
+		t.Fatalf("expected light and dark rules, got %s", css)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(css), "\n") {
+		if strings.Contains(line, "#0d1117") && !strings.Contains(line, "html.dark") {
+			t.Fatalf("expected dark colors to be scoped, got %q", line)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package blog
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+)
+
+// maxIncludeDepth bounds how deeply included notes may include others.
+const maxIncludeDepth = 3
+
+var (
+	// policyPostContent caches post content that took more than the note's
+	// own content to render: text posts that include notes, and book posts.
+	policyPostContent = cachePolicy{
+		Prefix: "post-content", Version: 1, TTLSeconds: 1800,
+	}
+	// policyIncludeDeps maps an included note to the notes that include
+	// it, so that editing it refreshes them.
+	policyIncludeDeps = cachePolicy{
+		Prefix: "include-deps", Version: 1, TTLSeconds: 86400,
+	}
+)
+
+const includeNoteSelector = "section.include-note[data-note-id]"
+
+// expandIncludes replaces the include sections of content, which belongs
+// to the last note of path. Notes already on the path, or deeper than
+// maxIncludeDepth, are left out.
+func (s *Service) expandIncludes(ctx context.Context, noteID, content string, path []string) string {
+	if !strings.Contains(content, "include-note") {
+		return content
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return content
+	}
+	sections := doc.Find(includeNoteSelector)
+	if sections.Length() == 0 {
+		return content
+	}
+
+	sections.Each(func(_ int, sel *goquery.Selection) {
+		includedID := strings.TrimSpace(sel.AttrOr("data-note-id", ""))
+		switch {
+		case includedID == "":
+			sel.Remove()
+			return
+		case slices.Contains(path, includedID):
+			logger.Logger.Warn().Str("note_id", noteID).Str("included", includedID).Msg("Skipping included note that includes itself")
+			sel.Remove()
+			return
+		case len(path) > maxIncludeDepth:
+			logger.Logger.Warn().Str("note_id", noteID).Str("included", includedID).Msg("Skipping included note nested too deeply")
+			sel.Remove()
+			return
+		}
+
+		s.recordInclude(includedID, path)
+		included, ok := s.includedNoteHTML(ctx, includedID, path)
+		if !ok {
+			sel.Remove()
+			return
+		}
+		sel.ReplaceWithHtml(`<div class="include-note">` + included + `</div>`)
+	})
+
+	result, _ := doc.Find("body").Html()
+	if result == "" {
+		return content
+	}
+	return strings.TrimSpace(result)
+}
+
+// includedNoteHTML renders an included note: text notes with their own
+// includes expanded, code notes as a code block.
+func (s *Service) includedNoteHTML(ctx context.Context, noteID string, path []string) (string, bool) {
+	note, err := s.getCachedNote(ctx, noteID)
+	if err != nil {
+		logger.Logger.Warn().Err(err).Str("note_id", noteID).Msg("Failed to load included note")
+		return "", false
+	}
+	if note.Type != "text" && note.Type != "code" {
+		return "", false
+	}
+	content, err := s.getCachedNoteContent(ctx, noteID)
+	if err != nil {
+		logger.Logger.Warn().Err(err).Str("note_id", noteID).Msg("Failed to load included note content")
+		return "", false
+	}
+	switch note.Type {
+	case "text":
+		return s.sanitizeContent(s.expandIncludes(ctx, noteID, content, append(slices.Clip(path), noteID))), true
+	case "code":
+		return codeNoteHTML(note, content), true
+	default:
+		return "", false
+	}
+}
+
+// recordInclude notes that every note on path depends on includedID. Posts
+// render concurrently, so the read-modify-write of the entry is serialized.
+func (s *Service) recordInclude(includedID string, path []string) {
+	s.includeDepsMu.Lock()
+	defer s.includeDepsMu.Unlock()
+	var includers []string
+	s.cache.readJSON(policyIncludeDeps, includedID, &includers)
+	changed := false
+	for _, id := range path {
+		if !slices.Contains(includers, id) {
+			includers = append(includers, id)
+			changed = true
+		}
+	}
+	if changed {
+		s.cache.writeJSON(policyIncludeDeps, includedID, includers)
+	}
+}
+
+// invalidateIncluders drops the expanded content of the posts that include
+// noteID, directly or through other included notes.
+func (s *Service) invalidateIncluders(noteID string) {
+	var includers []string
+	if !s.cache.readJSON(policyIncludeDeps, noteID, &includers) {
+		return
+	}
+	for _, id := range includers {
+		s.cache.del(policyPostContent, id)
+	}
+}
@@ -0,0 +1,112 @@
+package blog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestGetPostExpandsIncludedNotes(t *testing.T) {
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{NoteID: "post", Title: "Post", Attributes: []etapi.Attribute{etapitest.Label("blog", "true")}},
+		`<p>Intro.</p><section class="include-note" data-note-id="snippet" data-box-size="full">&nbsp;</section>`+
+			`<section class="include-note" data-note-id="script">&nbsp;</section>`)
+	fake.AddNote(etapi.Note{NoteID: "snippet", Title: "Snippet"},
+		`<p>Shared snippet.</p><section class="include-note" data-note-id="nested">&nbsp;</section>`)
+	fake.AddNote(etapi.Note{NoteID: "nested", Title: "Nested"},
+		`<p>Nested text.</p><section class="include-note" data-note-id="snippet">&nbsp;</section>`)
+	fake.AddNote(etapi.Note{NoteID: "script", Title: "Script", Type: "code"}, `echo "<hi>"`)
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+
+	cache, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new cache: %v", err)
+	}
+	service := NewService(etapi.NewClient(trilium.URL, "token"), cache)
+
+	post, err := service.GetPostContext(t.Context(), "post")
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	for _, want := range []string{"Intro.", "Shared snippet.", "Nested text.", "echo &#34;&lt;hi&gt;&#34;"} {
+		if !strings.Contains(post.ContentHTML, want) {
+			t.Fatalf("expected %q in %s", want, post.ContentHTML)
+		}
+	}
+	if strings.Count(post.ContentHTML, "Shared snippet.") != 1 {
+		t.Fatalf("expected the include cycle to be cut, got %s", post.ContentHTML)
+	}
+
+	// Editing a note included two levels down refreshes the post.
+	fake.SetContent("nested", "<p>Edited nested text.</p>")
+	post, _ = service.GetPostContext(t.Context(), "post")
+	if strings.Contains(post.ContentHTML, "Edited") {
+		t.Fatalf("expected the cached expansion before invalidation")
+	}
+	service.InvalidateNote("nested")
+	post, err = service.GetPostContext(t.Context(), "post")
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if !strings.Contains(post.ContentHTML, "Edited nested text.") {
+		t.Fatalf("expected the edited include after invalidation, got %s", post.ContentHTML)
+	}
+}
+
+func TestExpandIncludesStopsAtMaxDepth(t *testing.T) {
+	fake := etapitest.New()
+	for i := range maxIncludeDepth + 2 {
+		id := "n" + string(rune('0'+i))
+		next := "n" + string(rune('0'+i+1))
+		fake.AddNote(etapi.Note{NoteID: id, Title: id},
+			"<p>level "+id+"</p>"+`<section class="include-note" data-note-id="`+next+`"></section>`)
+	}
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+	service := NewService(etapi.NewClient(trilium.URL, "token"), &NoopStore{})
+
+	content, err := service.getCachedNoteContent(t.Context(), "n0")
+	if err != nil {
+		t.Fatalf("get content failed: %v", err)
+	}
+	expanded := service.expandIncludes(t.Context(), "n0", content, []string{"n0"})
+	if !strings.Contains(expanded, "level n3") || strings.Contains(expanded, "level n4") {
+		t.Fatalf("expected includes to stop after %d levels, got %s", maxIncludeDepth, expanded)
+	}
+}
+
+// slowStore returns reads late, widening the window between reading and
+// writing a cache entry.
+type slowStore struct{ *memoryStore }
+
+func (s slowStore) Get(key string) (string, error) {
+	value, err := s.memoryStore.Get(key)
+	time.Sleep(time.Millisecond)
+	return value, err
+}
+
+func TestRecordIncludeKeepsConcurrentIncluders(t *testing.T) {
+	service := NewService(etapi.NewClient("http://127.0.0.1:1", "token"), slowStore{newMemoryStore()})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.recordInclude("snippet", []string{fmt.Sprintf("post-%d", i)})
+		}()
+	}
+	wg.Wait()
+
+	var includers []string
+	service.cache.readJSON(policyIncludeDeps, "snippet", &includers)
+	if len(includers) != 20 {
+		t.Fatalf("expected every includer to be recorded, got %v", includers)
+	}
+}
@@ -0,0 +1,151 @@
+package blog
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+)
+
+// noteLinkTarget returns the noteId an internal Trilium link points to.
+// Trilium links to notes by their path, e.g. "#root/abc/def", where the last
+// segment is the target; the path may also follow the Trilium server URL.
+func noteLinkTarget(href string) string {
+	i := strings.Index(href, "#root")
+	if i < 0 {
+		return ""
+	}
+	path := href[i+1:]
+	if j := strings.IndexAny(path, "?&"); j >= 0 {
+		path = path[:j]
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	target := segments[len(segments)-1]
+	if target == "" || target == "root" {
+		return ""
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	return target
+}
+
+// postURL is the blog address of a published post.
+func postURL(noteID string) string {
+	return "/post/" + url.PathEscape(noteID)
+}
+
+// publishedPostIDs returns the noteIds of the published blog posts.
+func (s *Service) publishedPostIDs(ctx context.Context) (map[string]bool, error) {
+	notes, err := s.getCachedNotes(ctx, "#blog=true")
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(notes))
+	for _, n := range notes {
+		if isPost(n) {
+			ids[n.NoteID] = true
+		}
+	}
+	return ids, nil
+}
+
+// resolveNoteLinks rewrites internal Trilium links in rendered HTML: links
+// to published posts point to the post on the blog, and links to any other
+// note are replaced by their text, since readers cannot open them.
+func (s *Service) resolveNoteLinks(ctx context.Context, html string) string {
+	if !strings.Contains(html, "#root") {
+		return html
+	}
+	published, err := s.publishedPostIDs(ctx)
+	if err != nil {
+		logger.Error("Failed to list posts for note links; unwrapping them", err)
+		published = nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return html
+	}
+
+	doc.Find("a[href]").Each(func(_ int, sel *goquery.Selection) {
+		target := noteLinkTarget(sel.AttrOr("href", ""))
+		if target == "" {
+			return
+		}
+		if published[target] {
+			sel.SetAttr("href", postURL(target))
+			// Links between posts stay on the site; nofollow is for
+			// external links.
+			sel.RemoveAttr("rel")
+			return
+		}
+		sel.ReplaceWithSelection(sel.Contents())
+	})
+
+	result, _ := doc.Find("body").Html()
+	if result == "" {
+		return html
+	}
+	return strings.TrimSpace(result)
+}
+
+// linkedNoteIDs returns the notes an HTML body links to internally.
+func linkedNoteIDs(html string) map[string]bool {
+	ids := make(map[string]bool)
+	if !strings.Contains(html, "#root") {
+		return ids
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ids
+	}
+	doc.Find("a[href]").Each(func(_ int, sel *goquery.Selection) {
+		if target := noteLinkTarget(sel.AttrOr("href", "")); target != "" {
+			ids[target] = true
+		}
+	})
+	return ids
+}
+
+// BacklinksContext lists the published posts that link to noteID, most
+// recently modified first.
+func (s *Service) BacklinksContext(ctx context.Context, noteID string) (*Backlinks, error) {
+	note, err := s.getCachedNote(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if !isPost(*note) {
+		return nil, ErrNotBlogPost
+	}
+
+	notes, err := s.getCachedNotes(ctx, "#blog=true")
+	if err != nil {
+		return nil, err
+	}
+	result := &Backlinks{NoteID: noteID, Items: []Post{}}
+	for _, n := range notes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if n.NoteID == noteID || !isPost(n) {
+			continue
+		}
+		content, err := s.getPostContent(ctx, &n)
+		if err != nil || !linkedNoteIDs(content)[noteID] {
+			continue
+		}
+		result.Items = append(result.Items, Post{
+			NoteID:       n.NoteID,
+			Title:        n.Title,
+			DateModified: n.DateModified,
+			Summary:      s.extractSummary(s.sanitizeContent(content)),
+		})
+	}
+	sort.SliceStable(result.Items, func(i, j int) bool {
+		return parseDate(result.Items[i].DateModified).After(parseDate(result.Items[j].DateModified))
+	})
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package blog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestNoteLinkTarget(t *testing.T) {
+	for href, want := range map[string]string{
+		"#root/abc/def":                     "def",
+		"#root/abc/def?viewMode=source":     "def",
+		"https://notes.example.com/#root/x": "x",
+		"#root":                             "",
+		"#heading":                          "",
+		"https://example.com/page":          "",
+	} {
+		if got := noteLinkTarget(href); got != want {
+			t.Fatalf("noteLinkTarget(%q) = %q, want %q", href, got, want)
+		}
+	}
+}
+
+func TestGetPostResolvesNoteLinksAndBacklinks(t *testing.T) {
+	fake := etapitest.New()
+	blogLabel := []etapi.Attribute{etapitest.Label("blog", "true")}
+	fake.AddNote(etapi.Note{NoteID: "intro", Title: "Intro", DateModified: "2026-04-13T12:00:00Z", Attributes: blogLabel},
+		`<p>See <a class="reference-link" href="#root/dir/channels">Channels</a> and <a href="#root/private">my <b>notes</b></a>.</p>`)
+	fake.AddNote(etapi.Note{NoteID: "channels", Title: "Channels", DateModified: "2026-04-12T12:00:00Z", Attributes: blogLabel},
+		`<p>Back to the <a href="#root/intro">intro</a>.</p>`)
+	fake.AddNote(etapi.Note{NoteID: "private", Title: "Private"}, "<p>Drafts.</p>")
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+	service := NewService(etapi.NewClient(trilium.URL, "token"), &NoopStore{})
+
+	post, err := service.GetPostContext(t.Context(), "intro")
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if !strings.Contains(post.ContentHTML, `<a href="/post/channels">Channels</a>`) {
+		t.Fatalf("expected the link to point to the published post, got %s", post.ContentHTML)
+	}
+	if strings.Contains(post.ContentHTML, "private") || !strings.Contains(post.ContentHTML, "my <b>notes</b>") {
+		t.Fatalf("expected the unpublished link to be unwrapped, got %s", post.ContentHTML)
+	}
+
+	backlinks, err := service.BacklinksContext(t.Context(), "channels")
+	if err != nil {
+		t.Fatalf("backlinks failed: %v", err)
+	}
+	if len(backlinks.Items) != 1 || backlinks.Items[0].NoteID != "intro" || backlinks.Items[0].Summary == "" {
+		t.Fatalf("expected intro to link to channels, got %#v", backlinks.Items)
+	}
+	if _, err := service.BacklinksContext(t.Context(), "private"); err == nil {
+		t.Fatalf("expected an error for a note that is not a post")
+	}
+}
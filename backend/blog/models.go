@@ -3,15 +3,37 @@ package blog
 import "time"
 
 type Post struct {
-	NoteID       string      `json:"noteId"`
-	Title        string      `json:"title"`
-	DateModified string      `json:"dateModified"`
-	Summary      string      `json:"summary,omitempty"`
-	Summaries    *Summaries  `json:"summaries,omitempty"`
-	TOC          []TOCItem   `json:"toc,omitempty"`
-	CodeBlocks   []CodeBlock `json:"codeBlocks,omitempty"`
-	ContentHTML  string      `json:"contentHtml,omitempty"`
-	PageURL      string      `json:"pageUrl,omitempty"`
+	NoteID       string     `json:"noteId"`
+	Title        string     `json:"title"`
+	DateModified string     `json:"dateModified"`
+	Summary      string     `json:"summary,omitempty"`
+	Summaries    *Summaries `json:"summaries,omitempty"`
+	TOC          []TOCItem  `json:"toc,omitempty"`
+	// TOCTree holds the TOC entries nested under the heading they follow.
+	TOCTree     []TOCItem   `json:"tocTree,omitempty"`
+	CodeBlocks  []CodeBlock `json:"codeBlocks,omitempty"`
+	ContentHTML string      `json:"contentHtml,omitempty"`
+	PageURL     string      `json:"pageUrl,omitempty"`
+	// Tags, Keywords and Description come from the note's labels, or from
+	// AI suggestions when the labels are not set.
+	Tags        []string `json:"tags,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	Description string   `json:"description,omitempty"`
+	// Lang is the language the post is served in; Translation describes the
+	// machine translation when another language than the post's was asked.
+	Lang        string           `json:"lang,omitempty"`
+	Translation *PostTranslation `json:"translation,omitempty"`
+}
+
+// PostTranslation describes the machine translation of a post. Until it is
+// ready, Status reports its progress and the post is served untranslated.
+type PostTranslation struct {
+	Lang              string           `json:"lang"`
+	SourceLang        string           `json:"sourceLang"`
+	Status            string           `json:"status"`
+	MachineTranslated bool             `json:"machineTranslated"`
+	UpdatedAt         string           `json:"updatedAt,omitempty"`
+	Progress          *SummaryProgress `json:"progress,omitempty"`
 }
 
 type CodeBlock struct {
@@ -20,14 +42,36 @@ type CodeBlock struct {
 	LanguageLabel   string `json:"languageLabel"`
 	DetectedBy      string `json:"detectedBy,omitempty"`
 	ShowLineNumbers bool   `json:"showLineNumbers"`
+	// Title, HighlightLines, StartLine and Collapsed come from the block's
+	// @code directive or the note's #code* labels. HighlightLines are
+	// numbered like the displayed lines, which start at StartLine.
+	Title          string `json:"title,omitempty"`
+	HighlightLines []int  `json:"highlightLines,omitempty"`
+	StartLine      int    `json:"startLine,omitempty"`
+	Collapsed      bool   `json:"collapsed,omitempty"`
+	// Highlighted is set when the block was highlighted on the server and
+	// needs the stylesheet at Site.HighlightCSS instead of client-side
+	// highlighting.
+	Highlighted bool `json:"highlighted,omitempty"`
 }
 
 type SummaryEntry struct {
-	Type      string `json:"type,omitempty"`
-	Status    string `json:"status,omitempty"`
-	Text      string `json:"text,omitempty"`
-	UpdatedAt string `json:"updatedAt,omitempty"`
-	Error     string `json:"error,omitempty"`
+	Type        string           `json:"type,omitempty"`
+	Status      string           `json:"status,omitempty"`
+	Text        string           `json:"text,omitempty"`
+	UpdatedAt   string           `json:"updatedAt,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	Attempts    int              `json:"attempts,omitempty"`
+	NextRetryAt string           `json:"nextRetryAt,omitempty"`
+	History     []SummaryAttempt `json:"history,omitempty"`
+	Pinned      bool             `json:"pinned,omitempty"`
+	Progress    *SummaryProgress `json:"progress,omitempty"`
+}
+
+// SummaryProgress counts the steps of a summary generated in parts.
+type SummaryProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
 }
 
 type Summaries struct {
@@ -35,12 +79,17 @@ type Summaries struct {
 	AIEnabled bool          `json:"aiEnabled"`
 	AI        *SummaryEntry `json:"ai,omitempty"`
 	Code      *SummaryEntry `json:"code,omitempty"`
+	// AI suggestions for SEO, present when they are enabled.
+	Tags        *SummaryEntry `json:"tags,omitempty"`
+	Keywords    *SummaryEntry `json:"keywords,omitempty"`
+	Description *SummaryEntry `json:"description,omitempty"`
 }
 
 type TOCItem struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
-	Level int    `json:"level"`
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Level    int       `json:"level"`
+	Children []TOCItem `json:"children,omitempty"`
 }
 
 type PostList struct {
@@ -67,12 +116,59 @@ type SearchMatch struct {
 	Snippet      string `json:"snippet"`
 }
 
+// Backlinks lists the published posts that link to NoteID.
+type Backlinks struct {
+	NoteID string `json:"noteId"`
+	Items  []Post `json:"items"`
+}
+
+// AskAnswer answers a reader's question from the published posts. Sources
+// are the posts the answer cites as [Index].
+type AskAnswer struct {
+	Question  string      `json:"question"`
+	Answer    string      `json:"answer"`
+	Sources   []AskSource `json:"sources"`
+	CreatedAt string      `json:"createdAt"`
+	Cached    bool        `json:"cached,omitempty"`
+}
+
+type AskSource struct {
+	Index  int    `json:"index"`
+	NoteID string `json:"noteId"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// RelatedPosts lists the posts most similar to NoteID. Method is
+// "embedding" when they were ranked by embeddings and "overlap" when by
+// shared tags and terms.
+type RelatedPosts struct {
+	NoteID string        `json:"noteId"`
+	Method string        `json:"method"`
+	Items  []RelatedPost `json:"items"`
+}
+
+type RelatedPost struct {
+	NoteID       string  `json:"noteId"`
+	Title        string  `json:"title"`
+	DateModified string  `json:"dateModified"`
+	Summary      string  `json:"summary,omitempty"`
+	Score        float64 `json:"score"`
+}
+
 type Site struct {
 	Title      string           `json:"title"`
 	Subtitle   string           `json:"subtitle"`
 	Domain     string           `json:"domain"`
 	Locale     string           `json:"locale"`
 	ImageProxy ImageProxyConfig `json:"imageProxy"`
+	// Translation reports whether posts can be requested in another language.
+	Translation bool `json:"translation"`
+	// Ask reports whether /api/ask answers questions.
+	Ask bool `json:"ask"`
+	// HighlightCSS is the stylesheet for server-highlighted code blocks,
+	// empty when code is highlighted in the browser.
+	HighlightCSS string `json:"highlightCss,omitempty"`
 }
 
 type ImageProxyConfig struct {
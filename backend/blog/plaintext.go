@@ -0,0 +1,276 @@
+package blog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+const (
+	// summaryCodeMaxRunes is the longest code block kept in the text sent
+	// to the AI provider; longer blocks become a one-line placeholder.
+	summaryCodeMaxRunes = 600
+	// summaryTableMaxRows caps how many table rows are kept for the AI.
+	summaryTableMaxRows = 20
+)
+
+// plainTextOptions controls extractPlainText.
+type plainTextOptions struct {
+	// Markup keeps heading levels, list bullets, code fences and image alt
+	// text in a Markdown-like form. Without it only the words remain.
+	Markup bool
+	// MaxCodeRunes is the longest code block that is kept. 0 drops code,
+	// including inline code.
+	MaxCodeRunes int
+	// MaxTableRows caps the rows kept per table; 0 keeps them all.
+	MaxTableRows int
+}
+
+// htmlToPlainText returns the words of an article, one block per line, for
+// search indexing and snippets. Code is left out.
+func htmlToPlainText(content string) string {
+	return extractPlainText(content, plainTextOptions{})
+}
+
+// extractSummaryText turns sanitized article HTML into compact text for AI
+// summaries: "#" heading lines, "-" and "1." list items, short fenced code
+// blocks and tables as "a | b" rows. Attributes, images and embedded media
+// are dropped.
+func extractSummaryText(sanitized string) string {
+	return extractPlainText(sanitized, plainTextOptions{
+		Markup:       true,
+		MaxCodeRunes: summaryCodeMaxRunes,
+		MaxTableRows: summaryTableMaxRows,
+	})
+}
+
+func extractPlainText(content string, opts plainTextOptions) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return ""
+	}
+	w := &plainTextWriter{opts: opts}
+	for _, n := range doc.Find("body").Nodes {
+		w.children(n)
+	}
+	w.flush()
+	return strings.TrimSpace(w.out.String())
+}
+
+type plainTextList struct {
+	ordered bool
+	n       int
+}
+
+type plainTextWriter struct {
+	opts   plainTextOptions
+	out    strings.Builder
+	inline strings.Builder
+	prefix string
+	lists  []plainTextList
+	// gap puts a blank line before the next line, to set headings apart.
+	gap bool
+}
+
+// flush ends the current block and writes it on its own line.
+func (w *plainTextWriter) flush() {
+	text := strings.Join(strings.Fields(w.inline.String()), " ")
+	w.inline.Reset()
+	if text == "" {
+		return
+	}
+	w.line(w.prefix + text)
+	w.prefix = ""
+}
+
+func (w *plainTextWriter) line(text string) {
+	if w.out.Len() > 0 {
+		w.out.WriteByte('\n')
+		if w.gap {
+			w.out.WriteByte('\n')
+		}
+	}
+	w.gap = false
+	w.out.WriteString(text)
+}
+
+func (w *plainTextWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+func (w *plainTextWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.inline.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+
+	switch n.Data {
+	case "script", "style", "svg", "canvas", "video", "audio", "iframe", "object", "embed", "noscript", "template":
+	case "img":
+		if alt := strings.TrimSpace(attr(n, "alt")); w.opts.Markup && alt != "" {
+			w.inline.WriteString(" [image: " + alt + "] ")
+		}
+	case "br", "hr":
+		w.flush()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		w.flush()
+		if w.opts.Markup {
+			level, _ := strconv.Atoi(n.Data[1:])
+			w.prefix = strings.Repeat("#", level) + " "
+			w.gap = true
+		}
+		w.children(n)
+		w.flush()
+		w.prefix = ""
+	case "ul", "ol":
+		w.flush()
+		w.lists = append(w.lists, plainTextList{ordered: n.Data == "ol"})
+		w.children(n)
+		w.flush()
+		w.lists = w.lists[:len(w.lists)-1]
+	case "li":
+		w.flush()
+		if w.opts.Markup && len(w.lists) > 0 {
+			list := &w.lists[len(w.lists)-1]
+			list.n++
+			bullet := "- "
+			if list.ordered {
+				bullet = strconv.Itoa(list.n) + ". "
+			}
+			w.prefix = strings.Repeat("  ", len(w.lists)-1) + bullet
+		}
+		w.children(n)
+		w.flush()
+		w.prefix = ""
+	case "pre":
+		w.flush()
+		w.code(n)
+	case "code":
+		if w.opts.MaxCodeRunes <= 0 {
+			return
+		}
+		if text := strings.TrimSpace(nodeText(n)); text != "" {
+			w.inline.WriteString(" `" + text + "` ")
+		}
+	case "table":
+		w.flush()
+		w.table(n)
+	case "p", "div", "section", "article", "header", "footer", "main", "aside", "nav",
+		"blockquote", "figure", "figcaption", "dl", "dt", "dd", "details", "summary":
+		w.flush()
+		w.children(n)
+		w.flush()
+	default:
+		w.children(n)
+	}
+}
+
+// code writes a <pre> block as a fenced snippet, or a placeholder when it is
+// longer than MaxCodeRunes.
+func (w *plainTextWriter) code(n *html.Node) {
+	if w.opts.MaxCodeRunes <= 0 {
+		return
+	}
+	text := strings.Trim(nodeText(n), "\n")
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	lang := ""
+	if c := firstElement(n, "code"); c != nil {
+		lang, _, _ = normalizeCodeLanguageClass(attr(c, "class"))
+	}
+	if len([]rune(text)) > w.opts.MaxCodeRunes {
+		lines := strings.Count(text, "\n") + 1
+		label := "[code"
+		if lang != "" {
+			label += " (" + lang + ")"
+		}
+		w.line(fmt.Sprintf("%s, %d lines omitted]", label, lines))
+		return
+	}
+	w.line("```" + lang + "\n" + text + "\n```")
+}
+
+// table writes each row as its cells separated by " | ".
+func (w *plainTextWriter) table(n *html.Node) {
+	var rows []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.Data != "tr" {
+				walk(c)
+				continue
+			}
+			var cells []string
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+					cells = append(cells, strings.Join(strings.Fields(nodeText(cell)), " "))
+				}
+			}
+			if strings.TrimSpace(strings.Join(cells, "")) != "" {
+				rows = append(rows, strings.Join(cells, " | "))
+			}
+		}
+	}
+	walk(n)
+
+	limit := len(rows)
+	if w.opts.MaxTableRows > 0 && limit > w.opts.MaxTableRows {
+		limit = w.opts.MaxTableRows
+	}
+	for _, row := range rows[:limit] {
+		w.line(row)
+	}
+	if limit < len(rows) {
+		w.line(fmt.Sprintf("[%d more rows omitted]", len(rows)-limit))
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func firstElement(n *html.Node, tag string) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == tag {
+			return c
+		}
+		if found := firstElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "br" {
+			b.WriteByte('\n')
+			continue
+		}
+		b.WriteString(nodeText(c))
+	}
+	return b.String()
+}
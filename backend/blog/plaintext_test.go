@@ -0,0 +1,61 @@
+package blog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractSummaryText(t *testing.T) {
+	input := `<h2 style="color:red">Install</h2>
+<p class="x">Run <code>make</code> first.<img src="data:image/png;base64,AAAA" alt="diagram"></p>
+<ul><li>One</li><li>Two<ol><li>Nested</li></ol></li></ul>
+<pre><code class="language-text-x-go">fmt.Println("hi")</code></pre>
+<pre><code>` + strings.Repeat("x := 1\n", 200) + `</code></pre>
+<table><tr><th>Key</th><th>Value</th></tr><tr><td>a</td><td>1</td></tr></table>
+<h3>Next</h3><p>Done.</p>`
+
+	got := extractSummaryText(sanitizeContentForSummary(input))
+	want := "## Install\n" +
+		"Run `make` first. [image: diagram]\n" +
+		"- One\n" +
+		"- Two\n" +
+		"  1. Nested\n" +
+		"```go\nfmt.Println(\"hi\")\n```\n" +
+		"[code, 200 lines omitted]\n" +
+		"Key | Value\n" +
+		"a | 1\n" +
+		"\n### Next\n" +
+		"Done."
+	if got != want {
+		t.Fatalf("unexpected summary text:\n%s\n--- want ---\n%s", got, want)
+	}
+	for _, fragment := range []string{"style", "class", "base64", "<"} {
+		if strings.Contains(got, fragment) {
+			t.Fatalf("expected %q to be stripped, got %q", fragment, got)
+		}
+	}
+}
+
+func TestExtractSummaryTextCapsTables(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<table>")
+	for i := 0; i < summaryTableMaxRows+5; i++ {
+		b.WriteString("<tr><td>cell</td></tr>")
+	}
+	b.WriteString("</table>")
+
+	got := extractSummaryText(b.String())
+	if n := strings.Count(got, "cell"); n != summaryTableMaxRows {
+		t.Fatalf("expected %d rows, got %d", summaryTableMaxRows, n)
+	}
+	if !strings.HasSuffix(got, "[5 more rows omitted]") {
+		t.Fatalf("expected omitted rows note, got %q", got)
+	}
+}
+
+func TestHTMLToPlainTextSeparatesBlocksAndDropsCode(t *testing.T) {
+	got := htmlToPlainText(`<h2>Title</h2><p>First<br>line</p><p>Second <code>secret()</code></p><ul><li>Item</li></ul><pre>code()</pre>`)
+	if got != "Title\nFirst\nline\nSecond\nItem" {
+		t.Fatalf("unexpected plain text %q", got)
+	}
+}
@@ -0,0 +1,302 @@
+package blog
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+)
+
+const (
+	defaultRelatedLimit = 5
+	maxRelatedLimit     = 20
+	// Weights of the overlap fallback; tags say more about the topic than
+	// shared words do.
+	relatedTagWeight  = 0.6
+	relatedTermWeight = 0.4
+)
+
+// policyRelatedPosts caches the ranked related posts of a post, keyed by
+// its content and the embedding model. The TTL bounds how long newly
+// published posts and newly computed embeddings take to show up.
+var policyRelatedPosts = cachePolicy{
+	Prefix: "related", Version: 1, TTLSeconds: 600,
+}
+
+// relatedCandidate is a published post considered for the related list.
+type relatedCandidate struct {
+	note    etapi.Note
+	content string
+}
+
+// RelatedPostsContext returns up to limit posts similar to noteID. Posts are
+// ranked by the cosine similarity of their embeddings when embeddings are
+// enabled and computed for the post; otherwise by shared tags and terms.
+// The ranking is cached per post.
+func (s *Service) RelatedPostsContext(ctx context.Context, noteID string, limit int) (*RelatedPosts, error) {
+	if limit <= 0 {
+		limit = defaultRelatedLimit
+	}
+	limit = min(limit, maxRelatedLimit)
+
+	note, err := s.getCachedNote(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if !isPost(*note) {
+		return nil, ErrNotBlogPost
+	}
+	content, err := s.getPostContent(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+
+	model := ""
+	if s.aiQueue != nil && s.aiEnabled {
+		model = s.aiQueue.EmbeddingModel()
+	}
+	cacheKey := noteID + ":" + contentHash(content+"\x00"+model)
+	var result RelatedPosts
+	if !s.cache.readJSON(policyRelatedPosts, cacheKey, &result) {
+		ranked, err := s.rankRelatedPosts(ctx, note, content)
+		if err != nil {
+			return nil, err
+		}
+		result = *ranked
+		s.cache.writeJSON(policyRelatedPosts, cacheKey, result)
+	}
+	if len(result.Items) > limit {
+		result.Items = result.Items[:limit]
+	}
+	return &result, nil
+}
+
+// rankRelatedPosts ranks the published posts by their similarity to note,
+// keeping the best maxRelatedLimit.
+func (s *Service) rankRelatedPosts(ctx context.Context, note *etapi.Note, content string) (*RelatedPosts, error) {
+	noteID := note.NoteID
+	notes, err := s.getCachedNotes(ctx, "#blog=true")
+	if err != nil {
+		return nil, err
+	}
+	var target *relatedCandidate
+	var others []relatedCandidate
+	for _, n := range notes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !isPost(n) {
+			continue
+		}
+		content, err := s.getPostContent(ctx, &n)
+		if err != nil {
+			continue
+		}
+		if n.NoteID == noteID {
+			target = &relatedCandidate{note: n, content: content}
+			continue
+		}
+		others = append(others, relatedCandidate{note: n, content: content})
+	}
+	if target == nil {
+		target = &relatedCandidate{note: *note, content: content}
+	}
+
+	result := &RelatedPosts{NoteID: noteID, Method: "overlap", Items: []RelatedPost{}}
+	scores, embedded, ok := s.embeddingScores(*target, others)
+	if ok {
+		result.Method = "embedding"
+		// Posts whose vector is not computed yet are ranked by overlap
+		// rather than left out.
+		var missing []int
+		var pending []relatedCandidate
+		for i := range others {
+			if !embedded[i] {
+				missing = append(missing, i)
+				pending = append(pending, others[i])
+			}
+		}
+		for j, score := range s.overlapScores(*target, pending) {
+			scores[missing[j]] = score
+		}
+	} else {
+		scores = s.overlapScores(*target, others)
+	}
+
+	order := make([]int, 0, len(others))
+	for i := range others {
+		if scores[i] > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if scores[order[a]] != scores[order[b]] {
+			return scores[order[a]] > scores[order[b]]
+		}
+		return others[order[a]].note.DateModified > others[order[b]].note.DateModified
+	})
+	if len(order) > maxRelatedLimit {
+		order = order[:maxRelatedLimit]
+	}
+	for _, i := range order {
+		c := others[i]
+		summary := s.extractSummary(s.sanitizeContent(c.content))
+		if s.summaryStore != nil {
+			if ai, err := s.summaryStore.GetSummary(c.note.NoteID, "ai"); err == nil && ai != nil && ai.Status == "ready" && ai.Content != "" {
+				summary = ai.Content
+			}
+		}
+		result.Items = append(result.Items, RelatedPost{
+			NoteID:       c.note.NoteID,
+			Title:        c.note.Title,
+			DateModified: c.note.DateModified,
+			Summary:      summary,
+			Score:        scores[i],
+		})
+	}
+	return result, nil
+}
+
+// embeddingScores queues embeddings for posts whose content changed and
+// scores others by cosine similarity to target; embedded tells which of
+// them have a vector. ok is false when embeddings are disabled or the
+// target has no vector yet.
+func (s *Service) embeddingScores(target relatedCandidate, others []relatedCandidate) (scores []float64, embedded []bool, ok bool) {
+	if s.aiQueue == nil || !s.aiEnabled || s.aiQueue.EmbeddingModel() == "" || s.summaryStore == nil {
+		return nil, nil, false
+	}
+	model := s.aiQueue.EmbeddingModel()
+	s.ensureEmbedding(target.note, target.content, model)
+	for _, c := range others {
+		s.ensureEmbedding(c.note, c.content, model)
+	}
+
+	vectors, err := s.aiQueue.embeddings.ListEmbeddings(model)
+	if err != nil {
+		logger.Error("Failed to load embeddings; falling back to overlap", err)
+		return nil, nil, false
+	}
+	targetVec, ok := vectors[target.note.NoteID]
+	if !ok {
+		return nil, nil, false
+	}
+	scores = make([]float64, len(others))
+	embedded = make([]bool, len(others))
+	for i, c := range others {
+		if vec, ok := vectors[c.note.NoteID]; ok {
+			scores[i] = cosineSimilarity(targetVec.Vector, vec.Vector)
+			embedded[i] = true
+		}
+	}
+	return scores, embedded, true
+}
+
+// ensureEmbedding queues an "embedding" job when the note has no embedding
+// row for its current content and model. Like the summaries, the row
+// tracks the status of the job; the vector itself is in the EmbeddingStore.
+func (s *Service) ensureEmbedding(note etapi.Note, content, model string) {
+	if !hasReadableText(note.Type) {
+		return
+	}
+	hash := summaryPromptHash(contentHash(content), "embedding:"+model)
+	item, err := s.summaryStore.GetSummary(note.NoteID, "embedding")
+	if err != nil || (item != nil && item.SourceHash == hash && item.Status != "") {
+		return
+	}
+	_ = s.summaryStore.UpsertSummary(StoredSummary{
+		NoteID:     note.NoteID,
+		Type:       "embedding",
+		Status:     "pending",
+		SourceHash: hash,
+	})
+	s.aiQueue.Enqueue(AISummaryJob{
+		NoteID:     note.NoteID,
+		Type:       "embedding",
+		Title:      note.Title,
+		Content:    extractSummaryText(sanitizeContentForSummary(content)),
+		SourceHash: hash,
+	})
+}
+
+// overlapScores scores others by the Jaccard similarity of their tags and of
+// the terms of their titles and text.
+func (s *Service) overlapScores(target relatedCandidate, others []relatedCandidate) []float64 {
+	targetTags := s.relatedTags(target.note)
+	targetTerms := relatedTerms(target.note.Title, target.content)
+	scores := make([]float64, len(others))
+	for i, c := range others {
+		scores[i] = relatedTagWeight*jaccard(targetTags, s.relatedTags(c.note)) +
+			relatedTermWeight*jaccard(targetTerms, relatedTerms(c.note.Title, c.content))
+	}
+	return scores
+}
+
+// relatedTags returns the lower-cased #tag labels of a note, or its ready
+// AI tag suggestions when it has none.
+func (s *Service) relatedTags(note etapi.Note) map[string]bool {
+	tags := noteTags(note.Attributes)
+	if len(tags) == 0 && s.summaryStore != nil {
+		if item, err := s.summaryStore.GetSummary(note.NoteID, "tags"); err == nil && item != nil && item.Status == "ready" {
+			tags = splitSEOList(item.Content)
+		}
+	}
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[strings.ToLower(tag)] = true
+	}
+	return set
+}
+
+// relatedTerms returns the words of a post. Runs of Han characters, which
+// have no spaces between words, contribute their bigrams instead.
+func relatedTerms(title, content string) map[string]bool {
+	terms := make(map[string]bool)
+	text := normalizeSearchText(title + " " + htmlToPlainText(content))
+	for _, word := range strings.Fields(text) {
+		var han, latin []rune
+		flush := func() {
+			if len(han) == 1 {
+				terms[string(han)] = true
+			}
+			for i := 0; i+1 < len(han); i++ {
+				terms[string(han[i:i+2])] = true
+			}
+			// Very short words are mostly noise such as "a" or "of".
+			if len(latin) > 2 {
+				terms[string(latin)] = true
+			}
+			han, latin = han[:0], latin[:0]
+		}
+		for _, r := range word {
+			if unicode.Is(unicode.Han, r) {
+				if len(latin) > 0 {
+					flush()
+				}
+				han = append(han, r)
+				continue
+			}
+			if len(han) > 0 {
+				flush()
+			}
+			latin = append(latin, r)
+		}
+		flush()
+	}
+	return terms
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,199 @@
+package blog
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	if got := cosineSimilarity([]float32{1, 0}, []float32{2, 0}); math.Abs(got-1) > 1e-9 {
+		t.Fatalf("expected parallel vectors to score 1, got %v", got)
+	}
+	if got := cosineSimilarity([]float32{1, 0}, []float32{0, 3}); got != 0 {
+		t.Fatalf("expected orthogonal vectors to score 0, got %v", got)
+	}
+	if got := cosineSimilarity([]float32{1, 0}, []float32{1, 0, 0}); got != 0 {
+		t.Fatalf("expected vectors of different length to score 0, got %v", got)
+	}
+
+	vec := []float32{0.25, -1.5, 3}
+	decoded := decodeEmbedding(encodeEmbedding(vec))
+	if len(decoded) != len(vec) || decoded[0] != vec[0] || decoded[1] != vec[1] || decoded[2] != vec[2] {
+		t.Fatalf("expected vector to survive encoding, got %v", decoded)
+	}
+}
+
+func newRelatedTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	fake := etapitest.New()
+	for _, n := range []struct{ id, title, tag, content string }{
+		{"go-channels", "Go channels", "go", "<p>Channels let goroutines communicate.</p>"},
+		{"go-context", "Go context", "go", "<p>Context cancels goroutines and carries deadlines.</p>"},
+		{"rust-ownership", "Rust ownership", "rust", "<p>Ownership and borrowing in Rust.</p>"},
+	} {
+		fake.AddNote(etapi.Note{
+			NoteID:       n.id,
+			Title:        n.title,
+			DateModified: "2026-04-13T12:00:00Z",
+			Attributes:   []etapi.Attribute{etapitest.Label("blog", "true"), etapitest.Label("tag", n.tag)},
+		}, n.content)
+	}
+	return etapitest.NewServer(fake)
+}
+
+func TestRelatedPostsFallsBackToOverlap(t *testing.T) {
+	blogServer := newRelatedTestServer(t)
+	defer blogServer.Close()
+	service := NewService(etapi.NewClient(blogServer.URL, "token"), &NoopStore{})
+
+	related, err := service.RelatedPostsContext(t.Context(), "go-channels", 5)
+	if err != nil {
+		t.Fatalf("related posts failed: %v", err)
+	}
+	if related.Method != "overlap" {
+		t.Fatalf("expected overlap without embeddings, got %q", related.Method)
+	}
+	if len(related.Items) == 0 || related.Items[0].NoteID != "go-context" {
+		t.Fatalf("expected the post sharing a tag first, got %#v", related.Items)
+	}
+	for _, item := range related.Items {
+		if item.NoteID == "go-channels" {
+			t.Fatalf("expected the post itself to be left out, got %#v", related.Items)
+		}
+	}
+
+	if _, err := service.RelatedPostsContext(t.Context(), "missing", 5); err == nil {
+		t.Fatalf("expected an error for an unknown post")
+	}
+}
+
+func TestRelatedPostsRankedByEmbeddings(t *testing.T) {
+	blogServer := newRelatedTestServer(t)
+	defer blogServer.Close()
+
+	// Rust and channels point the same way, so embeddings rank them above
+	// the post that merely shares a tag.
+	embedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var body struct {
+			Model string `json:"model"`
+			Input string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		vec := []float32{0, 1}
+		if strings.Contains(body.Input, "Channels") || strings.Contains(body.Input, "Rust") {
+			vec = []float32{1, 0.1}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data":  []map[string]any{{"embedding": vec}},
+			"usage": map[string]int{"prompt_tokens": 7},
+		})
+	}))
+	defer embedServer.Close()
+
+	dir := t.TempDir()
+	store := newTestSummaryStore(t, filepath.Join(dir, "summaries.db"))
+	defer store.Close()
+	embeddings, err := NewEmbeddingStoreDB(filepath.Join(dir, "embeddings.db"))
+	if err != nil {
+		t.Fatalf("open embedding store: %v", err)
+	}
+	defer embeddings.Close()
+	embedder, err := NewOpenAIEmbedder(embedServer.URL, "token", "embed-model", nil)
+	if err != nil {
+		t.Fatalf("new embedder: %v", err)
+	}
+	queue := NewAISummaryQueue(store, "openai-compatible", "", "", "model", "prompt", 1, 1, 30000, 2000,
+		WithAIEmbeddings(embedder, embeddings))
+	defer queue.Close()
+	service := NewService(etapi.NewClient(blogServer.URL, "token"), &NoopStore{},
+		WithSummaryStore(store), WithAISummaryQueue(queue), WithAISummaryEnabled(true))
+
+	first, err := service.RelatedPostsContext(t.Context(), "go-channels", 5)
+	if err != nil {
+		t.Fatalf("related posts failed: %v", err)
+	}
+	if first.Method != "overlap" {
+		t.Fatalf("expected overlap until vectors are ready, got %q", first.Method)
+	}
+	for _, noteID := range []string{"go-channels", "go-context", "rust-ownership"} {
+		item := waitForSummaryType(t, store, noteID, "embedding", "ready")
+		if item.Content != "embed-model, 2 dimensions" {
+			t.Fatalf("unexpected embedding row %#v", item)
+		}
+	}
+
+	related, err := service.RelatedPostsContext(t.Context(), "go-channels", 5)
+	if err != nil {
+		t.Fatalf("related posts failed: %v", err)
+	}
+	if related.Method != "embedding" {
+		t.Fatalf("expected embedding ranking, got %q", related.Method)
+	}
+	if len(related.Items) != 2 || related.Items[0].NoteID != "rust-ownership" || related.Items[0].Score <= related.Items[1].Score {
+		t.Fatalf("expected the closest vector first, got %#v", related.Items)
+	}
+
+	report, err := queue.UsageReport("day", 1)
+	if err != nil || len(report.Items) != 1 || report.Items[0].InputTokens != 21 {
+		t.Fatalf("expected embedding calls to be recorded, got %#v (%v)", report, err)
+	}
+}
+
+func TestRelatedPostsRanksPostsWithoutVectorsByOverlap(t *testing.T) {
+	blogServer := newRelatedTestServer(t)
+	defer blogServer.Close()
+
+	dir := t.TempDir()
+	store := newTestSummaryStore(t, filepath.Join(dir, "summaries.db"))
+	defer store.Close()
+	embeddings, err := NewEmbeddingStoreDB(filepath.Join(dir, "embeddings.db"))
+	if err != nil {
+		t.Fatalf("open embedding store: %v", err)
+	}
+	defer embeddings.Close()
+	for noteID, vec := range map[string][]float32{"go-channels": {1, 0}, "rust-ownership": {1, 0.1}} {
+		if err := embeddings.SaveEmbedding(StoredEmbedding{NoteID: noteID, Model: "embed-model", Vector: vec}); err != nil {
+			t.Fatalf("save embedding: %v", err)
+		}
+	}
+	embedder, err := NewOpenAIEmbedder("http://127.0.0.1:1", "token", "embed-model", nil)
+	if err != nil {
+		t.Fatalf("new embedder: %v", err)
+	}
+	queue := NewAISummaryQueue(store, "openai-compatible", "", "", "model", "prompt", 1, 1000, 30000, 2000,
+		WithAIEmbeddings(embedder, embeddings))
+	defer queue.Close()
+	cache := newMemoryStore()
+	service := NewService(etapi.NewClient(blogServer.URL, "token"), cache,
+		WithSummaryStore(store), WithAISummaryQueue(queue), WithAISummaryEnabled(true))
+
+	related, err := service.RelatedPostsContext(t.Context(), "go-channels", 5)
+	if err != nil {
+		t.Fatalf("related posts failed: %v", err)
+	}
+	if related.Method != "embedding" || len(related.Items) != 2 || related.Items[0].NoteID != "rust-ownership" || related.Items[1].NoteID != "go-context" {
+		t.Fatalf("expected the post without a vector to be ranked by overlap, got %#v", related)
+	}
+
+	keys, _ := cache.Keys(policyRelatedPosts.key("go-channels:*"))
+	if len(keys) != 1 {
+		t.Fatalf("expected the ranking to be cached, got keys %v", keys)
+	}
+	related, err = service.RelatedPostsContext(t.Context(), "go-channels", 1)
+	if err != nil || len(related.Items) != 1 || related.Items[0].NoteID != "rust-ownership" {
+		t.Fatalf("expected the cached ranking cut to the limit, got %#v (%v)", related, err)
+	}
+}
@@ -0,0 +1,195 @@
+package blog
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+// noteRenderer turns a published note into the HTML body of its post.
+type noteRenderer func(s *Service, ctx context.Context, note *etapi.Note) (string, error)
+
+// noteRendererFor returns the renderer for a Trilium note type, or false
+// when notes of that type cannot be published.
+func noteRendererFor(noteType string) (noteRenderer, bool) {
+	switch noteType {
+	case "text":
+		return renderTextNote, true
+	case "code":
+		return renderCodeNote, true
+	case "mermaid":
+		return renderMermaidNote, true
+	case "book":
+		return renderBookNote, true
+	case "image":
+		return renderImageNote, true
+	case "file":
+		return renderFileNote, true
+	}
+	return nil, false
+}
+
+// isPostType reports whether notes of noteType can be published.
+func isPostType(noteType string) bool {
+	_, ok := noteRendererFor(noteType)
+	return ok
+}
+
+// hasReadableText reports whether posts of noteType have text worth
+// sending to the AI queue. Image and file posts render only a link to their
+// content, so they get no AI summaries, embeddings or translations.
+func hasReadableText(noteType string) bool {
+	return noteType != "image" && noteType != "file"
+}
+
+// isPost reports whether note is a published blog post.
+func isPost(note etapi.Note) bool {
+	return isPostType(note.Type) && hasBlogLabel(note.Attributes)
+}
+
+// getPostContent returns the HTML body of a post, rendered for its note type.
+func (s *Service) getPostContent(ctx context.Context, note *etapi.Note) (string, error) {
+	if val, ok := s.cache.get(policyPostContent, note.NoteID); ok {
+		return val, nil
+	}
+	render, ok := noteRendererFor(note.Type)
+	if !ok {
+		return "", ErrNotBlogPost
+	}
+	return render(s, ctx, note)
+}
+
+// renderTextNote returns the note's HTML with Trilium "include note"
+// sections replaced by the content of the notes they include.
+func renderTextNote(s *Service, ctx context.Context, note *etapi.Note) (string, error) {
+	content, err := s.getCachedNoteContent(ctx, note.NoteID)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(content, "include-note") {
+		return content, nil
+	}
+	expanded := s.expandIncludes(ctx, note.NoteID, content, []string{note.NoteID})
+	s.cache.set(policyPostContent, note.NoteID, expanded)
+	return expanded, nil
+}
+
+// renderCodeNote shows the note as a single code block in its language.
+func renderCodeNote(s *Service, ctx context.Context, note *etapi.Note) (string, error) {
+	content, err := s.getCachedNoteContent(ctx, note.NoteID)
+	if err != nil {
+		return "", err
+	}
+	return codeNoteHTML(note, content), nil
+}
+
+// renderMermaidNote shows the diagram source as a mermaid code block.
+func renderMermaidNote(s *Service, ctx context.Context, note *etapi.Note) (string, error) {
+	content, err := s.getCachedNoteContent(ctx, note.NoteID)
+	if err != nil {
+		return "", err
+	}
+	return `<pre><code class="language-mermaid">` + html.EscapeString(content) + "</code></pre>", nil
+}
+
+// renderBookNote renders a series index: the note's own text, if any,
+// followed by its published children in tree order. Editing a child
+// refreshes the index through the include dependencies.
+func renderBookNote(s *Service, ctx context.Context, note *etapi.Note) (string, error) {
+	content, err := s.getCachedNoteContent(ctx, note.NoteID)
+	if err != nil {
+		return "", err
+	}
+	published, err := s.publishedPostIDs(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(s.expandIncludes(ctx, note.NoteID, content, []string{note.NoteID}))
+	var items []string
+	for _, childID := range note.ChildNoteIDs {
+		if !published[childID] {
+			continue
+		}
+		child, err := s.getCachedNote(ctx, childID)
+		if err != nil {
+			continue
+		}
+		s.recordInclude(childID, []string{note.NoteID})
+		// Written as a Trilium link, so that it is resolved like the links
+		// of text posts.
+		items = append(items, `<li><a href="#root/`+html.EscapeString(note.NoteID+"/"+childID)+`">`+html.EscapeString(child.Title)+`</a></li>`)
+	}
+	if len(items) > 0 {
+		sb.WriteString("<ol>" + strings.Join(items, "") + "</ol>")
+	}
+
+	rendered := sb.String()
+	s.cache.set(policyPostContent, note.NoteID, rendered)
+	return rendered, nil
+}
+
+// renderImageNote shows the image as a figure captioned with the title.
+func renderImageNote(s *Service, ctx context.Context, note *etapi.Note) (string, error) {
+	title := html.EscapeString(note.Title)
+	return `<figure><img src="` + noteFileURL(note.NoteID) + `" alt="` + title + `"><figcaption>` + title + `</figcaption></figure>`, nil
+}
+
+// renderFileNote shows a download link with the file's type. The content
+// itself is only fetched when it is downloaded.
+func renderFileNote(s *Service, ctx context.Context, note *etapi.Note) (string, error) {
+	rendered := `<p><a href="` + noteFileURL(note.NoteID) + `">` + html.EscapeString(noteFileName(note)) + `</a></p>`
+	if note.Mime != "" {
+		rendered += `<p>` + html.EscapeString(note.Mime) + `</p>`
+	}
+	return rendered, nil
+}
+
+// codeNoteHTML renders the content of a code note as a code block, with
+// the language class Trilium gives code blocks of the note's MIME type.
+func codeNoteHTML(note *etapi.Note, content string) string {
+	class := ""
+	if note.Mime != "" {
+		mime := strings.NewReplacer("/", "-", ";env=", "-env-").Replace(strings.ToLower(note.Mime))
+		class = ` class="language-` + html.EscapeString(mime) + `"`
+	}
+	return "<pre><code" + class + ">" + html.EscapeString(content) + "</code></pre>"
+}
+
+// noteFileURL is the address the content of an image or file post is
+// served from.
+func noteFileURL(noteID string) string {
+	return "/api/files/" + noteID
+}
+
+// noteFileName is the name a file post downloads as: the name it was
+// uploaded with, or its title.
+func noteFileName(note *etapi.Note) string {
+	for _, a := range note.Attributes {
+		if a.Type == "label" && a.Name == "originalFileName" && a.Value != "" {
+			return a.Value
+		}
+	}
+	return note.Title
+}
+
+// GetNoteFile returns the content of an image or file post, its MIME type
+// and the name it downloads as. Binary content can be large, so it is read
+// from Trilium on each request rather than cached.
+func (s *Service) GetNoteFile(ctx context.Context, noteID string) ([]byte, string, string, error) {
+	note, err := s.getCachedNote(ctx, noteID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if (note.Type != "image" && note.Type != "file") || !hasBlogLabel(note.Attributes) {
+		return nil, "", "", ErrNotBlogPost
+	}
+	content, err := s.etapiClient.GetNoteContentContext(ctx, noteID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return []byte(content), note.Mime, noteFileName(note), nil
+}
@@ -0,0 +1,147 @@
+package blog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func newRendererTestService(t *testing.T) (*etapitest.Fake, *Service) {
+	t.Helper()
+	blog := []etapi.Attribute{etapitest.Label("blog", "true")}
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{NoteID: "code", Title: "main.go", Type: "code", Mime: "text/x-go", Attributes: blog},
+		"package main\n\nfunc main() { println(\"<hi>\") }\n")
+	fake.AddNote(etapi.Note{NoteID: "diagram", Title: "Flow", Type: "mermaid", Mime: "text/mermaid", Attributes: blog},
+		"graph TD; A-->B")
+	fake.AddNote(etapi.Note{NoteID: "series", Title: "Series", Type: "book", Mime: "", Attributes: blog}, "")
+	fake.AddNote(etapi.Note{NoteID: "part1", Title: "Part <1>", ParentNoteIDs: []string{"series"}, Attributes: blog},
+		"<p>First part.</p>")
+	fake.AddNote(etapi.Note{NoteID: "draft", Title: "Draft", ParentNoteIDs: []string{"series"}}, "<p>Not yet.</p>")
+	fake.AddNote(etapi.Note{NoteID: "part2", Title: "Part 2", ParentNoteIDs: []string{"series"}, Attributes: blog},
+		"<p>Second part.</p>")
+	fake.AddNote(etapi.Note{NoteID: "photo", Title: "Sunset", Type: "image", Mime: "image/png", Attributes: blog},
+		"\x89PNG")
+	fake.AddNote(etapi.Note{NoteID: "slides", Title: "Slides", Type: "file", Mime: "application/pdf",
+		Attributes: append(blog, etapitest.Label("originalFileName", "talk.pdf"))}, strings.Repeat("x", 2048))
+	fake.AddNote(etapi.Note{NoteID: "canvas", Title: "Canvas", Type: "canvas", Mime: "application/json", Attributes: blog}, "{}")
+	trilium := etapitest.NewServer(fake)
+	t.Cleanup(trilium.Close)
+
+	cache, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new cache: %v", err)
+	}
+	return fake, NewService(etapi.NewClient(trilium.URL, "token"), cache)
+}
+
+func TestGetPostRendersNoteTypes(t *testing.T) {
+	_, service := newRendererTestService(t)
+
+	tests := []struct {
+		noteID string
+		want   []string
+	}{
+		{"code", []string{`<pre><code class="language-go">`, "println(&#34;&lt;hi&gt;&#34;)"}},
+		{"diagram", []string{`<code class="language-mermaid">graph TD; A--&gt;B</code>`}},
+		{"series", []string{`<ol><li><a href="/post/part1">Part &lt;1&gt;</a></li><li><a href="/post/part2">Part 2</a></li></ol>`}},
+		{"photo", []string{`<img src="/api/files/photo" alt="Sunset"/>`, "<figcaption>Sunset</figcaption>"}},
+		{"slides", []string{`<a href="/api/files/slides" rel="nofollow">talk.pdf</a>`, "<p>application/pdf</p>"}},
+	}
+	for _, tt := range tests {
+		post, err := service.GetPostContext(t.Context(), tt.noteID)
+		if err != nil {
+			t.Fatalf("get %s failed: %v", tt.noteID, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(post.ContentHTML, want) {
+				t.Errorf("%s: expected %q in %s", tt.noteID, want, post.ContentHTML)
+			}
+		}
+	}
+
+	code, _ := service.GetPostContext(t.Context(), "code")
+	if len(code.CodeBlocks) != 1 || code.CodeBlocks[0].LanguageID != "go" {
+		t.Fatalf("expected one go code block, got %+v", code.CodeBlocks)
+	}
+	if _, err := service.GetPostContext(t.Context(), "canvas"); err != ErrNotBlogPost {
+		t.Fatalf("expected unsupported note types to be rejected, got %v", err)
+	}
+}
+
+func TestListPostsIncludesNonTextPosts(t *testing.T) {
+	_, service := newRendererTestService(t)
+
+	list, err := service.ListPostsContext(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	got := map[string]bool{}
+	for _, item := range list.Items {
+		got[item.NoteID] = true
+	}
+	for _, id := range []string{"code", "diagram", "series", "photo", "slides", "part1"} {
+		if !got[id] {
+			t.Errorf("expected %s in the post list", id)
+		}
+	}
+	if got["canvas"] {
+		t.Errorf("expected canvas notes to be left out")
+	}
+}
+
+func TestBookIndexRefreshesWhenAChildChanges(t *testing.T) {
+	fake, service := newRendererTestService(t)
+
+	if _, err := service.GetPostContext(t.Context(), "series"); err != nil {
+		t.Fatalf("get series failed: %v", err)
+	}
+	fake.AddNote(etapi.Note{NoteID: "part2", Title: "Part two", Attributes: []etapi.Attribute{etapitest.Label("blog", "true")}},
+		"<p>Second part.</p>")
+	service.InvalidateNote("part2")
+
+	post, err := service.GetPostContext(t.Context(), "series")
+	if err != nil {
+		t.Fatalf("get series failed: %v", err)
+	}
+	if !strings.Contains(post.ContentHTML, "Part two") {
+		t.Fatalf("expected the renamed child in the index, got %s", post.ContentHTML)
+	}
+}
+
+func TestGetNoteFile(t *testing.T) {
+	_, service := newRendererTestService(t)
+
+	data, mime, name, err := service.GetNoteFile(t.Context(), "slides")
+	if err != nil {
+		t.Fatalf("get file failed: %v", err)
+	}
+	if len(data) != 2048 || mime != "application/pdf" || name != "talk.pdf" {
+		t.Fatalf("unexpected file: %d bytes, %q, %q", len(data), mime, name)
+	}
+	if _, _, _, err := service.GetNoteFile(t.Context(), "code"); err != ErrNotBlogPost {
+		t.Fatalf("expected only image and file posts to be served, got %v", err)
+	}
+}
+
+func TestFilePostsDoNotCacheTheirBody(t *testing.T) {
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{NoteID: "slides", Title: "Slides", Type: "file", Mime: "application/pdf",
+		Attributes: []etapi.Attribute{etapitest.Label("blog", "true")}}, strings.Repeat("x", 2048))
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+	cache := newMemoryStore()
+	service := NewService(etapi.NewClient(trilium.URL, "token"), cache)
+
+	if _, err := service.GetPostContext(t.Context(), "slides"); err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if _, _, _, err := service.GetNoteFile(t.Context(), "slides"); err != nil {
+		t.Fatalf("get file failed: %v", err)
+	}
+	if keys, _ := cache.Keys(policyNoteContent.key("slides")); len(keys) != 0 {
+		t.Fatalf("expected the file body to stay out of the cache, got keys %v", keys)
+	}
+}
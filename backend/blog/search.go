@@ -14,6 +14,9 @@ type searchCandidate struct {
 	NormalizedTitle string
 	NormalizedBody  string
 	Score           int
+	// Similarity is the cosine similarity to the query embedding in the
+	// semantic search modes.
+	Similarity float64
 }
 
 func normalizeSearchText(text string) string {
@@ -153,6 +156,13 @@ func buildSearchMatch(post Post, plainText, query string) SearchMatch {
 }
 
 func isSearchMatch(note etapi.Note, content, query string) (searchCandidate, bool) {
+	candidate := newSearchCandidate(note, content, query)
+	return candidate, candidate.Score > 0
+}
+
+// newSearchCandidate builds the search entry of a post with its keyword
+// score, which is 0 when the query does not match.
+func newSearchCandidate(note etapi.Note, content, query string) searchCandidate {
 	sanitized := sanitizeSearchContent(content)
 	plainText := strings.TrimSpace(htmlToPlainText(sanitized))
 	post := Post{
@@ -165,18 +175,13 @@ func isSearchMatch(note etapi.Note, content, query string) (searchCandidate, boo
 	titleNorm := normalizeSearchText(note.Title)
 	bodyNorm := normalizeSearchText(plainText)
 	queryNorm := normalizeSearchText(query)
-	score := searchScore(titleNorm, bodyNorm, queryNorm)
-	if score <= 0 {
-		return searchCandidate{}, false
-	}
-
 	return searchCandidate{
 		Post:            post,
 		PlainText:       plainText,
 		NormalizedTitle: titleNorm,
 		NormalizedBody:  bodyNorm,
-		Score:           score,
-	}, true
+		Score:           searchScore(titleNorm, bodyNorm, queryNorm),
+	}
 }
 
 func sortSearchCandidates(items []searchCandidate) {
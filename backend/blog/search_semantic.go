@@ -0,0 +1,160 @@
+package blog
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+)
+
+const (
+	SearchModeKeyword  = "keyword"
+	SearchModeSemantic = "semantic"
+	SearchModeHybrid   = "hybrid"
+)
+
+const (
+	// DefaultSemanticSearchMinScore is the cosine similarity below which a
+	// post does not count as a semantic match.
+	DefaultSemanticSearchMinScore = 0.3
+	// semanticSearchScale turns similarities into the integer scores that
+	// search candidates are sorted by.
+	semanticSearchScale = 1000
+	// hybridKeywordWeight is the share of the keyword score in hybrid mode;
+	// the rest comes from the similarity.
+	hybridKeywordWeight = 0.5
+	// queryEmbeddingCacheSize bounds the query vectors kept in memory, so
+	// that repeating a search does not call the provider again.
+	queryEmbeddingCacheSize = 256
+)
+
+// NormalizeSearchMode maps the mode query parameter to a search mode;
+// anything unknown is a keyword search.
+func NormalizeSearchMode(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case SearchModeSemantic:
+		return SearchModeSemantic
+	case SearchModeHybrid:
+		return SearchModeHybrid
+	default:
+		return SearchModeKeyword
+	}
+}
+
+// WithSemanticSearchMinScore sets the cosine similarity a post needs to be a
+// semantic match. Scores outside (0, 1) keep the default.
+func WithSemanticSearchMinScore(score float64) ServiceOption {
+	return func(s *Service) {
+		if score > 0 && score < 1 {
+			s.semanticMinScore = score
+		}
+	}
+}
+
+// queryEmbeddingCache keeps recent query vectors. It is emptied when full
+// rather than tracking recency; queries repeat in bursts, not over days.
+type queryEmbeddingCache struct {
+	mu    sync.Mutex
+	items map[string][]float32
+}
+
+func (c *queryEmbeddingCache) get(key string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vec, ok := c.items[key]
+	return vec, ok
+}
+
+func (c *queryEmbeddingCache) put(key string, vec []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items == nil || len(c.items) >= queryEmbeddingCacheSize {
+		c.items = make(map[string][]float32)
+	}
+	c.items[key] = vec
+}
+
+// EmbedQuery embeds a search query right away, outside the job queue. The
+// call counts toward the usage records and the monthly budget.
+func (q *AISummaryQueue) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	if q.embedder == nil {
+		return nil, &AIConfigError{Message: "ai embeddings are not configured"}
+	}
+	key := q.embedder.Model() + "\x00" + query
+	if vec, ok := q.queryCache.get(key); ok {
+		return vec, nil
+	}
+	if q.overBudget() {
+		return nil, &AIConfigError{Message: "ai monthly budget reached"}
+	}
+	job := &ClaimedJob{AISummaryJob: AISummaryJob{Type: "embedding"}}
+	start := time.Now()
+	vec, usage, err := q.embedder.Embed(ctx, clampSummaryInput(query, q.maxInputRunes))
+	q.recordUsage(job, usage, time.Since(start), err == nil)
+	if err != nil {
+		return nil, err
+	}
+	q.queryCache.put(key, vec)
+	return vec, nil
+}
+
+// searchQueryEmbedding returns the vector of query, or nil when semantic
+// search is unavailable and the search should use keywords only.
+func (s *Service) searchQueryEmbedding(ctx context.Context, query string) []float32 {
+	if s.aiQueue == nil || !s.aiEnabled || s.aiQueue.EmbeddingModel() == "" || s.summaryStore == nil {
+		return nil
+	}
+	vec, err := s.aiQueue.EmbedQuery(ctx, query)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Logger.Warn().Err(err).Msg("Failed to embed search query; using keyword search")
+		}
+		return nil
+	}
+	return vec
+}
+
+// rankSearchCandidates drops the candidates that do not match in mode and
+// sorts the rest. In semantic mode the score is the similarity; in hybrid
+// mode it blends the keyword score, relative to the best one, with the
+// similarity. Similarities below minSimilarity count as no match.
+func rankSearchCandidates(items []searchCandidate, mode string, minSimilarity float64) []searchCandidate {
+	maxKeyword := 0
+	for _, item := range items {
+		maxKeyword = max(maxKeyword, item.Score)
+	}
+
+	ranked := make([]searchCandidate, 0, len(items))
+	for _, item := range items {
+		similarity := item.Similarity
+		if similarity < minSimilarity {
+			similarity = 0
+		}
+		switch mode {
+		case SearchModeSemantic:
+			if similarity <= 0 {
+				continue
+			}
+			item.Score = max(int(similarity*semanticSearchScale), 1)
+		case SearchModeHybrid:
+			if item.Score <= 0 && similarity <= 0 {
+				continue
+			}
+			keyword := 0.0
+			if maxKeyword > 0 {
+				keyword = float64(item.Score) / float64(maxKeyword)
+			}
+			blended := hybridKeywordWeight*keyword + (1-hybridKeywordWeight)*similarity
+			item.Score = max(int(blended*semanticSearchScale), 1)
+		default:
+			if item.Score <= 0 {
+				continue
+			}
+		}
+		ranked = append(ranked, item)
+	}
+	sortSearchCandidates(ranked)
+	return ranked
+}
@@ -0,0 +1,169 @@
+package blog
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+// conceptEmbedder is a deterministic stand-in for an embedding model: each
+// dimension counts the words of one concept.
+type conceptEmbedder struct {
+	calls atomic.Int32
+}
+
+var testConcepts = [][]string{
+	{"build", "builds", "compile", "compiler", "cache", "faster", "speed", "incremental"},
+	{"memory", "ownership", "borrow", "lifetime", "rust"},
+	{"garden", "tomato", "soil"},
+}
+
+func (e *conceptEmbedder) Name() string  { return "test" }
+func (e *conceptEmbedder) Model() string { return "concepts" }
+
+func (e *conceptEmbedder) Embed(ctx context.Context, input string) ([]float32, AIUsage, error) {
+	e.calls.Add(1)
+	vec := make([]float32, len(testConcepts))
+	for _, word := range strings.Fields(normalizeSearchText(input)) {
+		for i, concept := range testConcepts {
+			for _, w := range concept {
+				if word == w {
+					vec[i]++
+				}
+			}
+		}
+	}
+	return vec, AIUsage{InputTokens: len(strings.Fields(input))}, nil
+}
+
+func TestRankSearchCandidates(t *testing.T) {
+	candidates := []searchCandidate{
+		{Post: Post{NoteID: "keyword"}, Score: 100, Similarity: 0.1},
+		{Post: Post{NoteID: "meaning"}, Score: 0, Similarity: 0.9},
+		{Post: Post{NoteID: "both"}, Score: 50, Similarity: 0.8},
+		{Post: Post{NoteID: "none"}, Score: 0, Similarity: 0.2},
+	}
+	ids := func(items []searchCandidate) string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.Post.NoteID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := ids(rankSearchCandidates(candidates, SearchModeKeyword, 0.3)); got != "keyword,both" {
+		t.Fatalf("unexpected keyword ranking %s", got)
+	}
+	if got := ids(rankSearchCandidates(candidates, SearchModeSemantic, 0.3)); got != "meaning,both" {
+		t.Fatalf("unexpected semantic ranking %s", got)
+	}
+	if got := ids(rankSearchCandidates(candidates, SearchModeHybrid, 0.3)); got != "both,keyword,meaning" {
+		t.Fatalf("unexpected hybrid ranking %s", got)
+	}
+}
+
+func TestNormalizeSearchMode(t *testing.T) {
+	for input, want := range map[string]string{"": "keyword", "Semantic": "semantic", " hybrid ": "hybrid", "fuzzy": "keyword"} {
+		if got := NormalizeSearchMode(input); got != want {
+			t.Fatalf("NormalizeSearchMode(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSearchPostsSemanticFindsConcepts(t *testing.T) {
+	fake := etapitest.New()
+	for _, n := range []struct{ id, title, content string }{
+		{"ccache", "Caching the compiler", "<p>An incremental compile cache makes every build faster.</p>"},
+		{"rust", "Rust ownership", "<p>Ownership and borrow rules manage memory.</p>"},
+		{"garden", "Tomato gardening", "<p>Tomato plants in good soil.</p>"},
+	} {
+		fake.AddNote(etapi.Note{
+			NoteID:       n.id,
+			Title:        n.title,
+			DateModified: "2026-04-13T12:00:00Z",
+			Attributes:   []etapi.Attribute{etapitest.Label("blog", "true")},
+		}, n.content)
+	}
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+
+	dir := t.TempDir()
+	store := newTestSummaryStore(t, filepath.Join(dir, "summaries.db"))
+	defer store.Close()
+	embeddings, err := NewEmbeddingStoreDB(filepath.Join(dir, "embeddings.db"))
+	if err != nil {
+		t.Fatalf("open embedding store: %v", err)
+	}
+	defer embeddings.Close()
+	embedder := &conceptEmbedder{}
+	queue := NewAISummaryQueue(store, "openai-compatible", "", "", "model", "prompt", 1, 1, 30000, 2000,
+		WithAIEmbeddings(embedder, embeddings))
+	defer queue.Close()
+	service := NewService(etapi.NewClient(trilium.URL, "token"), &NoopStore{},
+		WithSummaryStore(store), WithAISummaryQueue(queue), WithAISummaryEnabled(true))
+
+	query := "speeding up builds"
+	keyword, err := service.SearchPostsModeContext(t.Context(), query, SearchModeKeyword, false, 0)
+	if err != nil {
+		t.Fatalf("keyword search failed: %v", err)
+	}
+	if keyword.Total != 0 {
+		t.Fatalf("expected no keyword match, got %#v", keyword.Items)
+	}
+
+	// The first semantic search queues the post embeddings.
+	if _, err := service.SearchPostsModeContext(t.Context(), query, SearchModeSemantic, false, 0); err != nil {
+		t.Fatalf("semantic search failed: %v", err)
+	}
+	for _, noteID := range []string{"ccache", "rust", "garden"} {
+		waitForSummaryType(t, store, noteID, "embedding", "ready")
+	}
+
+	semantic, err := service.SearchPostsModeContext(t.Context(), query, SearchModeSemantic, false, 0)
+	if err != nil {
+		t.Fatalf("semantic search failed: %v", err)
+	}
+	if semantic.Total != 1 || semantic.Items[0].NoteID != "ccache" {
+		t.Fatalf("expected the compile cache post, got %#v", semantic.Items)
+	}
+	if semantic.Items[0].Summaries == nil || semantic.Items[0].Match.Snippet == "" {
+		t.Fatalf("expected the usual search item fields, got %#v", semantic.Items[0])
+	}
+
+	// Both posts are as close to the query vector, but only one also
+	// matches the keywords.
+	hybrid, err := service.SearchPostsModeContext(t.Context(), "tomato builds", SearchModeHybrid, false, 0)
+	if err != nil {
+		t.Fatalf("hybrid search failed: %v", err)
+	}
+	if hybrid.Total != 2 || hybrid.Items[0].NoteID != "garden" || hybrid.Items[1].NoteID != "ccache" {
+		t.Fatalf("expected the keyword match ahead of the semantic one, got %#v", hybrid.Items)
+	}
+
+	calls := embedder.calls.Load()
+	if _, err := service.SearchPostsModeContext(t.Context(), query, SearchModeSemantic, false, 0); err != nil {
+		t.Fatalf("semantic search failed: %v", err)
+	}
+	if embedder.calls.Load() != calls {
+		t.Fatalf("expected a repeated query to use the cached vector")
+	}
+}
+
+func TestSearchPostsSemanticFallsBackToKeywords(t *testing.T) {
+	blogServer := newBlogTestServer(t, "note-1", "<p>Keyword search still works.</p>")
+	defer blogServer.Close()
+	service := NewService(etapi.NewClient(blogServer.URL, "token"), &NoopStore{})
+
+	result, err := service.SearchPostsModeContext(t.Context(), "keyword", SearchModeSemantic, false, 0)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Total != 1 || result.Items[0].NoteID != "note-1" {
+		t.Fatalf("expected a keyword match without embeddings, got %#v", result.Items)
+	}
+}
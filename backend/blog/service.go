@@ -1,6 +1,7 @@
 package blog
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -22,6 +23,7 @@ type Service struct {
 	store             Store
 	summaryStore      SummaryStore
 	aiQueue           *AISummaryQueue
+	summaryPrompts    *SummaryPrompts
 	preloadMu         sync.Mutex
 	preloading        bool
 	blogTitle         string
@@ -32,6 +34,19 @@ type Service struct {
 	imageProxyEnabled bool
 	imageProxyBaseUrl string
 	aiEnabled         bool
+	aiSEO             bool
+	aiTranslation     bool
+	aiAsk             bool
+	semanticMinScore  float64
+
+	serverHighlight    bool
+	highlightTheme     string
+	highlightDarkTheme string
+	highlightCSSOnce   sync.Once
+	highlightCSS       string
+
+	// includeDepsMu serializes updates of the include-deps cache entries.
+	includeDepsMu sync.Mutex
 }
 
 type ServiceOption func(*Service)
@@ -72,16 +87,29 @@ func WithAISummaryQueue(queue *AISummaryQueue) ServiceOption {
 	return func(s *Service) { s.aiQueue = queue }
 }
 
+// WithAISEOEnabled makes the AI queue also suggest tags, keywords and a meta
+// description for each post.
+func WithAISEOEnabled(enabled bool) ServiceOption {
+	return func(s *Service) { s.aiSEO = enabled }
+}
+
+// WithSummaryPrompts renders a prompt per note for AI summaries. Without it
+// every summary uses the queue's prompt.
+func WithSummaryPrompts(prompts *SummaryPrompts) ServiceOption {
+	return func(s *Service) { s.summaryPrompts = prompts }
+}
+
 func WithAISummaryEnabled(enabled bool) ServiceOption {
 	return func(s *Service) { s.aiEnabled = enabled }
 }
 
 func NewService(client *etapi.Client, store Store, opts ...ServiceOption) *Service {
 	s := &Service{
-		etapiClient: client,
-		store:       store,
-		cache:       newCacheLayer(store),
-		pageSize:    9,
+		etapiClient:      client,
+		store:            store,
+		cache:            newCacheLayer(store),
+		pageSize:         9,
+		semanticMinScore: DefaultSemanticSearchMinScore,
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -93,18 +121,20 @@ type cachedAttachmentMeta struct {
 	ContentType string `json:"contentType"`
 }
 
-func (s *Service) getCachedNotes(search string) ([]etapi.Note, error) {
+func (s *Service) getCachedNotes(ctx context.Context, search string) ([]etapi.Note, error) {
 	var result []etapi.Note
 	if s.cache.readJSON(policyNotesList, search, &result) {
+		// The refresh outlives the request that triggered it, so it must not
+		// inherit the request's cancellation.
 		s.cache.maybeRefreshAhead(policyNotesList, search, func() {
-			if notes, err := s.etapiClient.GetNotes(search); err == nil {
+			if notes, err := s.etapiClient.GetNotesContext(context.Background(), search); err == nil {
 				s.cache.writeJSON(policyNotesList, search, notes)
 			}
 		})
 		return result, nil
 	}
 
-	loaded, err := s.etapiClient.GetNotes(search)
+	loaded, err := s.etapiClient.GetNotesContext(ctx, search)
 	if err != nil {
 		return nil, err
 	}
@@ -112,13 +142,13 @@ func (s *Service) getCachedNotes(search string) ([]etapi.Note, error) {
 	return loaded, nil
 }
 
-func (s *Service) getCachedNote(noteID string) (*etapi.Note, error) {
+func (s *Service) getCachedNote(ctx context.Context, noteID string) (*etapi.Note, error) {
 	var result etapi.Note
 	if s.cache.readJSON(policyNote, noteID, &result) {
 		return &result, nil
 	}
 
-	loaded, err := s.etapiClient.GetNote(noteID)
+	loaded, err := s.etapiClient.GetNoteContext(ctx, noteID)
 	if err != nil {
 		return nil, err
 	}
@@ -126,12 +156,12 @@ func (s *Service) getCachedNote(noteID string) (*etapi.Note, error) {
 	return loaded, nil
 }
 
-func (s *Service) getCachedNoteContent(noteID string) (string, error) {
+func (s *Service) getCachedNoteContent(ctx context.Context, noteID string) (string, error) {
 	if val, ok := s.cache.get(policyNoteContent, noteID); ok {
 		return val, nil
 	}
 
-	loaded, err := s.etapiClient.GetNoteContent(noteID)
+	loaded, err := s.etapiClient.GetNoteContentContext(ctx, noteID)
 	if err != nil {
 		return "", err
 	}
@@ -139,7 +169,7 @@ func (s *Service) getCachedNoteContent(noteID string) (string, error) {
 	return loaded, nil
 }
 
-func (s *Service) getCachedAttachment(attachmentID string) (data []byte, contentType string, err error) {
+func (s *Service) getCachedAttachment(ctx context.Context, attachmentID string) (data []byte, contentType string, err error) {
 	var meta cachedAttachmentMeta
 	if s.cache.readJSON(policyAttachmentMeta, attachmentID, &meta) {
 		if raw, ok := s.cache.get(policyAttachmentData, attachmentID); ok {
@@ -147,12 +177,12 @@ func (s *Service) getCachedAttachment(attachmentID string) (data []byte, content
 		}
 	}
 
-	attachment, err := s.etapiClient.GetAttachment(attachmentID)
+	attachment, err := s.etapiClient.GetAttachmentContext(ctx, attachmentID)
 	if err != nil {
 		return nil, "", err
 	}
 
-	note, err := s.getCachedNote(attachment.OwnerID)
+	note, err := s.getCachedNote(ctx, attachment.OwnerID)
 	if err != nil {
 		return nil, "", err
 	}
@@ -160,7 +190,7 @@ func (s *Service) getCachedAttachment(attachmentID string) (data []byte, content
 		return nil, "", ErrNotBlogPost
 	}
 
-	content, err := s.etapiClient.GetAttachmentContentBytes(attachmentID)
+	content, err := s.etapiClient.GetAttachmentContentBytesContext(ctx, attachmentID)
 	if err != nil {
 		return nil, "", err
 	}
@@ -171,7 +201,7 @@ func (s *Service) getCachedAttachment(attachmentID string) (data []byte, content
 }
 
 func (s *Service) GetSite() Site {
-	return Site{
+	site := Site{
 		Title:    s.blogTitle,
 		Subtitle: s.blogSubtitle,
 		Domain:   s.domain,
@@ -180,23 +210,35 @@ func (s *Service) GetSite() Site {
 			Enabled: s.imageProxyEnabled,
 			BaseURL: s.imageProxyBaseUrl,
 		},
+		Translation: s.aiQueue != nil && s.aiEnabled && s.aiTranslation,
+		Ask:         s.aiQueue != nil && s.aiEnabled && s.aiAsk,
 	}
+	if s.serverHighlight {
+		site.HighlightCSS = highlightCSSPath
+	}
+	return site
 }
 
 func (s *Service) ListPosts(page int) (*PostList, error) {
-	notes, err := s.getCachedNotes("#blog=true")
+	return s.ListPostsContext(context.Background(), page)
+}
+
+func (s *Service) ListPostsContext(ctx context.Context, page int) (*PostList, error) {
+	notes, err := s.getCachedNotes(ctx, "#blog=true")
 	if err != nil {
 		return nil, err
 	}
 
 	var posts []Post
+	postNotes := make(map[string]etapi.Note)
 	for _, n := range notes {
-		if n.Type == "text" && hasBlogLabel(n.Attributes) {
+		if isPost(n) {
 			posts = append(posts, Post{
 				NoteID:       n.NoteID,
 				Title:        n.Title,
 				DateModified: n.DateModified,
 			})
+			postNotes[n.NoteID] = n
 		}
 	}
 
@@ -230,7 +272,8 @@ func (s *Service) ListPosts(page int) (*PostList, error) {
 		// idx is passed as a value to avoid closure issues with the loop variable
 		go func(idx int) {
 			defer wg.Done()
-			content, err := s.getCachedNoteContent(pagePosts[idx].NoteID)
+			note := postNotes[pagePosts[idx].NoteID]
+			content, err := s.getPostContent(ctx, &note)
 			if err != nil {
 				mu.Lock()
 				if fetchErr == nil {
@@ -243,16 +286,20 @@ func (s *Service) ListPosts(page int) (*PostList, error) {
 			summary := s.extractSummary(sanitized)
 			mu.Lock()
 			pagePosts[idx].Summary = summary
-			summaries := s.resolveSummaries(pagePosts[idx].NoteID, pagePosts[idx].Title, content)
+			summaries := s.resolveSummaries(&note, content)
 			if summaries != nil {
 				pagePosts[idx].Summaries = summaries
 				pagePosts[idx].Summary = preferredSummaryText(summaries, summary)
 			}
+			applyPostSEO(&pagePosts[idx], note.Attributes, summaries)
 			mu.Unlock()
 		}(i)
 	}
 	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if fetchErr != nil && len(pagePosts) == 0 {
 		return nil, fetchErr
 	}
@@ -267,6 +314,18 @@ func (s *Service) ListPosts(page int) (*PostList, error) {
 }
 
 func (s *Service) SearchPosts(query string, preview bool, limit int) (*SearchResponse, error) {
+	return s.SearchPostsContext(context.Background(), query, preview, limit)
+}
+
+func (s *Service) SearchPostsContext(ctx context.Context, query string, preview bool, limit int) (*SearchResponse, error) {
+	return s.SearchPostsModeContext(ctx, query, SearchModeKeyword, preview, limit)
+}
+
+// SearchPostsModeContext searches posts by keyword, by meaning ("semantic")
+// or by both ("hybrid"). The semantic modes compare the query embedding with
+// the stored post embeddings and fall back to keywords when embeddings are
+// disabled or the query cannot be embedded.
+func (s *Service) SearchPostsModeContext(ctx context.Context, query, mode string, preview bool, limit int) (*SearchResponse, error) {
 	query = strings.TrimSpace(query)
 	if query == "" {
 		return &SearchResponse{
@@ -276,33 +335,65 @@ func (s *Service) SearchPosts(query string, preview bool, limit int) (*SearchRes
 		}, nil
 	}
 
-	notes, err := s.getCachedNotes("#blog=true")
+	notes, err := s.getCachedNotes(ctx, "#blog=true")
 	if err != nil {
 		return nil, err
 	}
 
+	mode = NormalizeSearchMode(mode)
+	var vectors map[string]StoredEmbedding
+	var queryVec []float32
+	if mode != SearchModeKeyword {
+		queryVec = s.searchQueryEmbedding(ctx, query)
+	}
+	if queryVec != nil {
+		vectors, err = s.aiQueue.embeddings.ListEmbeddings(s.aiQueue.EmbeddingModel())
+		if err != nil {
+			logger.Error("Failed to load embeddings; using keyword search", err)
+			queryVec = nil
+		}
+	}
+	if queryVec == nil {
+		mode = SearchModeKeyword
+	}
+
 	candidates := make([]searchCandidate, 0, len(notes))
+	contents := make(map[string]string, len(notes))
+	postNotes := make(map[string]*etapi.Note, len(notes))
 	for _, note := range notes {
-		if note.Type != "text" || !hasBlogLabel(note.Attributes) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !isPost(note) {
 			continue
 		}
-		content, err := s.getCachedNoteContent(note.NoteID)
+		content, err := s.getPostContent(ctx, &note)
 		if err != nil {
 			continue
 		}
-		candidate, ok := isSearchMatch(note, content, query)
-		if !ok {
+		candidate := newSearchCandidate(note, content, query)
+		if mode != SearchModeKeyword {
+			s.ensureEmbedding(note, content, s.aiQueue.EmbeddingModel())
+			if vec, ok := vectors[note.NoteID]; ok {
+				candidate.Similarity = cosineSimilarity(queryVec, vec.Vector)
+			}
+		} else if candidate.Score <= 0 {
 			continue
 		}
-		summaries, sumErr := s.ensureSummaries(note.NoteID, note.Title, content)
-		if sumErr == nil {
-			candidate.Post.Summaries = summaries
-			candidate.Post.Summary = preferredSummaryText(summaries, candidate.Post.Summary)
-		}
+		contents[note.NoteID] = content
+		postNotes[note.NoteID] = &note
 		candidates = append(candidates, candidate)
 	}
 
-	sortSearchCandidates(candidates)
+	candidates = rankSearchCandidates(candidates, mode, s.semanticMinScore)
+	for i := range candidates {
+		post := candidates[i].Post
+		summaries, sumErr := s.ensureSummaries(postNotes[post.NoteID], contents[post.NoteID])
+		if sumErr == nil {
+			candidates[i].Post.Summaries = summaries
+			candidates[i].Post.Summary = preferredSummaryText(summaries, candidates[i].Post.Summary)
+		}
+	}
 
 	total := len(candidates)
 	if preview {
@@ -330,14 +421,18 @@ func (s *Service) SearchPosts(query string, preview bool, limit int) (*SearchRes
 }
 
 func (s *Service) ListFeaturedPosts() ([]Post, error) {
-	notes, err := s.getCachedNotes("#blogtop=true")
+	return s.ListFeaturedPostsContext(context.Background())
+}
+
+func (s *Service) ListFeaturedPostsContext(ctx context.Context) ([]Post, error) {
+	notes, err := s.getCachedNotes(ctx, "#blogtop=true")
 	if err != nil {
 		return nil, err
 	}
 
 	posts := make([]Post, 0, len(notes))
 	for _, note := range notes {
-		if note.Type != "text" || !hasFeaturedLabel(note.Attributes) {
+		if !isPostType(note.Type) || !hasFeaturedLabel(note.Attributes) {
 			continue
 		}
 
@@ -347,10 +442,10 @@ func (s *Service) ListFeaturedPosts() ([]Post, error) {
 			DateModified: note.DateModified,
 		}
 
-		content, err := s.getCachedNoteContent(note.NoteID)
+		content, err := s.getPostContent(ctx, &note)
 		if err == nil {
 			post.Summary = s.extractSummary(s.sanitizeContent(content))
-			summaries := s.resolveSummaries(note.NoteID, note.Title, content)
+			summaries := s.resolveSummaries(&note, content)
 			if summaries != nil {
 				post.Summaries = summaries
 				post.Summary = preferredSummaryText(summaries, post.Summary)
@@ -360,18 +455,25 @@ func (s *Service) ListFeaturedPosts() ([]Post, error) {
 		posts = append(posts, post)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
 func (s *Service) GenerateSitemap() (string, error) {
-	notes, err := s.getCachedNotes("#blog=true")
+	return s.GenerateSitemapContext(context.Background())
+}
+
+func (s *Service) GenerateSitemapContext(ctx context.Context) (string, error) {
+	notes, err := s.getCachedNotes(ctx, "#blog=true")
 	if err != nil {
 		return "", err
 	}
 
 	var posts []Post
 	for _, n := range notes {
-		if n.Type == "text" && hasBlogLabel(n.Attributes) {
+		if isPost(n) {
 			posts = append(posts, Post{
 				NoteID:       n.NoteID,
 				Title:        n.Title,
@@ -398,61 +500,90 @@ func (s *Service) GenerateSitemap() (string, error) {
 }
 
 func (s *Service) GetPost(noteId string) (*Post, error) {
-	note, err := s.getCachedNote(noteId)
+	return s.GetPostContext(context.Background(), noteId)
+}
+
+func (s *Service) GetPostContext(ctx context.Context, noteId string) (*Post, error) {
+	return s.GetPostLangContext(ctx, noteId, "")
+}
+
+// GetPostLangContext returns a post in lang. When lang is not the post's
+// language and translation is enabled, the body and title are the machine
+// translation once it is ready; an empty lang serves the original.
+func (s *Service) GetPostLangContext(ctx context.Context, noteId, lang string) (*Post, error) {
+	note, err := s.getCachedNote(ctx, noteId)
 	if err != nil {
 		return nil, err
 	}
 
-	if !hasBlogLabel(note.Attributes) {
+	if !isPost(*note) {
 		return nil, ErrNotBlogPost
 	}
 
-	content, err := s.getCachedNoteContent(noteId)
+	content, err := s.getPostContent(ctx, note)
 	if err != nil {
 		return nil, err
 	}
 
-	sanitized := s.sanitizeContent(content)
-	toc, modifiedHtml := s.extractTOC(sanitized)
-	processed, codeBlocks := s.processContent(modifiedHtml)
-	summaryText := s.extractSummary(sanitized)
-	summaries := s.resolveSummaries(note.NoteID, note.Title, content)
+	body, title, translation := s.translatePost(note, content, lang)
+	sanitized := s.sanitizeContent(body)
+	toc, modifiedHtml := s.extractTOCDepth(sanitized, tocDepth(note.Attributes))
+	processed, codeBlocks := s.processNoteContent(modifiedHtml, note.Attributes)
+	processed = s.resolveNoteLinks(ctx, processed)
+	summaryText := s.extractSummary(s.sanitizeContent(content))
+	summaries := s.resolveSummaries(note, content)
 	if summaries != nil {
 		summaryText = preferredSummaryText(summaries, summaryText)
 	}
 
-	return &Post{
+	post := &Post{
 		NoteID:       note.NoteID,
-		Title:        note.Title,
+		Title:        title,
 		DateModified: note.DateModified,
 		ContentHTML:  processed,
 		CodeBlocks:   codeBlocks,
 		TOC:          toc,
+		TOCTree:      buildTOCTree(toc),
 		PageURL:      getPageURL(note.Attributes),
 		Summary:      summaryText,
 		Summaries:    summaries,
-	}, nil
+		Lang:         s.postLocale(note.Attributes),
+		Translation:  translation,
+	}
+	if translation != nil && translation.MachineTranslated {
+		post.Lang = translation.Lang
+	}
+	applyPostSEO(post, note.Attributes, summaries)
+	return post, nil
 }
 
 func (s *Service) GetPostSummaries(noteId string) (*Summaries, error) {
-	note, err := s.getCachedNote(noteId)
+	return s.GetPostSummariesContext(context.Background(), noteId)
+}
+
+func (s *Service) GetPostSummariesContext(ctx context.Context, noteId string) (*Summaries, error) {
+	note, err := s.getCachedNote(ctx, noteId)
 	if err != nil {
 		return nil, err
 	}
-	if !hasBlogLabel(note.Attributes) {
+	if !isPost(*note) {
 		return nil, ErrNotBlogPost
 	}
 
-	content, err := s.getCachedNoteContent(noteId)
+	content, err := s.getPostContent(ctx, note)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.resolveSummaries(note.NoteID, note.Title, content), nil
+	return s.resolveSummaries(note, content), nil
 }
 
 func (s *Service) GetAsset(attachmentId string) ([]byte, string, error) {
-	data, contentType, err := s.getCachedAttachment(attachmentId)
+	return s.GetAssetContext(context.Background(), attachmentId)
+}
+
+func (s *Service) GetAssetContext(ctx context.Context, attachmentId string) ([]byte, string, error) {
+	data, contentType, err := s.getCachedAttachment(ctx, attachmentId)
 	if err != nil {
 		return nil, "", err
 	}
@@ -509,23 +640,6 @@ func sanitizeSearchContent(html string) string {
 	return string(p.SanitizeBytes([]byte(html)))
 }
 
-func htmlToPlainText(html string) string {
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
-	if err != nil {
-		return ""
-	}
-
-	doc.Find("pre, code, style, script, svg, canvas, video, audio").Each(func(i int, sel *goquery.Selection) {
-		sel.Remove()
-	})
-	doc.Find("a").Each(func(i int, sel *goquery.Selection) {
-		text := strings.TrimSpace(sel.Text())
-		sel.ReplaceWithHtml(text)
-	})
-
-	return strings.TrimSpace(doc.Text())
-}
-
 func extractSearchSummary(text string) string {
 	text = strings.TrimSpace(text)
 	if text == "" {
@@ -544,40 +658,6 @@ func (s *Service) sanitizeContent(html string) string {
 	return string(p.SanitizeBytes([]byte(html)))
 }
 
-func (s *Service) extractTOC(html string) ([]TOCItem, string) {
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
-	if err != nil {
-		return nil, html
-	}
-
-	var toc []TOCItem
-	doc.Find("h1, h2, h3").EachWithBreak(func(i int, sel *goquery.Selection) bool {
-		text := strings.TrimSpace(sel.Text())
-		if text == "" {
-			return true
-		}
-		id := sel.AttrOr("id", "")
-		if id == "" {
-			id = generateID(text)
-			sel.SetAttr("id", id)
-		}
-		level := 1
-		if strings.HasPrefix(sel.Nodes[0].Data, "h2") {
-			level = 2
-		} else if strings.HasPrefix(sel.Nodes[0].Data, "h3") {
-			level = 3
-		}
-		toc = append(toc, TOCItem{ID: id, Title: text, Level: level})
-		return true
-	})
-
-	result, _ := doc.Find("body").Html()
-	if result == "" {
-		return toc, html
-	}
-	return toc, strings.TrimSpace(result)
-}
-
 func (s *Service) extractSummary(html string) string {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
 	if err != nil {
@@ -877,6 +957,12 @@ func cleanTrailingChars(s string) string {
 }
 
 func (s *Service) processContent(html string) (string, []CodeBlock) {
+	return s.processNoteContent(html, nil)
+}
+
+// processNoteContent is processContent with code block defaults taken
+// from the note's labels.
+func (s *Service) processNoteContent(html string, attrs []etapi.Attribute) (string, []CodeBlock) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
 	if err != nil {
 		return html, nil
@@ -908,20 +994,30 @@ func (s *Service) processContent(html string) (string, []CodeBlock) {
 	})
 
 	var codeBlocks []CodeBlock
+	defaults := codeBlockDefaults(attrs)
 
 	doc.Find("pre code").Each(func(i int, sel *goquery.Selection) {
+		block := defaults
 		codeText := sel.Text()
+		if stripped := parseCodeDirective(codeText, &block); stripped != codeText {
+			codeText = stripped
+			sel.SetText(codeText)
+		}
 		className, _ := sel.Attr("class")
 		languageID, detectedBy := detectCodeBlockLanguage(codeText, className)
 		setCodeLanguageClass(sel, languageID)
 
-		codeBlocks = append(codeBlocks, CodeBlock{
-			Index:           i,
-			LanguageID:      languageID,
-			LanguageLabel:   friendlyLanguageLabel(languageID),
-			DetectedBy:      detectedBy,
-			ShowLineNumbers: true,
-		})
+		block.Index = i
+		block.LanguageID = languageID
+		block.LanguageLabel = friendlyLanguageLabel(languageID)
+		block.DetectedBy = detectedBy
+		if s.serverHighlight {
+			if highlighted, err := s.highlightCode(codeText, languageID, block); err == nil {
+				sel.Closest("pre").ReplaceWithHtml(highlighted)
+				block.Highlighted = true
+			}
+		}
+		codeBlocks = append(codeBlocks, block)
 	})
 
 	result, _ := doc.Find("body").Html()
@@ -1323,13 +1419,6 @@ func isInternalAssetPath(src, domain string) bool {
 	return false
 }
 
-func generateID(text string) string {
-	re := regexp.MustCompile(`[^\p{L}\p{N}]+`)
-	id := re.ReplaceAllString(text, "-")
-	id = strings.Trim(id, "-")
-	return id
-}
-
 var attachmentPathRe = regexp.MustCompile(`^(?:/?(?:api/)?)?attachments/([^/]+)`)
 
 func extractAttachmentId(src string) string {
@@ -1343,7 +1432,8 @@ func extractAttachmentId(src string) string {
 func (s *Service) Preload() {
 	logger.Info("Preload: starting")
 
-	notes, err := s.getCachedNotes("#blog=true")
+	ctx := context.Background()
+	notes, err := s.getCachedNotes(ctx, "#blog=true")
 	if err != nil {
 		logger.Error("Preload: failed to fetch notes list", err)
 		return
@@ -1352,7 +1442,7 @@ func (s *Service) Preload() {
 
 	var blogNotes []etapi.Note
 	for _, n := range notes {
-		if n.Type == "text" && hasBlogLabel(n.Attributes) {
+		if isPost(n) {
 			blogNotes = append(blogNotes, n)
 		}
 	}
@@ -1371,16 +1461,16 @@ func (s *Service) Preload() {
 	for _, n := range blogNotes {
 		wg.Add(1)
 		sem <- struct{}{}
-		go func(noteID string) {
+		go func(note etapi.Note) {
 			defer wg.Done()
 			defer func() { <-sem }()
-			if _, err := s.getCachedNoteContent(noteID); err != nil {
-				logger.Error(fmt.Sprintf("Preload: failed for note %s", noteID), err)
+			if _, err := s.getPostContent(ctx, &note); err != nil {
+				logger.Error(fmt.Sprintf("Preload: failed for note %s", note.NoteID), err)
 				failed++
 			} else {
 				cached++
 			}
-		}(n.NoteID)
+		}(n)
 	}
 	wg.Wait()
 
@@ -1390,6 +1480,8 @@ func (s *Service) Preload() {
 func (s *Service) InvalidateNote(noteID string) {
 	s.cache.del(policyNote, noteID)
 	s.cache.del(policyNoteContent, noteID)
+	s.cache.del(policyPostContent, noteID)
+	s.invalidateIncluders(noteID)
 }
 
 func (s *Service) InvalidateNotesList(search string) {
@@ -1423,7 +1515,18 @@ func (s *Service) invalidateByPolicies(policies []cachePolicy) int {
 }
 
 func (s *Service) GetCacheStats() CacheStats {
-	return s.cache.stats()
+	stats := s.cache.stats()
+	breaker := s.UpstreamStatus()
+	stats.Trilium = &breaker
+	return stats
+}
+
+// UpstreamStatus reports the Trilium circuit breaker state.
+func (s *Service) UpstreamStatus() etapi.BreakerStatus {
+	if s.etapiClient == nil {
+		return etapi.BreakerStatus{State: etapi.BreakerClosed}
+	}
+	return s.etapiClient.BreakerStatus()
 }
 
 func (s *Service) TriggerPreload() bool {
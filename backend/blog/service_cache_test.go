@@ -1,15 +1,13 @@
 package blog
 
 import (
-	"fmt"
-	"net/http"
-	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
 )
 
 type memoryStore struct {
@@ -81,28 +79,14 @@ func TestGetPostCachesResolvedPost(t *testing.T) {
 	noteID := "cached-post"
 	content := "<h1>Hello</h1><p>This article should be served from cache on the second request.</p>"
 
-	var mu sync.Mutex
-	noteCalls := 0
-	contentCalls := 0
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/etapi/notes/" + noteID:
-			mu.Lock()
-			noteCalls++
-			mu.Unlock()
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprintf(w, `{"noteId":"%s","title":"Cached Post","dateModified":"2026-04-13T12:00:00Z","type":"text","mime":"text/html","attributes":[{"type":"label","name":"blog","value":"true"}]}`, noteID)
-		case "/etapi/notes/" + noteID + "/content":
-			mu.Lock()
-			contentCalls++
-			mu.Unlock()
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			_, _ = w.Write([]byte(content))
-		default:
-			http.NotFound(w, r)
-		}
-	}))
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:       noteID,
+		Title:        "Cached Post",
+		DateModified: "2026-04-13T12:00:00Z",
+		Attributes:   []etapi.Attribute{etapitest.Label("blog", "true")},
+	}, content)
+	server := etapitest.NewServer(fake)
 	defer server.Close()
 
 	service := NewService(
@@ -126,50 +110,27 @@ func TestGetPostCachesResolvedPost(t *testing.T) {
 		t.Fatalf("expected cached post content to match original fetch")
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-	if noteCalls != 1 {
-		t.Fatalf("expected note metadata to be fetched once, got %d", noteCalls)
+	if got := fake.Requests("/etapi/notes/" + noteID); got != 1 {
+		t.Fatalf("expected note metadata to be fetched once, got %d", got)
 	}
-	if contentCalls != 1 {
-		t.Fatalf("expected note content to be fetched once, got %d", contentCalls)
+	if got := fake.Requests("/etapi/notes/" + noteID + "/content"); got != 1 {
+		t.Fatalf("expected note content to be fetched once, got %d", got)
 	}
 }
 
 func TestGetAssetCachesAttachmentContent(t *testing.T) {
-	attachmentID := "asset-1"
 	content := []byte("asset-bytes")
 	contentType := "image/png"
 
-	var mu sync.Mutex
-	attachmentMetaCalls := 0
-	noteCalls := 0
-	assetCalls := 0
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/etapi/attachments/" + attachmentID:
-			mu.Lock()
-			attachmentMetaCalls++
-			mu.Unlock()
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprintf(w, `{"ownerId":"note-1","mime":"%s"}`, contentType)
-		case "/etapi/notes/note-1":
-			mu.Lock()
-			noteCalls++
-			mu.Unlock()
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprint(w, `{"noteId":"note-1","title":"Attachment Owner","dateModified":"2026-04-13T12:00:00Z","type":"text","mime":"text/html","attributes":[{"type":"label","name":"blog","value":"true"}]}`)
-		case "/etapi/attachments/" + attachmentID + "/content":
-			mu.Lock()
-			assetCalls++
-			mu.Unlock()
-			w.Header().Set("Content-Type", contentType)
-			_, _ = w.Write(content)
-		default:
-			http.NotFound(w, r)
-		}
-	}))
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:       "note-1",
+		Title:        "Attachment Owner",
+		DateModified: "2026-04-13T12:00:00Z",
+		Attributes:   []etapi.Attribute{etapitest.Label("blog", "true")},
+	}, "")
+	attachmentID := fake.AddAttachment("note-1", "asset.png", contentType, content)
+	server := etapitest.NewServer(fake)
 	defer server.Close()
 
 	service := NewService(
@@ -193,16 +154,14 @@ func TestGetAssetCachesAttachmentContent(t *testing.T) {
 		t.Fatalf("expected cached content type %q, got %q and %q", contentType, firstType, secondType)
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-	if attachmentMetaCalls != 1 {
-		t.Fatalf("expected attachment metadata to be fetched once, got %d", attachmentMetaCalls)
+	if got := fake.Requests("/etapi/attachments/" + attachmentID); got != 1 {
+		t.Fatalf("expected attachment metadata to be fetched once, got %d", got)
 	}
-	if noteCalls != 1 {
-		t.Fatalf("expected owner note metadata to be fetched once, got %d", noteCalls)
+	if got := fake.Requests("/etapi/notes/note-1"); got != 1 {
+		t.Fatalf("expected owner note metadata to be fetched once, got %d", got)
 	}
-	if assetCalls != 1 {
-		t.Fatalf("expected attachment content to be fetched once, got %d", assetCalls)
+	if got := fake.Requests("/etapi/attachments/" + attachmentID + "/content"); got != 1 {
+		t.Fatalf("expected attachment content to be fetched once, got %d", got)
 	}
 }
 
@@ -210,28 +169,14 @@ func TestListPostsCachesNotesAndContent(t *testing.T) {
 	noteID := "listed-post"
 	content := "<p>List posts should reuse cached note metadata and content.</p>"
 
-	var mu sync.Mutex
-	listCalls := 0
-	contentCalls := 0
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/etapi/notes":
-			mu.Lock()
-			listCalls++
-			mu.Unlock()
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprintf(w, `{"results":[{"noteId":"%s","title":"Listed Post","dateModified":"2026-04-13T12:00:00Z","type":"text","mime":"text/html","attributes":[{"type":"label","name":"blog","value":"true"}]}]}`, noteID)
-		case "/etapi/notes/" + noteID + "/content":
-			mu.Lock()
-			contentCalls++
-			mu.Unlock()
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			_, _ = w.Write([]byte(content))
-		default:
-			http.NotFound(w, r)
-		}
-	}))
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:       noteID,
+		Title:        "Listed Post",
+		DateModified: "2026-04-13T12:00:00Z",
+		Attributes:   []etapi.Attribute{etapitest.Label("blog", "true")},
+	}, content)
+	server := etapitest.NewServer(fake)
 	defer server.Close()
 
 	service := NewService(
@@ -255,33 +200,23 @@ func TestListPostsCachesNotesAndContent(t *testing.T) {
 		t.Fatalf("expected summaries to be populated from cached content")
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-	if listCalls != 1 {
-		t.Fatalf("expected note list to be fetched once, got %d", listCalls)
+	if got := fake.Requests("/etapi/notes"); got != 1 {
+		t.Fatalf("expected note list to be fetched once, got %d", got)
 	}
-	if contentCalls != 1 {
-		t.Fatalf("expected listed note content to be fetched once, got %d", contentCalls)
+	if got := fake.Requests("/etapi/notes/" + noteID + "/content"); got != 1 {
+		t.Fatalf("expected listed note content to be fetched once, got %d", got)
 	}
 }
 
 func TestGetAssetRejectsAttachmentsFromNonBlogNotes(t *testing.T) {
-	attachmentID := "asset-private"
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/etapi/attachments/" + attachmentID:
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprint(w, `{"ownerId":"note-private","mime":"image/png"}`)
-		case "/etapi/notes/note-private":
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprint(w, `{"noteId":"note-private","title":"Private Note","dateModified":"2026-04-13T12:00:00Z","type":"text","mime":"text/html","attributes":[]}`)
-		case "/etapi/attachments/" + attachmentID + "/content":
-			t.Fatalf("attachment content should not be fetched for non-blog note")
-		default:
-			http.NotFound(w, r)
-		}
-	}))
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:       "note-private",
+		Title:        "Private Note",
+		DateModified: "2026-04-13T12:00:00Z",
+	}, "")
+	attachmentID := fake.AddAttachment("note-private", "private.png", "image/png", []byte("secret"))
+	server := etapitest.NewServer(fake)
 	defer server.Close()
 
 	service := NewService(
@@ -292,4 +227,7 @@ func TestGetAssetRejectsAttachmentsFromNonBlogNotes(t *testing.T) {
 	if _, _, err := service.GetAsset(attachmentID); err == nil {
 		t.Fatalf("expected non-blog attachment fetch to fail")
 	}
+	if got := fake.Requests("/etapi/attachments/" + attachmentID + "/content"); got != 0 {
+		t.Fatalf("attachment content should not be fetched for non-blog note, got %d requests", got)
+	}
 }
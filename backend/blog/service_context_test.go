@@ -0,0 +1,84 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+func TestSearchPostsContextStopsFetchingAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	contentCalls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etapi/notes":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"results":[`)
+			for i := 0; i < 20; i++ {
+				if i > 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"noteId":"note-%d","title":"Post %d","dateModified":"2026-04-13T12:00:00Z","type":"text","mime":"text/html","attributes":[{"type":"label","name":"blog","value":"true"}]}`, i, i)
+			}
+			fmt.Fprint(w, `]}`)
+		default:
+			mu.Lock()
+			contentCalls++
+			mu.Unlock()
+			// The first content fetch is where the reader disconnects.
+			cancel()
+			_, _ = w.Write([]byte("<p>search body</p>"))
+		}
+	}))
+	defer server.Close()
+
+	service := NewService(etapi.NewClient(server.URL, "token"), &NoopStore{})
+
+	_, err := service.SearchPostsContext(ctx, "search", false, 5)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if contentCalls != 1 {
+		t.Fatalf("expected search to stop after the cancel, got %d content fetches", contentCalls)
+	}
+}
+
+func TestGetPostContextHonorsDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	service := NewService(etapi.NewClient(server.URL, "token"), &NoopStore{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := service.GetPostContext(ctx, "slow-note")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected GetPostContext to return at the deadline, took %s", elapsed)
+	}
+}
@@ -2,7 +2,6 @@ package blog
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
@@ -11,6 +10,7 @@ import (
 	"time"
 
 	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
 )
 
 type failingSummaryStore struct{}
@@ -26,18 +26,14 @@ func (f failingSummaryStore) UpsertSummary(item StoredSummary) error {
 func newBlogTestServer(t *testing.T, noteID, content string) *httptest.Server {
 	t.Helper()
 
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/etapi/notes/" + noteID:
-			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprintf(w, `{"noteId":"%s","title":"Test Post","dateModified":"2026-04-13T12:00:00Z","type":"text","mime":"text/html","attributes":[{"type":"label","name":"blog","value":"true"}]}`, noteID)
-		case "/etapi/notes/" + noteID + "/content":
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			_, _ = w.Write([]byte(content))
-		default:
-			http.NotFound(w, r)
-		}
-	}))
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:       noteID,
+		Title:        "Test Post",
+		DateModified: "2026-04-13T12:00:00Z",
+		Attributes:   []etapi.Attribute{etapitest.Label("blog", "true")},
+	}, content)
+	return etapitest.NewServer(fake)
 }
 
 func TestGetPostIgnoresSummaryStoreFailures(t *testing.T) {
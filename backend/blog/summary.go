@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/harveyTon/trilium-blog/backend/etapi"
 	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
 )
 
@@ -48,16 +49,20 @@ func extractSummaryFromSanitizedContent(sanitized string) string {
 	return (&Service{}).extractSummary(sanitized)
 }
 
-func (s *Service) resolveSummaries(noteID, title, content string) *Summaries {
-	summaries, err := s.ensureSummaries(noteID, title, content)
+func (s *Service) resolveSummaries(note *etapi.Note, content string) *Summaries {
+	summaries, err := s.ensureSummaries(note, content)
 	if err == nil {
 		return summaries
 	}
 	logger.Error("Failed to resolve summaries; falling back to code summary", err)
-	return fallbackSummaries(noteID, content)
+	return fallbackSummaries(note.NoteID, content)
 }
 
-func (s *Service) ensureSummaries(noteID, title, content string) (*Summaries, error) {
+// ensureSummaries returns the summaries of a post, queueing AI jobs for
+// those that are missing or stale. Posts without text to read, such as
+// images, only get the code summary.
+func (s *Service) ensureSummaries(note *etapi.Note, content string) (*Summaries, error) {
+	noteID, title, attrs := note.NoteID, note.Title, note.Attributes
 	if s.summaryStore == nil {
 		return fallbackSummaries(noteID, content), nil
 	}
@@ -68,7 +73,7 @@ func (s *Service) ensureSummaries(noteID, title, content string) (*Summaries, er
 	if err != nil {
 		return nil, err
 	}
-	if codeStored == nil || codeStored.SourceHash != hash || codeStored.Content == "" {
+	if codeStored == nil || (!codeStored.Pinned && (codeStored.SourceHash != hash || codeStored.Content == "")) {
 		codeStored = &StoredSummary{
 			NoteID:     noteID,
 			Type:       "code",
@@ -86,51 +91,91 @@ func (s *Service) ensureSummaries(noteID, title, content string) (*Summaries, er
 		return nil, err
 	}
 
-	if s.aiQueue != nil && s.aiEnabled {
-		if aiStored == nil || aiStored.SourceHash != hash || aiStored.Status == "" {
+	aiReadable := hasReadableText(note.Type)
+	if s.aiQueue != nil && s.aiEnabled && aiReadable {
+		prompt := s.summaryPrompt(noteID, title, attrs)
+		aiHash := summaryPromptHash(hash, prompt)
+		if aiStored == nil || (!aiStored.Pinned && (aiStored.SourceHash != aiHash || aiStored.Status == "")) {
 			_ = s.summaryStore.UpsertSummary(StoredSummary{
 				NoteID:     noteID,
 				Type:       "ai",
 				Status:     "pending",
 				Content:    "",
-				SourceHash: hash,
+				SourceHash: aiHash,
 				Error:      "",
 			})
 			s.aiQueue.Enqueue(AISummaryJob{
 				NoteID:     noteID,
 				Title:      title,
-				Content:    content,
-				SourceHash: hash,
+				Content:    extractSummaryText(sanitizeContentForSummary(content)),
+				Prompt:     prompt,
+				SourceHash: aiHash,
 			})
 			aiStored, _ = s.summaryStore.GetSummary(noteID, "ai")
+			if aiStored != nil {
+				s.aiQueue.publish(*aiStored)
+			}
 		}
 	}
 
 	result := &Summaries{
 		NoteID:    noteID,
 		AIEnabled: s.aiEnabled && s.aiQueue != nil,
-		Code: &SummaryEntry{
-			Type:      "code",
-			Status:    codeStored.Status,
-			Text:      codeStored.Content,
-			UpdatedAt: codeStored.UpdatedAt,
-			Error:     codeStored.Error,
-		},
+		Code:      summaryEntryFromStored(codeStored),
 	}
 
 	if aiStored != nil {
-		result.AI = &SummaryEntry{
-			Type:      "ai",
-			Status:    aiStored.Status,
-			Text:      aiStored.Content,
-			UpdatedAt: aiStored.UpdatedAt,
-			Error:     aiStored.Error,
+		result.AI = summaryEntryFromStored(aiStored)
+	}
+
+	if s.aiQueue != nil && s.aiEnabled && s.aiSEO && aiReadable {
+		rows, err := s.ensureSEO(noteID, title, attrs, content, hash)
+		if err != nil {
+			return nil, err
+		}
+		for summaryType, entry := range map[string]**SummaryEntry{
+			"tags":        &result.Tags,
+			"keywords":    &result.Keywords,
+			"description": &result.Description,
+		} {
+			if rows[summaryType] != nil {
+				*entry = summaryEntryFromStored(rows[summaryType])
+			}
 		}
 	}
 
 	return result, nil
 }
 
+// SubscribeSummary streams summary changes of noteID until cancel is called.
+// ok is false when AI summaries are not generated in this process, in which
+// case nothing will ever be sent.
+func (s *Service) SubscribeSummary(noteID string) (events <-chan SummaryEvent, cancel func(), ok bool) {
+	if s.aiQueue == nil || s.aiQueue.Hub() == nil {
+		return nil, func() {}, false
+	}
+	events, cancel = s.aiQueue.Hub().Subscribe(noteID)
+	return events, cancel, true
+}
+
+func summaryEntryFromStored(item *StoredSummary) *SummaryEntry {
+	entry := &SummaryEntry{
+		Type:        item.Type,
+		Status:      item.Status,
+		Text:        item.Content,
+		UpdatedAt:   item.UpdatedAt,
+		Error:       item.Error,
+		Attempts:    item.Attempts,
+		NextRetryAt: item.NextRetryAt,
+		History:     item.History,
+		Pinned:      item.Pinned,
+	}
+	if item.ChunksTotal > 0 && item.Status == "processing" {
+		entry.Progress = &SummaryProgress{Done: item.ChunksDone, Total: item.ChunksTotal}
+	}
+	return entry
+}
+
 func preferredSummaryText(summaries *Summaries, fallback string) string {
 	if summaries != nil {
 		if summaries.AIEnabled && summaries.AI != nil && summaries.AI.Status == "ready" && strings.TrimSpace(summaries.AI.Text) != "" {
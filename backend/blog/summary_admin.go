@@ -0,0 +1,223 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+)
+
+var (
+	ErrSummaryAdminUnavailable = errors.New("summary store does not support administration")
+	ErrAISummaryDisabled       = errors.New("ai summaries are disabled")
+)
+
+// SummaryTypes lists the types a stored summary can have: the code summary,
+// the rows written by each AI job and a translation into each supported
+// language.
+func SummaryTypes() []string {
+	types := []string{"code"}
+	for _, kind := range aiJobKinds {
+		types = append(types, kind.types...)
+	}
+	for locale := range summaryLanguageNames {
+		types = append(types, translationTypePrefix+locale)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ValidSummaryType reports whether summaryType is one of SummaryTypes.
+func ValidSummaryType(summaryType string) bool {
+	return slices.Contains(SummaryTypes(), summaryType)
+}
+
+// SummaryFilter narrows ListSummaries. Empty fields match everything.
+type SummaryFilter struct {
+	Status string
+	Type   string
+	NoteID string
+	Limit  int
+	Offset int
+}
+
+// SummaryAdminStore is the part of the summary store used by the admin API.
+type SummaryAdminStore interface {
+	ListSummaries(filter SummaryFilter) ([]StoredSummary, int, error)
+	DeleteSummary(noteID, summaryType string) error
+}
+
+// ListSummaries returns the matching rows, most recently updated first, and
+// the total number of matches ignoring Limit and Offset.
+func (s *SummaryStoreDB) ListSummaries(filter SummaryFilter) ([]StoredSummary, int, error) {
+	var where []string
+	var args []any
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, filter.Type)
+	}
+	if filter.NoteID != "" {
+		where = append(where, "note_id = ?")
+		args = append(args, filter.NoteID)
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM summaries`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.Query(`
+		SELECT note_id, type, status, content, source_hash, updated_at, error, attempts, next_retry_at, history, pinned, chunks_done, chunks_total
+		FROM summaries`+clause+`
+		ORDER BY updated_at DESC, note_id, type
+		LIMIT ? OFFSET ?
+	`, append(args, limit, max(filter.Offset, 0))...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var items []StoredSummary
+	for rows.Next() {
+		item, err := scanSummary(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, *item)
+	}
+	return items, total, rows.Err()
+}
+
+// DeleteSummary removes a summary together with any queued job for it. For
+// rows written by a job of another type (the SEO fields) that job is removed.
+func (s *SummaryStoreDB) DeleteSummary(noteID, summaryType string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM summary_jobs WHERE note_id = ? AND type = ?`, noteID, summaryJobType(summaryType)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM summaries WHERE note_id = ? AND type = ?`, noteID, summaryType); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Service) summaryAdminStore() (SummaryAdminStore, error) {
+	store, ok := s.summaryStore.(SummaryAdminStore)
+	if !ok {
+		return nil, ErrSummaryAdminUnavailable
+	}
+	return store, nil
+}
+
+func (s *Service) ListSummaries(filter SummaryFilter) ([]StoredSummary, int, error) {
+	store, err := s.summaryAdminStore()
+	if err != nil {
+		return nil, 0, err
+	}
+	return store.ListSummaries(filter)
+}
+
+// ClearSummary deletes a summary. The next view of the post generates it
+// again.
+func (s *Service) ClearSummary(noteID, summaryType string) error {
+	store, err := s.summaryAdminStore()
+	if err != nil {
+		return err
+	}
+	return store.DeleteSummary(noteID, summaryType)
+}
+
+// PinSummary stores text as the summary of noteID and keeps it there until
+// it is cleared or regenerated.
+func (s *Service) PinSummary(noteID, summaryType, text string) error {
+	if s.summaryStore == nil {
+		return ErrSummaryAdminUnavailable
+	}
+	item := StoredSummary{
+		NoteID:  noteID,
+		Type:    summaryType,
+		Status:  "ready",
+		Content: strings.TrimSpace(text),
+		Pinned:  true,
+	}
+	prev, err := s.summaryStore.GetSummary(noteID, summaryType)
+	if err != nil {
+		return err
+	}
+	if prev != nil {
+		item.SourceHash = prev.SourceHash
+	}
+	item.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := s.summaryStore.UpsertSummary(item); err != nil {
+		return err
+	}
+	if s.aiQueue != nil {
+		s.aiQueue.publish(item)
+	}
+	return nil
+}
+
+// RegenerateSummaryContext drops the AI summary of a post, pinned or not,
+// and queues a fresh generation.
+func (s *Service) RegenerateSummaryContext(ctx context.Context, noteID string) (*Summaries, error) {
+	if s.aiQueue == nil || !s.aiEnabled {
+		return nil, ErrAISummaryDisabled
+	}
+	if err := s.ClearSummary(noteID, "ai"); err != nil {
+		return nil, err
+	}
+	return s.GetPostSummariesContext(ctx, noteID)
+}
+
+// AIUsageReport returns AI spending aggregated by "day" or "month".
+func (s *Service) AIUsageReport(granularity string, limit int) (*AIUsageReport, error) {
+	if s.aiQueue == nil {
+		return nil, ErrAISummaryDisabled
+	}
+	return s.aiQueue.UsageReport(granularity, limit)
+}
+
+// RegenerateFailedSummariesContext regenerates every failed AI summary and
+// returns how many were queued. Summaries of notes that can no longer be
+// loaded are cleared and skipped.
+func (s *Service) RegenerateFailedSummariesContext(ctx context.Context) (int, error) {
+	if s.aiQueue == nil || !s.aiEnabled {
+		return 0, ErrAISummaryDisabled
+	}
+	failed, _, err := s.ListSummaries(SummaryFilter{Status: "failed", Type: "ai"})
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, item := range failed {
+		if err := ctx.Err(); err != nil {
+			return queued, err
+		}
+		if _, err := s.RegenerateSummaryContext(ctx, item.NoteID); err != nil {
+			logger.Logger.Warn().Err(err).Str("note_id", item.NoteID).Msg("Failed to regenerate AI summary")
+			continue
+		}
+		queued++
+	}
+	return queued, nil
+}
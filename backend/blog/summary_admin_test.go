@@ -0,0 +1,134 @@
+package blog
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+func TestSummaryStoreDB_ListSummariesFilters(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+
+	for _, item := range []StoredSummary{
+		{NoteID: "a", Type: "ai", Status: "ready", UpdatedAt: "2026-01-01T00:00:00Z"},
+		{NoteID: "b", Type: "ai", Status: "failed", UpdatedAt: "2026-01-03T00:00:00Z"},
+		{NoteID: "c", Type: "ai", Status: "failed", UpdatedAt: "2026-01-02T00:00:00Z"},
+		{NoteID: "a", Type: "code", Status: "ready", UpdatedAt: "2026-01-01T00:00:00Z"},
+	} {
+		if err := store.UpsertSummary(item); err != nil {
+			t.Fatalf("upsert failed: %v", err)
+		}
+	}
+
+	items, total, err := store.ListSummaries(SummaryFilter{Status: "failed", Type: "ai", Limit: 1})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if total != 2 || len(items) != 1 || items[0].NoteID != "b" {
+		t.Fatalf("expected newest failed summary first with total 2, got %d %#v", total, items)
+	}
+
+	items, _, err = store.ListSummaries(SummaryFilter{Status: "failed", Type: "ai", Limit: 1, Offset: 1})
+	if err != nil || len(items) != 1 || items[0].NoteID != "c" {
+		t.Fatalf("expected second page to hold c, got %#v (%v)", items, err)
+	}
+
+	_, total, _ = store.ListSummaries(SummaryFilter{})
+	if total != 4 {
+		t.Fatalf("expected all 4 summaries without a filter, got %d", total)
+	}
+}
+
+func TestSummaryStoreDB_PinnedSummaryIsNotOverwritten(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+
+	if err := store.UpsertSummary(StoredSummary{NoteID: "n", Type: "ai", Status: "ready", Content: "edited", Pinned: true}); err != nil {
+		t.Fatalf("pin failed: %v", err)
+	}
+	if err := store.UpsertSummary(StoredSummary{NoteID: "n", Type: "ai", Status: "ready", Content: "generated"}); err != nil {
+		t.Fatalf("upsert failed: %v", err)
+	}
+	item, _ := store.GetSummary("n", "ai")
+	if item == nil || item.Content != "edited" || !item.Pinned {
+		t.Fatalf("expected pinned summary to survive generation, got %#v", item)
+	}
+
+	if err := store.DeleteSummary("n", "ai"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if item, _ := store.GetSummary("n", "ai"); item != nil {
+		t.Fatalf("expected summary to be deleted, got %#v", item)
+	}
+}
+
+func TestSummaryStoreDB_DeleteSEOSummaryRemovesSEOJob(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+
+	_, _ = store.EnqueueJob(AISummaryJob{NoteID: "n", Type: "seo", SourceHash: "h"}, time.Now())
+	_ = store.UpsertSummary(StoredSummary{NoteID: "n", Type: "keywords", Status: "pending", SourceHash: "h"})
+
+	if err := store.DeleteSummary("n", "keywords"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if n, _ := store.CountJobs(); n != 0 {
+		t.Fatalf("expected the seo job to be removed, got %d queued", n)
+	}
+}
+
+func TestServicePinnedSummaryIsNotRegenerated(t *testing.T) {
+	noteID := "note-pin"
+	server := newBlogTestServer(t, noteID, "<p>Original article body that would normally be summarized.</p>")
+	defer server.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", "http://127.0.0.1:1", "token", "model", "prompt", 1, 1, 1000, 2000)
+	defer queue.Close()
+	service := NewService(
+		etapi.NewClient(server.URL, "token"),
+		&NoopStore{},
+		WithSummaryStore(store),
+		WithAISummaryQueue(queue),
+		WithAISummaryEnabled(true),
+	)
+
+	if err := service.PinSummary(noteID, "ai", " Hand written. "); err != nil {
+		t.Fatalf("pin failed: %v", err)
+	}
+	summaries, err := service.GetPostSummaries(noteID)
+	if err != nil {
+		t.Fatalf("get summaries failed: %v", err)
+	}
+	if summaries.AI == nil || summaries.AI.Text != "Hand written." || !summaries.AI.Pinned || summaries.AI.Status != "ready" {
+		t.Fatalf("expected pinned AI summary, got %#v", summaries.AI)
+	}
+	if n, _ := store.CountJobs(); n != 0 {
+		t.Fatalf("expected no generation job for a pinned summary, got %d", n)
+	}
+
+	if _, err := service.RegenerateSummaryContext(t.Context(), noteID); err != nil {
+		t.Fatalf("regenerate failed: %v", err)
+	}
+	item, _ := store.GetSummary(noteID, "ai")
+	if item == nil || item.Pinned || item.Content != "" {
+		t.Fatalf("expected regenerate to unpin and reset the summary, got %#v", item)
+	}
+}
+
+func TestValidSummaryType(t *testing.T) {
+	for _, summaryType := range []string{"ai", "code", "tags", "keywords", "description", "embedding", "translation-en", "translation-zh-CN"} {
+		if !ValidSummaryType(summaryType) {
+			t.Errorf("expected %q to be a valid summary type", summaryType)
+		}
+	}
+	for _, summaryType := range []string{"", "seo", "translation-fr", "bogus"} {
+		if ValidSummaryType(summaryType) {
+			t.Errorf("expected %q to be rejected", summaryType)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// chunkSummaryPrompt is sent instead of the configured prompt for each part
+// of a long article; the configured prompt is appended so that partial
+// summaries already follow its language and tone.
+const chunkSummaryPrompt = "You are summarizing one section of a longer article. " +
+	"Write a short summary of this section only, keeping its key facts and conclusions. " +
+	"It will be combined with the summaries of the other sections. " +
+	"The final summary must follow these instructions:\n"
+
+type summaryChunk struct {
+	Headings []string
+	Text     string
+}
+
+type summarySection struct {
+	heading string
+	text    string
+}
+
+// splitSummarySections cuts the text produced by extractSummaryText at its
+// h1-h3 heading lines, the same headings extractTOC lists. Text before the
+// first heading becomes a section with no heading; "#" lines inside code
+// fences are not headings.
+func splitSummarySections(text string) []summarySection {
+	sections := []summarySection{{}}
+	var body []string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+		}
+		if heading, ok := summaryHeadingLine(line); ok && !inFence {
+			sections[len(sections)-1].text = strings.TrimSpace(strings.Join(body, "\n"))
+			sections = append(sections, summarySection{heading: heading})
+			body = []string{line}
+			continue
+		}
+		body = append(body, line)
+	}
+	sections[len(sections)-1].text = strings.TrimSpace(strings.Join(body, "\n"))
+	if sections[0].text == "" && len(sections) > 1 {
+		sections = sections[1:]
+	}
+	return sections
+}
+
+// summaryHeadingLine reports whether line is an h1-h3 heading written by
+// extractSummaryText and returns its title.
+func summaryHeadingLine(line string) (string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 3 || !strings.HasPrefix(line[level:], " ") {
+		return "", false
+	}
+	title := strings.TrimSpace(line[level:])
+	return title, title != ""
+}
+
+// splitSummaryChunks groups the sections of an article's summary text into at most
+// maxChunks chunks of about maxRunes each. When the article needs more than
+// maxChunks chunks, sections are packed into fewer, larger groups and each
+// group is clamped, so every part of the article is still represented.
+func splitSummaryChunks(content string, maxRunes, maxChunks int) []summaryChunk {
+	sections := splitSummarySections(content)
+	if maxRunes <= 0 || maxChunks <= 1 {
+		return packSummaryChunks(sections, 0, maxRunes)
+	}
+
+	total := 0
+	for _, s := range sections {
+		total += len([]rune(s.text))
+	}
+	budget := maxRunes
+	for {
+		chunks := packSummaryChunks(sections, budget, maxRunes)
+		if len(chunks) <= maxChunks {
+			return chunks
+		}
+		budget += max(maxRunes/2, total/maxChunks/4, 1)
+	}
+}
+
+// packSummaryChunks merges consecutive sections until adding the next one
+// would exceed budget (0 means everything in one chunk), then clamps each
+// chunk to maxRunes.
+func packSummaryChunks(sections []summarySection, budget, maxRunes int) []summaryChunk {
+	var chunks []summaryChunk
+	var current summaryChunk
+	size := 0
+	flush := func() {
+		if strings.TrimSpace(current.Text) != "" {
+			current.Text = clampSummaryInput(current.Text, maxRunes)
+			chunks = append(chunks, current)
+		}
+		current = summaryChunk{}
+		size = 0
+	}
+	for _, s := range sections {
+		n := len([]rune(s.text))
+		if budget > 0 && size > 0 && size+n > budget {
+			flush()
+		}
+		if s.heading != "" {
+			current.Headings = append(current.Headings, s.heading)
+		}
+		if current.Text != "" {
+			current.Text += "\n\n"
+		}
+		current.Text += s.text
+		size += n
+	}
+	flush()
+	return chunks
+}
+
+// generateChunked summarizes each chunk, then summarizes the partial
+// summaries with the configured prompt. progress is called after each step
+// with the number of finished and total steps.
+func (q *AISummaryQueue) generateChunked(ctx context.Context, job *ClaimedJob, chunks []summaryChunk, progress func(done, total int)) (AIResult, error) {
+	total := len(chunks) + 1
+	prompt := q.jobPrompt(job)
+	var usage AIUsage
+	partials := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		result, err := q.summarize(ctx, job, AIRequest{
+			Prompt:  chunkSummaryPrompt + prompt,
+			Title:   job.Title,
+			Content: chunk.Text,
+		})
+		usage.InputTokens += result.Usage.InputTokens
+		usage.OutputTokens += result.Usage.OutputTokens
+		if err != nil {
+			return AIResult{Usage: usage}, fmt.Errorf("summarizing part %d of %d: %w", i+1, len(chunks), err)
+		}
+		label := fmt.Sprintf("Part %d", i+1)
+		if len(chunk.Headings) > 0 {
+			label += ": " + strings.Join(chunk.Headings, " / ")
+		}
+		partials = append(partials, label+"\n"+result.Text)
+		progress(i+1, total)
+	}
+
+	result, err := q.summarize(ctx, job, AIRequest{
+		Prompt:  prompt,
+		Title:   job.Title,
+		Content: "Summaries of the article's sections, in order:\n\n" + strings.Join(partials, "\n\n"),
+	})
+	usage.InputTokens += result.Usage.InputTokens
+	usage.OutputTokens += result.Usage.OutputTokens
+	result.Usage = usage
+	if err == nil {
+		progress(total, total)
+	}
+	return result, err
+}
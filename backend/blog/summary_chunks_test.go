@@ -0,0 +1,133 @@
+package blog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSplitSummarySections(t *testing.T) {
+	content := `<p>Intro text.</p>
+<h2>Setup</h2><p>Install the tools.</p>
+<h3>Config</h3><p>Edit the file.</p>
+<h2></h2><p>Still config.</p>
+<h2>Usage</h2><ul><li>Run it.</li></ul>`
+
+	sections := splitSummarySections(extractSummaryText(content))
+	var headings []string
+	for _, s := range sections {
+		headings = append(headings, s.heading)
+	}
+	if got := strings.Join(headings, "|"); got != "|Setup|Config|Usage" {
+		t.Fatalf("unexpected section headings %q", got)
+	}
+	if sections[0].text != "Intro text." || !strings.HasPrefix(sections[1].text, "## Setup\n") {
+		t.Fatalf("unexpected intro %q", sections[0].text)
+	}
+	if !strings.Contains(sections[2].text, "Edit the file.") || !strings.Contains(sections[2].text, "Still config.") {
+		t.Fatalf("expected the empty heading to stay in the previous section, got %q", sections[2].text)
+	}
+	if !strings.Contains(sections[3].text, "Run it.") {
+		t.Fatalf("unexpected last section %q", sections[3].text)
+	}
+}
+
+func TestSplitSummaryChunksRespectsLimit(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 12; i++ {
+		b.WriteString("<h2>Section</h2><p>" + strings.Repeat("word ", 40) + "</p>")
+	}
+
+	text := extractSummaryText(b.String())
+	chunks := splitSummaryChunks(text, 250, 100)
+	if len(chunks) != 12 {
+		t.Fatalf("expected one chunk per section, got %d", len(chunks))
+	}
+
+	chunks = splitSummaryChunks(text, 250, 4)
+	if len(chunks) == 0 || len(chunks) > 4 {
+		t.Fatalf("expected at most 4 chunks, got %d", len(chunks))
+	}
+	headings := 0
+	for _, c := range chunks {
+		headings += len(c.Headings)
+		if n := len([]rune(c.Text)); n > 250 {
+			t.Fatalf("chunk of %d runes exceeds the input limit", n)
+		}
+	}
+	if headings != 12 {
+		t.Fatalf("expected every section to be assigned to a chunk, got %d headings", headings)
+	}
+}
+
+func TestAISummaryQueueSummarizesLongArticlesInParts(t *testing.T) {
+	var mu sync.Mutex
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		prompts = append(prompts, body.Messages[len(body.Messages)-1].Content)
+		n := len(prompts)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		reply := "partial"
+		if strings.Contains(body.Messages[len(body.Messages)-1].Content, "Summaries of the article's sections") {
+			reply = "final"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": reply}}},
+			"usage":   map[string]int{"prompt_tokens": 10 * n, "completion_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", server.URL, "token", "model", "prompt", 1, 1, 1000, 300,
+		WithAILongDocument(3), WithSummaryHub(NewSummaryHub()))
+	defer queue.Close()
+	events, cancel := queue.Hub().Subscribe("n1")
+	defer cancel()
+
+	var b strings.Builder
+	for _, h := range []string{"One", "Two", "Three"} {
+		b.WriteString("<h2>" + h + "</h2><p>" + strings.Repeat(h+" text. ", 30) + "</p>")
+	}
+	queue.Enqueue(AISummaryJob{NoteID: "n1", Title: "T", Content: extractSummaryText(b.String()), SourceHash: "h"})
+	item := waitForSummaryStatus(t, store, "n1", "ready")
+
+	if item.Content != "final" || item.ChunksTotal != 0 {
+		t.Fatalf("unexpected final summary %#v", item)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(prompts) != 4 {
+		t.Fatalf("expected 3 part summaries and 1 combining call, got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[3], "Part 2: Two\npartial") {
+		t.Fatalf("expected partial summaries in the final request, got %q", prompts[3])
+	}
+
+	var progress []SummaryProgress
+	for len(events) > 0 {
+		if p := (<-events).Entry.Progress; p != nil {
+			progress = append(progress, *p)
+		}
+	}
+	if len(progress) == 0 || progress[len(progress)-1].Total != 4 {
+		t.Fatalf("expected progress events out of 4 steps, got %#v", progress)
+	}
+	report, err := queue.UsageReport("day", 0)
+	if err != nil || len(report.Items) != 1 || report.Items[0].Calls != 4 {
+		t.Fatalf("expected every call to be recorded, got %#v (%v)", report, err)
+	}
+}
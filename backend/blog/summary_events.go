@@ -0,0 +1,77 @@
+package blog
+
+import "sync"
+
+// summaryEventBuffer is how many undelivered events a subscriber may fall
+// behind by before the oldest ones are dropped.
+const summaryEventBuffer = 8
+
+// SummaryEvent announces that a stored summary changed.
+type SummaryEvent struct {
+	NoteID string
+	Entry  SummaryEntry
+}
+
+// SummaryHub fans summary status changes out to in-process subscribers,
+// keyed by note ID. Publishing never blocks: a subscriber that does not keep
+// up loses its oldest events, so the latest status always gets through.
+type SummaryHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan SummaryEvent]struct{}
+}
+
+func NewSummaryHub() *SummaryHub {
+	return &SummaryHub{subs: make(map[string]map[chan SummaryEvent]struct{})}
+}
+
+// Subscribe returns a channel of events for noteID and a function that
+// unsubscribes and closes it.
+func (h *SummaryHub) Subscribe(noteID string) (<-chan SummaryEvent, func()) {
+	ch := make(chan SummaryEvent, summaryEventBuffer)
+	h.mu.Lock()
+	if h.subs[noteID] == nil {
+		h.subs[noteID] = make(map[chan SummaryEvent]struct{})
+	}
+	h.subs[noteID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[noteID], ch)
+			if len(h.subs[noteID]) == 0 {
+				delete(h.subs, noteID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (h *SummaryHub) Publish(event SummaryEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[event.NoteID] {
+		select {
+		case ch <- event:
+			continue
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers reports how many subscribers are listening on noteID.
+func (h *SummaryHub) Subscribers(noteID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[noteID])
+}
@@ -0,0 +1,88 @@
+package blog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummaryHubDeliversToSubscribersOfTheNote(t *testing.T) {
+	hub := NewSummaryHub()
+	events, cancel := hub.Subscribe("a")
+	other, cancelOther := hub.Subscribe("b")
+	defer cancelOther()
+
+	hub.Publish(SummaryEvent{NoteID: "a", Entry: SummaryEntry{Status: "processing"}})
+	if got := <-events; got.Entry.Status != "processing" {
+		t.Fatalf("unexpected event %#v", got)
+	}
+	select {
+	case got := <-other:
+		t.Fatalf("subscriber of another note got %#v", got)
+	default:
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatalf("expected channel to be closed after cancel")
+	}
+	if n := hub.Subscribers("a"); n != 0 {
+		t.Fatalf("expected no subscribers left, got %d", n)
+	}
+	hub.Publish(SummaryEvent{NoteID: "a"})
+}
+
+func TestSummaryHubDropsOldestForSlowSubscribers(t *testing.T) {
+	hub := NewSummaryHub()
+	events, cancel := hub.Subscribe("a")
+	defer cancel()
+
+	for i := 0; i < summaryEventBuffer+3; i++ {
+		hub.Publish(SummaryEvent{NoteID: "a", Entry: SummaryEntry{Attempts: i}})
+	}
+	var last SummaryEvent
+	for i := 0; i < summaryEventBuffer; i++ {
+		last = <-events
+	}
+	if last.Entry.Attempts != summaryEventBuffer+2 {
+		t.Fatalf("expected the latest event to survive, got attempt %d", last.Entry.Attempts)
+	}
+}
+
+func TestAISummaryQueuePublishesStatusChanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"done"}}]}`))
+	}))
+	defer server.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	hub := NewSummaryHub()
+	events, cancel := hub.Subscribe("n1")
+	defer cancel()
+	queue := NewAISummaryQueue(store, "openai-compatible", server.URL, "token", "model", "prompt", 1, 1, 1000, 2000, WithSummaryHub(hub))
+	defer queue.Close()
+
+	queue.Enqueue(AISummaryJob{NoteID: "n1", Title: "T", Content: "body", SourceHash: "h"})
+
+	var statuses []string
+	timeout := time.After(3 * time.Second)
+	for len(statuses) < 2 {
+		select {
+		case event := <-events:
+			statuses = append(statuses, event.Entry.Status)
+			if event.Entry.Type != "ai" {
+				t.Fatalf("unexpected entry type %q", event.Entry.Type)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for events, got %v", statuses)
+		}
+	}
+	if statuses[0] != "processing" || statuses[1] != "ready" {
+		t.Fatalf("expected processing then ready, got %v", statuses)
+	}
+}
@@ -0,0 +1,301 @@
+package blog
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	jobStatusQueued = "queued"
+	jobStatusLeased = "leased"
+)
+
+// SummaryJobStore persists AI summary jobs so that they survive restarts.
+// A job row exists from Enqueue until the worker that leased it calls
+// CompleteJob; a lease that expires (crashed or stuck worker) makes the job
+// claimable again.
+type SummaryJobStore interface {
+	EnqueueJob(job AISummaryJob, runAt time.Time) (bool, error)
+	ClaimJob(owner string, now time.Time, lease time.Duration) (*ClaimedJob, error)
+	CompleteJob(noteID, summaryType, owner, sourceHash string) error
+	RescheduleJob(noteID, summaryType, owner string, runAt time.Time) error
+	RecoverJobs() (int, error)
+	CountJobs() (int, error)
+}
+
+// SummaryQueueStore is what AISummaryQueue needs from its backing store.
+type SummaryQueueStore interface {
+	SummaryStore
+	SummaryJobStore
+	SummaryUsageStore
+}
+
+type ClaimedJob struct {
+	AISummaryJob
+	Attempts int
+}
+
+// aiJobKind describes what a job of one type generates.
+type aiJobKind struct {
+	// types are the summary rows the job writes; its status is reported on
+	// each of them.
+	types []string
+	// chunked allows long articles to be summarized in parts.
+	chunked bool
+	// prompt is used when the job carries none; empty means the queue's.
+	prompt string
+	// embed computes an embedding with the queue's Embedder instead of
+	// calling the summary provider.
+	embed bool
+	// translate translates the job's HTML in parts, keeping code and images.
+	translate bool
+	// parse splits the provider's answer into the content of each row.
+	parse func(text string) (map[string]string, error)
+}
+
+var aiJobKinds = map[string]aiJobKind{
+	"ai": {
+		types:   []string{"ai"},
+		chunked: true,
+		parse: func(text string) (map[string]string, error) {
+			return map[string]string{"ai": text}, nil
+		},
+	},
+	"seo": {
+		types:  seoSummaryTypes,
+		prompt: defaultSEOPrompt,
+		parse:  parseSEOResult,
+	},
+	"embedding": {
+		types: []string{"embedding"},
+		embed: true,
+		parse: func(text string) (map[string]string, error) {
+			return map[string]string{"embedding": text}, nil
+		},
+	},
+}
+
+func jobType(t string) string {
+	if t == "" {
+		return "ai"
+	}
+	return t
+}
+
+// summaryJobType returns the type of the job that writes summary rows of
+// rowType. Most rows share their job's type; the SEO job writes tags,
+// keywords and description.
+func summaryJobType(rowType string) string {
+	for t, kind := range aiJobKinds {
+		if slices.Contains(kind.types, rowType) {
+			return t
+		}
+	}
+	return rowType
+}
+
+// summaryJobTypeSQL is summaryJobType as an SQL expression over
+// summaries.type, for joining summary rows to their jobs.
+func summaryJobTypeSQL() string {
+	var cases []string
+	for t, kind := range aiJobKinds {
+		for _, rowType := range kind.types {
+			if rowType != t {
+				cases = append(cases, fmt.Sprintf("WHEN '%s' THEN '%s'", rowType, t))
+			}
+		}
+	}
+	if len(cases) == 0 {
+		return "summaries.type"
+	}
+	sort.Strings(cases)
+	return "CASE summaries.type " + strings.Join(cases, " ") + " ELSE summaries.type END"
+}
+
+func jobKind(jobType string) aiJobKind {
+	if kind, ok := aiJobKinds[jobType]; ok {
+		return kind
+	}
+	if locale, ok := translationLocale(jobType); ok {
+		return translationJobKind(jobType, locale)
+	}
+	return aiJobKinds["ai"]
+}
+
+func (s *SummaryStoreDB) initJobs() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS summary_jobs (
+			note_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			content TEXT NOT NULL DEFAULT '',
+			source_hash TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_run_at INTEGER NOT NULL,
+			lease_owner TEXT NOT NULL DEFAULT '',
+			lease_expires_at INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			PRIMARY KEY (note_id, type)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS summary_jobs_next_run ON summary_jobs (status, next_run_at)`); err != nil {
+		return err
+	}
+	return s.ensureColumn("summary_jobs", "prompt", "prompt TEXT NOT NULL DEFAULT ''")
+}
+
+// EnqueueJob adds an AI summary job, or replaces an existing one whose
+// source hash differs. It reports false when an identical job is already
+// queued or running. A running job keeps its lease and only takes the new
+// content, which CompleteJob queues again once the worker is done.
+func (s *SummaryStoreDB) EnqueueJob(job AISummaryJob, runAt time.Time) (bool, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := s.db.Exec(`
+		INSERT INTO summary_jobs (note_id, type, title, content, prompt, source_hash, status, attempts, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?)
+		ON CONFLICT(note_id, type) DO UPDATE SET
+			title = excluded.title,
+			content = excluded.content,
+			prompt = excluded.prompt,
+			source_hash = excluded.source_hash,
+			status = CASE WHEN summary_jobs.status = ? THEN summary_jobs.status ELSE excluded.status END,
+			attempts = CASE WHEN summary_jobs.status = ? THEN summary_jobs.attempts ELSE 0 END,
+			next_run_at = excluded.next_run_at,
+			lease_owner = CASE WHEN summary_jobs.status = ? THEN summary_jobs.lease_owner ELSE '' END,
+			lease_expires_at = CASE WHEN summary_jobs.status = ? THEN summary_jobs.lease_expires_at ELSE 0 END,
+			updated_at = excluded.updated_at
+		WHERE summary_jobs.source_hash != excluded.source_hash
+	`, job.NoteID, jobType(job.Type), job.Title, job.Content, job.Prompt, job.SourceHash, jobStatusQueued, runAt.UnixMilli(), now, now,
+		jobStatusLeased, jobStatusLeased, jobStatusLeased, jobStatusLeased)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ClaimJob leases the next runnable job to owner: a queued job whose
+// next_run_at has passed, or a leased job whose lease has expired. It returns
+// nil when there is nothing to do.
+func (s *SummaryStoreDB) ClaimJob(owner string, now time.Time, lease time.Duration) (*ClaimedJob, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	nowMs := now.UnixMilli()
+	var job ClaimedJob
+	err = tx.QueryRow(`
+		SELECT note_id, type, title, content, prompt, source_hash, attempts
+		FROM summary_jobs
+		WHERE (status = ? AND next_run_at <= ?) OR (status = ? AND lease_expires_at <= ?)
+		ORDER BY next_run_at, created_at
+		LIMIT 1
+	`, jobStatusQueued, nowMs, jobStatusLeased, nowMs).Scan(
+		&job.NoteID, &job.Type, &job.Title, &job.Content, &job.Prompt, &job.SourceHash, &job.Attempts)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.Attempts++
+	if _, err := tx.Exec(`
+		UPDATE summary_jobs
+		SET status = ?, attempts = ?, lease_owner = ?, lease_expires_at = ?, updated_at = ?
+		WHERE note_id = ? AND type = ?
+	`, jobStatusLeased, job.Attempts, owner, now.Add(lease).UnixMilli(), now.UTC().Format(time.RFC3339), job.NoteID, job.Type); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CompleteJob removes a finished job. It is a no-op when owner lost the
+// lease. When the job was given new content while owner worked on the one
+// with sourceHash, the job is queued again instead.
+func (s *SummaryStoreDB) CompleteJob(noteID, summaryType, owner, sourceHash string) error {
+	if _, err := s.db.Exec(`
+		DELETE FROM summary_jobs
+		WHERE note_id = ? AND type = ? AND status = ? AND lease_owner = ? AND source_hash = ?
+	`, noteID, summaryType, jobStatusLeased, owner, sourceHash); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`
+		UPDATE summary_jobs
+		SET status = ?, attempts = 0, lease_owner = '', lease_expires_at = 0, updated_at = ?
+		WHERE note_id = ? AND type = ? AND status = ? AND lease_owner = ?
+	`, jobStatusQueued, time.Now().UTC().Format(time.RFC3339), noteID, summaryType, jobStatusLeased, owner)
+	return err
+}
+
+// RescheduleJob hands a failed job back to the queue to run again at runAt,
+// keeping its attempt count. Like CompleteJob it only acts on owner's lease.
+func (s *SummaryStoreDB) RescheduleJob(noteID, summaryType, owner string, runAt time.Time) error {
+	_, err := s.db.Exec(`
+		UPDATE summary_jobs
+		SET status = ?, next_run_at = ?, lease_owner = '', lease_expires_at = 0, updated_at = ?
+		WHERE note_id = ? AND type = ? AND status = ? AND lease_owner = ?
+	`, jobStatusQueued, runAt.UnixMilli(), time.Now().UTC().Format(time.RFC3339), noteID, summaryType, jobStatusLeased, owner)
+	return err
+}
+
+// RecoverJobs runs at startup. Jobs leased by the previous process are put
+// back in the queue and their summaries marked pending again. Summaries left
+// pending, processing or deferred without a job (written before jobs were
+// persisted, or whose job was cleared) are reset so that the next view of the
+// post enqueues them. It returns the number of requeued jobs.
+func (s *SummaryStoreDB) RecoverJobs() (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.Exec(`
+		UPDATE summary_jobs
+		SET status = ?, lease_owner = '', lease_expires_at = 0, updated_at = ?
+		WHERE status = ?
+	`, jobStatusQueued, now, jobStatusLeased)
+	if err != nil {
+		return 0, err
+	}
+	requeued, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	hasJob := `SELECT 1 FROM summary_jobs j WHERE j.note_id = summaries.note_id AND j.type = ` + summaryJobTypeSQL()
+	if _, err := tx.Exec(`
+		UPDATE summaries SET status = 'pending', updated_at = ?
+		WHERE status = 'processing' AND EXISTS (`+hasJob+`)
+	`, now); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE summaries SET status = '', updated_at = ?
+		WHERE status IN ('pending', 'processing', 'deferred') AND NOT EXISTS (`+hasJob+`)
+	`, now); err != nil {
+		return 0, err
+	}
+	return int(requeued), tx.Commit()
+}
+
+func (s *SummaryStoreDB) CountJobs() (int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM summary_jobs WHERE status = ?`, jobStatusQueued).Scan(&n)
+	return n, err
+}
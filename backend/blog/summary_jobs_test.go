@@ -0,0 +1,197 @@
+package blog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestSummaryStore(t *testing.T, path string) *SummaryStoreDB {
+	t.Helper()
+	store, err := NewSummaryStoreDB(path)
+	if err != nil {
+		t.Fatalf("failed to create summary store: %v", err)
+	}
+	return store
+}
+
+func TestSummaryJobsClaimLeasesOnce(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+
+	now := time.Now()
+	if added, err := store.EnqueueJob(AISummaryJob{NoteID: "n1", Title: "T", Content: "C", SourceHash: "h1"}, now); err != nil || !added {
+		t.Fatalf("expected job to be added, got %v (%v)", added, err)
+	}
+	if added, _ := store.EnqueueJob(AISummaryJob{NoteID: "n1", SourceHash: "h1"}, now); added {
+		t.Fatalf("expected identical job to be deduplicated")
+	}
+
+	job, err := store.ClaimJob("a", now, time.Minute)
+	if err != nil || job == nil {
+		t.Fatalf("expected to claim job, got %v (%v)", job, err)
+	}
+	if job.Attempts != 1 || job.Title != "T" || job.Content != "C" {
+		t.Fatalf("unexpected claimed job %#v", job)
+	}
+	if other, _ := store.ClaimJob("b", now, time.Minute); other != nil {
+		t.Fatalf("expected leased job not to be claimed twice")
+	}
+
+	// An expired lease makes the job claimable again.
+	stolen, err := store.ClaimJob("b", now.Add(2*time.Minute), time.Minute)
+	if err != nil || stolen == nil || stolen.Attempts != 2 {
+		t.Fatalf("expected expired lease to be reclaimed, got %#v (%v)", stolen, err)
+	}
+
+	// The original owner lost the lease, so its completion is ignored.
+	if err := store.CompleteJob("n1", "ai", "a", "h1"); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	if n, _ := store.CountJobs(); n != 0 {
+		t.Fatalf("expected no queued jobs while leased, got %d", n)
+	}
+	if err := store.CompleteJob("n1", "ai", "b", "h1"); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	if job, _ := store.ClaimJob("c", now.Add(time.Hour), time.Minute); job != nil {
+		t.Fatalf("expected completed job to be gone, got %#v", job)
+	}
+}
+
+func TestSummaryJobsKeepLeaseWhenContentChanges(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+
+	now := time.Now()
+	_, _ = store.EnqueueJob(AISummaryJob{NoteID: "n1", Content: "old", SourceHash: "h1"}, now)
+	if job, _ := store.ClaimJob("a", now, time.Minute); job == nil {
+		t.Fatalf("expected to claim job")
+	}
+	if added, err := store.EnqueueJob(AISummaryJob{NoteID: "n1", Content: "new", SourceHash: "h2"}, now); err != nil || !added {
+		t.Fatalf("expected new content to be taken, got %v (%v)", added, err)
+	}
+	if job, _ := store.ClaimJob("b", now, time.Minute); job != nil {
+		t.Fatalf("expected the running job to stay leased, got %#v", job)
+	}
+
+	if err := store.CompleteJob("n1", "ai", "a", "h1"); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	job, err := store.ClaimJob("b", now, time.Minute)
+	if err != nil || job == nil || job.Content != "new" || job.SourceHash != "h2" || job.Attempts != 1 {
+		t.Fatalf("expected the new content to be queued after completion, got %#v (%v)", job, err)
+	}
+	if err := store.CompleteJob("n1", "ai", "b", "h2"); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	if n, _ := store.CountJobs(); n != 0 {
+		t.Fatalf("expected the job to be done, got %d queued", n)
+	}
+}
+
+func TestSummaryJobsRespectNextRunAt(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+
+	now := time.Now()
+	_, _ = store.EnqueueJob(AISummaryJob{NoteID: "later", SourceHash: "h"}, now.Add(time.Minute))
+	if job, _ := store.ClaimJob("a", now, time.Minute); job != nil {
+		t.Fatalf("expected future job not to be claimable yet")
+	}
+	if job, _ := store.ClaimJob("a", now.Add(time.Minute), time.Minute); job == nil {
+		t.Fatalf("expected job to be claimable once due")
+	}
+}
+
+func TestSummaryJobsRecoverResetsOrphanedSummaries(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+
+	_, _ = store.EnqueueJob(AISummaryJob{NoteID: "queued", SourceHash: "h"}, time.Now())
+	_ = store.UpsertSummary(StoredSummary{NoteID: "queued", Type: "ai", Status: "deferred", SourceHash: "h"})
+	_ = store.UpsertSummary(StoredSummary{NoteID: "orphan", Type: "ai", Status: "deferred", SourceHash: "h"})
+
+	if _, err := store.RecoverJobs(); err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if item, _ := store.GetSummary("queued", "ai"); item == nil || item.Status != "deferred" {
+		t.Fatalf("expected deferred summary with a job to stay deferred, got %#v", item)
+	}
+	if item, _ := store.GetSummary("orphan", "ai"); item == nil || item.Status != "" {
+		t.Fatalf("expected deferred summary without a job to be reset, got %#v", item)
+	}
+}
+
+func TestSummaryJobsRecoverMatchesSEORowsToTheirJob(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+
+	_, _ = store.EnqueueJob(AISummaryJob{NoteID: "n1", Type: "seo", SourceHash: "h"}, time.Now())
+	if job, _ := store.ClaimJob("dead-process", time.Now(), time.Hour); job == nil {
+		t.Fatalf("expected to claim job")
+	}
+	for _, rowType := range seoSummaryTypes {
+		_ = store.UpsertSummary(StoredSummary{NoteID: "n1", Type: rowType, Status: "processing", SourceHash: "h"})
+	}
+
+	if n, err := store.RecoverJobs(); err != nil || n != 1 {
+		t.Fatalf("expected one requeued job, got %d (%v)", n, err)
+	}
+	for _, rowType := range seoSummaryTypes {
+		if item, _ := store.GetSummary("n1", rowType); item == nil || item.Status != "pending" {
+			t.Fatalf("expected %s row of a requeued seo job to be pending, got %#v", rowType, item)
+		}
+	}
+}
+
+func TestAISummaryQueueResumesJobsAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summaries.db")
+
+	// A previous process leased the job and died mid-generation.
+	store := newTestSummaryStore(t, path)
+	_, _ = store.EnqueueJob(AISummaryJob{NoteID: "n1", Title: "T", Content: "body", SourceHash: "h"}, time.Now())
+	if job, _ := store.ClaimJob("dead-process", time.Now(), time.Hour); job == nil {
+		t.Fatalf("expected to claim job")
+	}
+	_ = store.UpsertSummary(StoredSummary{NoteID: "n1", Type: "ai", Status: "processing", SourceHash: "h"})
+	// A summary stuck from before jobs were persisted has nothing to resume.
+	_ = store.UpsertSummary(StoredSummary{NoteID: "legacy", Type: "ai", Status: "processing", SourceHash: "x"})
+	store.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"resumed summary"}}]}`))
+	}))
+	defer server.Close()
+
+	store = newTestSummaryStore(t, path)
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", server.URL, "token", "model", "prompt", 1, 1, 1000, 2000)
+	defer queue.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		item, err := store.GetSummary("n1", "ai")
+		if err != nil {
+			t.Fatalf("get summary failed: %v", err)
+		}
+		if item != nil && item.Status == "ready" {
+			if item.Content != "resumed summary" {
+				t.Fatalf("unexpected summary %q", item.Content)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected recovered job to complete, last status %#v", item)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	legacy, _ := store.GetSummary("legacy", "ai")
+	if legacy == nil || legacy.Status != "" {
+		t.Fatalf("expected orphaned processing summary to be reset, got %#v", legacy)
+	}
+}
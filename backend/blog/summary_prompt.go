@@ -0,0 +1,223 @@
+package blog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+)
+
+// Built-in prompts, used for a locale that has no configured template.
+var defaultSummaryPrompts = map[string]string{
+	"zh-CN": `请用简体中文为博客读者总结文章《{{.Title}}》，字数控制在约 {{.TargetLength}} 字。` +
+		`突出核心观点和结论，不要展开解释，不要使用列表或任何格式，仅输出一段纯文本。` +
+		`{{if .Tags}}文章标签：{{join .Tags "、"}}。{{end}}`,
+	"en": `Summarize the article "{{.Title}}" for a blog reader in English, in about {{.TargetLength}} words. ` +
+		`Focus on its key points and conclusions. Do not use lists or any formatting; output a single plain-text paragraph.` +
+		`{{if .Tags}} Tags: {{join .Tags ", "}}.{{end}}`,
+}
+
+// fallbackSummaryPrompt is the queue's prompt when none is configured, used
+// for jobs that carry no prompt of their own.
+const fallbackSummaryPrompt = "Summarize the article for a blog reader in one concise plain-text paragraph, in the language of the article."
+
+var summaryLanguageNames = map[string]string{
+	"zh-CN": "Simplified Chinese",
+	"en":    "English",
+}
+
+// SummaryPromptData is what prompt templates can use.
+type SummaryPromptData struct {
+	Title  string
+	Locale string
+	// Language is the English name of Locale, e.g. "English".
+	Language     string
+	Tags         []string
+	TargetLength int
+}
+
+var summaryPromptFuncs = template.FuncMap{"join": strings.Join}
+
+// SummaryPrompts renders the system prompt for each AI summary. A note can
+// pick another locale with a #summaryLang label or bring its own template
+// with #summaryPrompt.
+type SummaryPrompts struct {
+	defaultLocale string
+	targetLength  int
+	templates     map[string]*template.Template
+}
+
+// NewSummaryPrompts parses the prompt templates. byLocale holds the
+// templates configured per locale; fallback, when set, is used for locales
+// without one instead of the built-in prompt.
+func NewSummaryPrompts(defaultLocale string, targetLength int, fallback string, byLocale map[string]string) (*SummaryPrompts, error) {
+	p := &SummaryPrompts{
+		defaultLocale: normalizeSummaryLocale(defaultLocale),
+		targetLength:  targetLength,
+		templates:     make(map[string]*template.Template),
+	}
+	for locale, builtIn := range defaultSummaryPrompts {
+		text := byLocale[locale]
+		if strings.TrimSpace(text) == "" {
+			text = fallback
+		}
+		if strings.TrimSpace(text) == "" {
+			text = builtIn
+		}
+		tmpl, err := parseSummaryPrompt(locale, text)
+		if err != nil {
+			return nil, err
+		}
+		// Parsing accepts unknown fields such as {{.Author}}; executing once
+		// catches them here instead of on every summary.
+		sample := SummaryPromptData{
+			Title:        "Title",
+			Locale:       locale,
+			Language:     summaryLanguageNames[locale],
+			Tags:         []string{"tag"},
+			TargetLength: targetLength,
+		}
+		if err := tmpl.Execute(io.Discard, sample); err != nil {
+			return nil, fmt.Errorf("rendering %s summary prompt: %w", locale, err)
+		}
+		p.templates[locale] = tmpl
+	}
+	return p, nil
+}
+
+func parseSummaryPrompt(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(summaryPromptFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s summary prompt: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Render returns the prompt for a note. An invalid #summaryPrompt is logged
+// and the locale's template is used instead.
+func (p *SummaryPrompts) Render(noteID, title string, attrs []etapi.Attribute) (string, error) {
+	locale := noteSummaryLocale(noteID, p.defaultLocale, attrs)
+	data := SummaryPromptData{
+		Title:        title,
+		Locale:       locale,
+		Language:     summaryLanguageNames[locale],
+		Tags:         noteTags(attrs),
+		TargetLength: p.targetLength,
+	}
+
+	if override := labelValue(attrs, "summaryPrompt"); override != "" {
+		text, err := renderSummaryPrompt("summaryPrompt", override, data)
+		if err == nil {
+			return text, nil
+		}
+		logger.Logger.Warn().Err(err).Str("note_id", noteID).Msg("Ignoring invalid #summaryPrompt")
+	}
+	var b strings.Builder
+	if err := p.templates[locale].Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering %s summary prompt: %w", locale, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func renderSummaryPrompt(name, text string, data SummaryPromptData) (string, error) {
+	tmpl, err := parseSummaryPrompt(name, text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering %s: %w", name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// summaryPrompt renders the prompt for a note, or returns "" to use the
+// queue's prompt when no templates are configured or rendering fails.
+func (s *Service) summaryPrompt(noteID, title string, attrs []etapi.Attribute) string {
+	if s.summaryPrompts == nil {
+		return ""
+	}
+	prompt, err := s.summaryPrompts.Render(noteID, title, attrs)
+	if err != nil {
+		logger.Logger.Warn().Err(err).Str("note_id", noteID).Msg("Failed to render AI summary prompt")
+		return ""
+	}
+	return prompt
+}
+
+// normalizeSummaryLocale maps a config value to a locale that has a
+// prompt, "zh-CN" when it has none.
+func normalizeSummaryLocale(locale string) string {
+	if normalized, ok := summaryLocale(locale); ok {
+		return normalized
+	}
+	return "zh-CN"
+}
+
+// summaryLocale maps a language name or tag such as "en-US" to a locale
+// that has a prompt, reporting false for other languages.
+func summaryLocale(lang string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(lang)) {
+	case "en", "en-us", "en-gb", "english":
+		return "en", true
+	case "zh", "zh-cn", "zh-hans", "zh-hans-cn", "chinese":
+		return "zh-CN", true
+	default:
+		return "", false
+	}
+}
+
+// noteSummaryLocale returns the locale picked by a note's #summaryLang
+// label, or defaultLocale without one. An unsupported language is logged
+// and ignored.
+func noteSummaryLocale(noteID, defaultLocale string, attrs []etapi.Attribute) string {
+	lang := labelValue(attrs, "summaryLang")
+	if lang == "" {
+		return defaultLocale
+	}
+	locale, ok := summaryLocale(lang)
+	if !ok {
+		logger.Logger.Warn().Str("note_id", noteID).Str("summary_lang", lang).Msg("Unsupported #summaryLang; using the default language")
+		return defaultLocale
+	}
+	return locale
+}
+
+// summaryPromptHash folds the prompt into the content hash of an AI summary,
+// so that changing the prompt regenerates it. An empty prompt leaves the
+// hash unchanged.
+func summaryPromptHash(hash, prompt string) string {
+	if prompt == "" {
+		return hash
+	}
+	return contentHash(hash + "\x00" + prompt)
+}
+
+func labelValue(attrs []etapi.Attribute, name string) string {
+	for _, a := range attrs {
+		if a.Type == "label" && a.Name == name {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+// noteTags collects the values of #tag labels; a value may hold several
+// comma-separated tags.
+func noteTags(attrs []etapi.Attribute) []string {
+	var tags []string
+	for _, a := range attrs {
+		if a.Type != "label" || a.Name != "tag" {
+			continue
+		}
+		for _, tag := range strings.Split(a.Value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,136 @@
+package blog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestSummaryPromptsRender(t *testing.T) {
+	prompts, err := NewSummaryPrompts("zh-CN", 80, "", map[string]string{
+		"en": "Summarize {{.Title}} in {{.Language}} in {{.TargetLength}} words.",
+	})
+	if err != nil {
+		t.Fatalf("failed to parse prompts: %v", err)
+	}
+
+	got, err := prompts.Render("n1", "Go 并发", []etapi.Attribute{etapitest.Label("tag", "go, concurrency")})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if !strings.Contains(got, "《Go 并发》") || !strings.Contains(got, "约 80 字") || !strings.Contains(got, "go、concurrency") {
+		t.Fatalf("unexpected built-in Chinese prompt %q", got)
+	}
+
+	got, _ = prompts.Render("n1", "Channels", []etapi.Attribute{etapitest.Label("summaryLang", "en-US")})
+	if got != "Summarize Channels in English in 80 words." {
+		t.Fatalf("unexpected English prompt %q", got)
+	}
+
+	got, _ = prompts.Render("n1", "Channels", []etapi.Attribute{etapitest.Label("summaryPrompt", "One line about {{.Title}} ({{.Locale}}).")})
+	if got != "One line about Channels (zh-CN)." {
+		t.Fatalf("unexpected per-note prompt %q", got)
+	}
+
+	got, _ = prompts.Render("n1", "Channels", []etapi.Attribute{
+		etapitest.Label("summaryLang", "en"),
+		etapitest.Label("summaryPrompt", "{{.Missing"),
+	})
+	if got != "Summarize Channels in English in 80 words." {
+		t.Fatalf("expected an invalid per-note prompt to fall back, got %q", got)
+	}
+}
+
+func TestNewSummaryPromptsRejectsInvalidTemplates(t *testing.T) {
+	if _, err := NewSummaryPrompts("en", 100, "{{.Title", nil); err == nil {
+		t.Fatalf("expected a parse error")
+	}
+	if _, err := NewSummaryPrompts("en", 100, "", map[string]string{"en": "Summarize {{.Title}} by {{.Author}}."}); err == nil {
+		t.Fatalf("expected an unknown field to be rejected at startup")
+	}
+}
+
+func TestSummaryPromptsIgnoreUnsupportedSummaryLang(t *testing.T) {
+	prompts, err := NewSummaryPrompts("en", 80, "", map[string]string{
+		"en": "Summarize {{.Title}} in {{.Language}}.",
+	})
+	if err != nil {
+		t.Fatalf("failed to parse prompts: %v", err)
+	}
+	got, _ := prompts.Render("n1", "Channels", []etapi.Attribute{etapitest.Label("summaryLang", "ja")})
+	if got != "Summarize Channels in English." {
+		t.Fatalf("expected an unsupported language to keep the default, got %q", got)
+	}
+	if got, _ := prompts.Render("n1", "通道", []etapi.Attribute{etapitest.Label("summaryLang", "zh")}); !strings.Contains(got, "《通道》") {
+		t.Fatalf("expected zh to pick the Chinese prompt, got %q", got)
+	}
+}
+
+func TestEnsureSummariesRegeneratesWhenPromptChanges(t *testing.T) {
+	noteID := "note-prompt"
+	blogServer := newBlogTestServer(t, noteID, "<h2>Intro</h2><p>Body text for the summary.</p>")
+	defer blogServer.Close()
+
+	var mu sync.Mutex
+	var systemPrompts []string
+	aiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		systemPrompts = append(systemPrompts, body.Messages[0].Content)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"summary"}}]}`))
+	}))
+	defer aiServer.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", aiServer.URL, "token", "model", "queue prompt", 1, 1, 30000, 2000)
+	defer queue.Close()
+
+	newService := func(prompt string) *Service {
+		prompts, err := NewSummaryPrompts("en", 50, prompt, nil)
+		if err != nil {
+			t.Fatalf("failed to parse prompts: %v", err)
+		}
+		return NewService(etapi.NewClient(blogServer.URL, "token"), &NoopStore{},
+			WithSummaryStore(store), WithAISummaryQueue(queue), WithAISummaryEnabled(true),
+			WithSummaryPrompts(prompts))
+	}
+
+	if _, err := newService("First prompt for {{.Title}}").GetPostSummaries(noteID); err != nil {
+		t.Fatalf("summary fetch failed: %v", err)
+	}
+	first := waitForSummaryStatus(t, store, noteID, "ready")
+
+	summaries, err := newService("Second prompt for {{.Title}}").GetPostSummaries(noteID)
+	if err != nil {
+		t.Fatalf("summary fetch failed: %v", err)
+	}
+	if summaries.AI == nil || summaries.AI.Status != "pending" {
+		t.Fatalf("expected a changed prompt to queue a new summary, got %#v", summaries.AI)
+	}
+	second := waitForSummaryStatus(t, store, noteID, "ready")
+	if second.SourceHash == first.SourceHash {
+		t.Fatalf("expected the source hash to include the prompt")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(systemPrompts) != 2 || systemPrompts[0] != "First prompt for Test Post" || systemPrompts[1] != "Second prompt for Test Post" {
+		t.Fatalf("unexpected prompts sent to the provider: %q", systemPrompts)
+	}
+}
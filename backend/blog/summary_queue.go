@@ -1,42 +1,117 @@
 package blog
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+	"github.com/harveyTon/trilium-blog/backend/pkg/metrics"
 )
 
 type AISummaryJob struct {
-	NoteID     string
-	Title      string
-	Content    string
+	NoteID string
+	// Type selects what the job generates, see aiJobKinds; empty means "ai".
+	Type    string
+	Title   string
+	Content string
+	// Prompt is the rendered system prompt; empty uses the queue's prompt.
+	Prompt     string
 	SourceHash string
 }
 
+// aiJobPollInterval is how often idle workers look for jobs whose
+// next_run_at has arrived or whose lease has expired. Enqueue wakes workers
+// immediately, so this only bounds the delay for those cases.
+const aiJobPollInterval = 2 * time.Second
+
 type AISummaryQueue struct {
-	store         SummaryStore
-	provider      string
-	baseURL       string
-	apiKey        string
+	store         SummaryQueueStore
+	provider      AIProvider
+	providerErr   error
 	model         string
 	prompt        string
 	concurrency   int
 	rateLimit     time.Duration
+	lease         time.Duration
+	retry         AIRetryPolicy
 	maxInputRunes int
+	hub           *SummaryHub
+	pricing       AIPricing
+	monthlyBudget float64
+	maxChunks     int
+	embedder      Embedder
+	embeddings    EmbeddingStore
+	queryCache    queryEmbeddingCache
+	paused        atomic.Bool
+
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+type AISummaryQueueOption func(*AISummaryQueue)
+
+// WithAIRetryPolicy sets how often and how quickly failed summaries are
+// retried. maxAttempts counts the first try; 1 disables retries.
+func WithAIRetryPolicy(maxAttempts int, baseDelay, maxDelay time.Duration) AISummaryQueueOption {
+	return func(q *AISummaryQueue) {
+		if maxAttempts > 0 {
+			q.retry.MaxAttempts = maxAttempts
+		}
+		if baseDelay > 0 {
+			q.retry.BaseDelay = baseDelay
+		}
+		if maxDelay > 0 {
+			q.retry.MaxDelay = maxDelay
+		}
+	}
+}
+
+// WithAIPricing sets the price per million input and output tokens used to
+// estimate the cost of each call.
+func WithAIPricing(inputPerMTok, outputPerMTok float64) AISummaryQueueOption {
+	return func(q *AISummaryQueue) {
+		q.pricing = AIPricing{InputPerMTok: max(inputPerMTok, 0), OutputPerMTok: max(outputPerMTok, 0)}
+	}
+}
+
+// WithAIMonthlyBudget pauses generation once the estimated cost of the
+// current UTC month reaches limit. Zero means no limit.
+func WithAIMonthlyBudget(limit float64) AISummaryQueueOption {
+	return func(q *AISummaryQueue) { q.monthlyBudget = max(limit, 0) }
+}
 
-	jobs      chan AISummaryJob
-	inFlight  map[string]struct{}
-	inFlightM sync.Mutex
-	client    *http.Client
+// WithAILongDocument turns on map-reduce summarization: articles longer than
+// the input limit are split at their headings into at most maxChunks parts,
+// each part is summarized, and the partial summaries are summarized again.
+// maxChunks below 2 keeps the default of truncating long articles.
+func WithAILongDocument(maxChunks int) AISummaryQueueOption {
+	return func(q *AISummaryQueue) { q.maxChunks = maxChunks }
 }
 
-func NewAISummaryQueue(store SummaryStore, provider, baseURL, apiKey, model, prompt string, concurrency, rateLimitMs, timeoutMs, maxInputChars int) *AISummaryQueue {
+// WithAIEmbeddings lets the queue run "embedding" jobs, storing the vector
+// embedder computes for each post in store.
+func WithAIEmbeddings(embedder Embedder, store EmbeddingStore) AISummaryQueueOption {
+	return func(q *AISummaryQueue) {
+		if embedder != nil && store != nil {
+			q.embedder, q.embeddings = embedder, store
+		}
+	}
+}
+
+// WithSummaryHub publishes every status change the workers make to hub.
+func WithSummaryHub(hub *SummaryHub) AISummaryQueueOption {
+	return func(q *AISummaryQueue) { q.hub = hub }
+}
+
+func NewAISummaryQueue(store SummaryQueueStore, provider, baseURL, apiKey, model, prompt string, concurrency, rateLimitMs, timeoutMs, maxInputChars int, opts ...AISummaryQueueOption) *AISummaryQueue {
 	if concurrency <= 0 {
 		concurrency = 1
 	}
@@ -49,144 +124,451 @@ func NewAISummaryQueue(store SummaryStore, provider, baseURL, apiKey, model, pro
 	if maxInputChars <= 0 {
 		maxInputChars = 12000
 	}
+	if strings.TrimSpace(prompt) == "" {
+		prompt = fallbackSummaryPrompt
+	}
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+	client := &http.Client{Timeout: timeout}
+	aiProvider, providerErr := NewAIProvider(provider, baseURL, apiKey, model, client)
+	if providerErr != nil {
+		logger.Logger.Warn().Err(providerErr).Str("provider", provider).Msg("AI summary provider unavailable; jobs will fail until it is configured")
+	}
 	q := &AISummaryQueue{
 		store:         store,
-		provider:      strings.TrimSpace(provider),
-		baseURL:       strings.TrimRight(baseURL, "/"),
-		apiKey:        apiKey,
+		provider:      aiProvider,
+		providerErr:   providerErr,
 		model:         model,
 		prompt:        prompt,
 		concurrency:   concurrency,
 		rateLimit:     time.Duration(rateLimitMs) * time.Millisecond,
+		lease:         2*timeout + 30*time.Second,
+		retry:         defaultAIRetryPolicy(),
 		maxInputRunes: maxInputChars,
-		jobs:          make(chan AISummaryJob, 128),
-		inFlight:      map[string]struct{}{},
-		client:        &http.Client{Timeout: time.Duration(timeoutMs) * time.Millisecond},
+		wake:          make(chan struct{}, concurrency),
+		stop:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	if n, err := store.RecoverJobs(); err != nil {
+		logger.Error("Failed to recover AI summary jobs", err)
+	} else if n > 0 {
+		logger.Logger.Info().Int("jobs", n).Msg("Requeued AI summary jobs left running by the previous process")
 	}
+	q.updateDepth()
+
+	owner := fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
 	for i := 0; i < concurrency; i++ {
-		go q.worker()
+		q.wg.Add(1)
+		go q.worker(fmt.Sprintf("%s-%d", owner, i))
 	}
 	return q
 }
 
+// Enqueue persists job and wakes a worker. Jobs already queued or running for
+// the same content are left alone.
 func (q *AISummaryQueue) Enqueue(job AISummaryJob) {
-	q.inFlightM.Lock()
-	if _, exists := q.inFlight[job.NoteID]; exists {
-		q.inFlightM.Unlock()
+	added, err := q.store.EnqueueJob(job, time.Now())
+	if err != nil {
+		logger.Logger.Error().Err(err).Str("note_id", job.NoteID).Msg("Failed to queue AI summary generation")
+		return
+	}
+	if !added {
+		return
+	}
+	logger.Logger.Info().Str("note_id", job.NoteID).Msg("Queued AI summary generation")
+	q.updateDepth()
+	if q.overBudget() {
+		// The job stays queued and runs once the budget allows it again.
+		q.saveAll(jobKind(job.Type), StoredSummary{NoteID: job.NoteID, Status: "deferred", SourceHash: job.SourceHash}, nil)
 		return
 	}
-	q.inFlight[job.NoteID] = struct{}{}
-	q.inFlightM.Unlock()
-
 	select {
-	case q.jobs <- job:
-		logger.Logger.Info().Str("note_id", job.NoteID).Msg("Queued AI summary generation")
+	case q.wake <- struct{}{}:
 	default:
-		go func() {
-			q.jobs <- job
-			logger.Logger.Info().Str("note_id", job.NoteID).Msg("Queued AI summary generation after waiting for capacity")
-		}()
 	}
 }
 
-func (q *AISummaryQueue) release(noteID string) {
-	q.inFlightM.Lock()
-	delete(q.inFlight, noteID)
-	q.inFlightM.Unlock()
+// Close stops the workers after their current job. Unfinished jobs stay in
+// the store and are picked up again on the next start.
+func (q *AISummaryQueue) Close() {
+	q.once.Do(func() {
+		close(q.stop)
+	})
+	q.wg.Wait()
 }
 
-func (q *AISummaryQueue) worker() {
-	for job := range q.jobs {
-		logger.Logger.Info().Str("note_id", job.NoteID).Msg("Starting AI summary generation")
-		_ = q.store.UpsertSummary(StoredSummary{
-			NoteID:     job.NoteID,
-			Type:       "ai",
-			Status:     "processing",
-			SourceHash: job.SourceHash,
-			Content:    "",
-		})
-		content, err := q.generate(job.Title, job.Content)
+// Hub returns the hub status changes are published to, or nil.
+func (q *AISummaryQueue) Hub() *SummaryHub {
+	return q.hub
+}
+
+// EmbeddingModel returns the model of the configured Embedder, or "" when
+// embeddings are disabled.
+func (q *AISummaryQueue) EmbeddingModel() string {
+	if q.embedder == nil {
+		return ""
+	}
+	return q.embedder.Model()
+}
+
+// save stores item and announces it to subscribers of the note.
+func (q *AISummaryQueue) save(item StoredSummary) {
+	if item.UpdatedAt == "" {
+		item.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	if err := q.store.UpsertSummary(item); err != nil {
+		logger.Logger.Error().Err(err).Str("note_id", item.NoteID).Msg("Failed to store AI summary status")
+		return
+	}
+	q.publish(item)
+}
+
+func (q *AISummaryQueue) publish(item StoredSummary) {
+	if q.hub == nil {
+		return
+	}
+	q.hub.Publish(SummaryEvent{NoteID: item.NoteID, Entry: *summaryEntryFromStored(&item)})
+}
+
+func (q *AISummaryQueue) updateDepth() {
+	if n, err := q.store.CountJobs(); err == nil {
+		metrics.SetAIQueueDepth(n)
+	}
+}
+
+func (q *AISummaryQueue) worker(owner string) {
+	defer q.wg.Done()
+	ticker := time.NewTicker(aiJobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if q.overBudget() {
+			select {
+			case <-q.stop:
+				return
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		job, err := q.store.ClaimJob(owner, time.Now(), q.lease)
 		if err != nil {
-			_ = q.store.UpsertSummary(StoredSummary{
-				NoteID:     job.NoteID,
-				Type:       "ai",
-				Status:     "failed",
-				SourceHash: job.SourceHash,
-				Content:    "",
-				Error:      err.Error(),
-			})
-			logger.Logger.Error().Err(err).Str("note_id", job.NoteID).Msg("AI summary generation failed")
-			q.release(job.NoteID)
-			time.Sleep(q.rateLimit)
+			logger.Error("Failed to claim AI summary job", err)
+		}
+		if job == nil {
+			select {
+			case <-q.stop:
+				return
+			case <-q.wake:
+			case <-ticker.C:
+			}
 			continue
 		}
-		_ = q.store.UpsertSummary(StoredSummary{
+
+		q.updateDepth()
+		q.run(owner, job)
+
+		select {
+		case <-q.stop:
+			return
+		case <-time.After(q.rateLimit):
+		}
+	}
+}
+
+func (q *AISummaryQueue) run(owner string, job *ClaimedJob) {
+	kind := jobKind(job.Type)
+	prev, _ := q.store.GetSummary(job.NoteID, kind.types[0])
+	if q.allPinned(job.NoteID, kind) {
+		logger.Logger.Info().Str("note_id", job.NoteID).Str("type", job.Type).Msg("AI summary is pinned; skipping generation")
+		if err := q.store.CompleteJob(job.NoteID, job.Type, owner, job.SourceHash); err != nil {
+			logger.Error("Failed to complete AI summary job", err)
+		}
+		return
+	}
+
+	metrics.AIInFlightInc()
+	logger.Logger.Info().Str("note_id", job.NoteID).Str("type", job.Type).Int("attempt", job.Attempts).Msg("Starting AI summary generation")
+
+	// Carry the history of earlier attempts on the same content forward.
+	var history []SummaryAttempt
+	if job.Attempts > 1 && prev != nil && prev.SourceHash == job.SourceHash {
+		history = prev.History
+	}
+	q.saveAll(kind, StoredSummary{
+		NoteID:     job.NoteID,
+		Status:     "processing",
+		SourceHash: job.SourceHash,
+		Content:    "",
+		Attempts:   job.Attempts,
+		History:    history,
+	}, nil)
+	start := time.Now()
+	result, err := q.generate(context.Background(), job, func(done, total int) {
+		q.saveAll(kind, StoredSummary{
+			NoteID:      job.NoteID,
+			Status:      "processing",
+			SourceHash:  job.SourceHash,
+			Attempts:    job.Attempts,
+			History:     history,
+			ChunksDone:  done,
+			ChunksTotal: total,
+		}, nil)
+	})
+	var contents map[string]string
+	if err == nil {
+		contents, err = kind.parse(result.Text)
+	}
+	metrics.ObserveAIJob(err != nil, time.Since(start))
+	metrics.AIInFlightDec()
+	now := time.Now().UTC()
+
+	if err == nil {
+		q.saveAll(kind, StoredSummary{
 			NoteID:     job.NoteID,
-			Type:       "ai",
 			Status:     "ready",
 			SourceHash: job.SourceHash,
-			Content:    content,
 			Error:      "",
-		})
-		logger.Logger.Info().Str("note_id", job.NoteID).Msg("AI summary generation completed")
-		q.release(job.NoteID)
-		time.Sleep(q.rateLimit)
+			Attempts:   job.Attempts,
+			History: appendSummaryAttempt(history, SummaryAttempt{
+				Attempt: job.Attempts,
+				At:      now.Format(time.RFC3339),
+				Status:  "ready",
+			}),
+		}, contents)
+		logger.Logger.Info().
+			Str("note_id", job.NoteID).
+			Str("type", job.Type).
+			Str("provider", q.providerName(job)).
+			Int("attempt", job.Attempts).
+			Int("input_tokens", result.Usage.InputTokens).
+			Int("output_tokens", result.Usage.OutputTokens).
+			Msg("AI summary generation completed")
+		if err := q.store.CompleteJob(job.NoteID, job.Type, owner, job.SourceHash); err != nil {
+			logger.Error("Failed to complete AI summary job", err)
+		}
+		return
+	}
+
+	kindName, retryable := classifyAIError(err)
+	delay, retry := q.retry.nextDelay(job.Attempts, err)
+	history = appendSummaryAttempt(history, SummaryAttempt{
+		Attempt:   job.Attempts,
+		At:        now.Format(time.RFC3339),
+		Status:    "failed",
+		Kind:      kindName,
+		Error:     err.Error(),
+		Retryable: retryable,
+	})
+	item := StoredSummary{
+		NoteID:     job.NoteID,
+		Status:     "failed",
+		SourceHash: job.SourceHash,
+		Content:    "",
+		Error:      err.Error(),
+		Attempts:   job.Attempts,
+		History:    history,
+	}
+	logEvent := logger.Logger.Error().Err(err).Str("note_id", job.NoteID).Str("type", job.Type).Str("kind", kindName).Int("attempt", job.Attempts)
+
+	if retry {
+		nextRun := now.Add(delay)
+		item.Status = "pending"
+		item.NextRetryAt = nextRun.Format(time.RFC3339)
+		q.saveAll(kind, item, nil)
+		if err := q.store.RescheduleJob(job.NoteID, job.Type, owner, nextRun); err != nil {
+			logger.Error("Failed to reschedule AI summary job", err)
+		}
+		logEvent.Dur("retry_in", delay).Msg("AI summary generation failed; will retry")
+		return
+	}
+
+	q.saveAll(kind, item, nil)
+	if err := q.store.CompleteJob(job.NoteID, job.Type, owner, job.SourceHash); err != nil {
+		logger.Error("Failed to complete AI summary job", err)
+	}
+	logEvent.Msg("AI summary generation failed; giving up")
+}
+
+// saveAll saves item as each summary row of kind, with the content taken
+// from contents by type.
+func (q *AISummaryQueue) saveAll(kind aiJobKind, item StoredSummary, contents map[string]string) {
+	for _, summaryType := range kind.types {
+		item.Type = summaryType
+		if contents != nil {
+			item.Content = contents[summaryType]
+		}
+		q.save(item)
 	}
 }
 
-func (q *AISummaryQueue) generate(title, content string) (string, error) {
-	if q.provider != "" && q.provider != "openai-compatible" {
-		return "", fmt.Errorf("unsupported ai summary provider: %s", q.provider)
+// allPinned reports whether every row a job of kind would write is pinned.
+func (q *AISummaryQueue) allPinned(noteID string, kind aiJobKind) bool {
+	for _, summaryType := range kind.types {
+		item, err := q.store.GetSummary(noteID, summaryType)
+		if err != nil || item == nil || !item.Pinned {
+			return false
+		}
+	}
+	return true
+}
+
+// providerName and modelName report what a job was sent to.
+func (q *AISummaryQueue) providerName(job *ClaimedJob) string {
+	if jobKind(job.Type).embed && q.embedder != nil {
+		return q.embedder.Name()
 	}
-	if q.baseURL == "" || q.apiKey == "" || q.model == "" {
-		return "", fmt.Errorf("ai summary provider is not fully configured")
+	if q.provider == nil {
+		return ""
 	}
-	content = clampSummaryInput(content, q.maxInputRunes)
+	return q.provider.Name()
+}
 
-	requestBody := map[string]any{
-		"model": q.model,
-		"messages": []map[string]string{
-			{"role": "system", "content": q.prompt},
-			{"role": "user", "content": buildAISummaryInput(title, content)},
-		},
+func (q *AISummaryQueue) modelName(job *ClaimedJob) string {
+	if jobKind(job.Type).embed && q.embedder != nil {
+		return q.embedder.Model()
 	}
+	return q.model
+}
 
-	body, err := json.Marshal(requestBody)
+func (q *AISummaryQueue) recordUsage(job *ClaimedJob, usage AIUsage, elapsed time.Duration, success bool) {
+	cost := q.pricing.Cost(usage)
+	metrics.ObserveAIUsage(usage.InputTokens, usage.OutputTokens, cost)
+	err := q.store.RecordUsage(AIUsageRecord{
+		NoteID:       job.NoteID,
+		Type:         job.Type,
+		Provider:     q.providerName(job),
+		Model:        q.modelName(job),
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		LatencyMs:    elapsed.Milliseconds(),
+		Cost:         cost,
+		Success:      success,
+	})
 	if err != nil {
-		return "", err
+		logger.Error("Failed to record AI usage", err)
 	}
+}
 
-	req, err := http.NewRequest("POST", q.baseURL+"/chat/completions", bytes.NewReader(body))
+// overBudget reports whether this month's estimated spend has reached the
+// monthly budget, logging when generation pauses or resumes.
+func (q *AISummaryQueue) overBudget() bool {
+	if q.monthlyBudget <= 0 {
+		return false
+	}
+	spent, err := q.store.UsageCostSince(startOfMonth(time.Now()))
 	if err != nil {
-		return "", err
+		logger.Error("Failed to read AI usage", err)
+		return q.paused.Load()
+	}
+	over := spent >= q.monthlyBudget
+	if was := q.paused.Swap(over); was != over {
+		event := logger.Logger.Info()
+		msg := "AI summary budget available again; resuming generation"
+		if over {
+			event = logger.Logger.Warn()
+			msg = "AI summary monthly budget reached; pausing generation"
+		}
+		event.Float64("spent", spent).Float64("budget", q.monthlyBudget).Msg(msg)
 	}
-	req.Header.Set("Authorization", "Bearer "+q.apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	return over
+}
 
-	resp, err := q.client.Do(req)
+// UsageReport aggregates recorded calls by "day" or "month" and reports the
+// spend of the current month against the budget.
+func (q *AISummaryQueue) UsageReport(granularity string, limit int) (*AIUsageReport, error) {
+	items, err := q.store.UsageAggregates(granularity, limit)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	spent, err := q.store.UsageCostSince(startOfMonth(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []AIUsageAggregate{}
 	}
-	defer resp.Body.Close()
+	return &AIUsageReport{
+		Granularity:  granularity,
+		Items:        items,
+		MonthToDate:  spent,
+		MonthlyLimit: q.monthlyBudget,
+		Paused:       q.monthlyBudget > 0 && spent >= q.monthlyBudget,
+	}, nil
+}
+
+// generate summarizes job, splitting long articles into chunks when long
+// document mode is on. progress is only called for chunked generation and
+// translations in several parts.
+func (q *AISummaryQueue) generate(ctx context.Context, job *ClaimedJob, progress func(done, total int)) (AIResult, error) {
+	if jobKind(job.Type).embed {
+		return q.embed(ctx, job)
+	}
+	if q.providerErr != nil {
+		return AIResult{}, q.providerErr
+	}
+	if jobKind(job.Type).translate {
+		return q.translate(ctx, job, progress)
+	}
+	if q.maxChunks > 1 && jobKind(job.Type).chunked && len([]rune(job.Content)) > q.maxInputRunes {
+		if chunks := splitSummaryChunks(job.Content, q.maxInputRunes, q.maxChunks); len(chunks) > 1 {
+			logger.Logger.Info().Str("note_id", job.NoteID).Int("chunks", len(chunks)).Msg("Summarizing long article in parts")
+			progress(0, len(chunks)+1)
+			return q.generateChunked(ctx, job, chunks, progress)
+		}
+	}
+	return q.summarize(ctx, job, AIRequest{
+		Prompt:  q.jobPrompt(job),
+		Title:   job.Title,
+		Content: clampSummaryInput(job.Content, q.maxInputRunes),
+	})
+}
 
-	if resp.StatusCode >= 300 {
-		return "", fmt.Errorf("ai provider returned status %d", resp.StatusCode)
+func (q *AISummaryQueue) jobPrompt(job *ClaimedJob) string {
+	if job.Prompt != "" {
+		return job.Prompt
+	}
+	if prompt := jobKind(job.Type).prompt; prompt != "" {
+		return prompt
 	}
+	return q.prompt
+}
 
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+// summarize makes one provider call and records its usage.
+func (q *AISummaryQueue) summarize(ctx context.Context, job *ClaimedJob, req AIRequest) (AIResult, error) {
+	start := time.Now()
+	result, err := q.provider.Summarize(ctx, req)
+	q.recordUsage(job, result.Usage, time.Since(start), err == nil)
+	return result, err
+}
+
+// embed computes and stores the embedding of a job's article. The result
+// text describes the vector for the summary row that tracks the job.
+func (q *AISummaryQueue) embed(ctx context.Context, job *ClaimedJob) (AIResult, error) {
+	if q.embedder == nil {
+		return AIResult{}, &AIConfigError{Message: "ai embeddings are not configured"}
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	start := time.Now()
+	vec, usage, err := q.embedder.Embed(ctx, buildAISummaryInput(job.Title, clampSummaryInput(job.Content, q.maxInputRunes)))
+	q.recordUsage(job, usage, time.Since(start), err == nil)
+	if err != nil {
+		return AIResult{Usage: usage}, err
 	}
-	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("ai provider returned no choices")
+	err = q.embeddings.SaveEmbedding(StoredEmbedding{
+		NoteID:     job.NoteID,
+		Model:      q.embedder.Model(),
+		SourceHash: job.SourceHash,
+		Vector:     vec,
+	})
+	if err != nil {
+		return AIResult{Usage: usage}, fmt.Errorf("storing embedding: %w", err)
 	}
-	return strings.TrimSpace(result.Choices[0].Message.Content), nil
+	return AIResult{
+		Text:  fmt.Sprintf("%s, %d dimensions", q.embedder.Model(), len(vec)),
+		Usage: usage,
+	}, nil
 }
 
 func buildAISummaryInput(title, content string) string {
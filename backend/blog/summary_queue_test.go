@@ -18,6 +18,7 @@ func TestAISummaryQueue_EnqueueDeduplicatesAndFailsGracefully(t *testing.T) {
 	defer store.Close()
 
 	queue := NewAISummaryQueue(store, "openai-compatible", "", "", "", "prompt", 1, 10, 100, 2000)
+	defer queue.Close()
 	queue.Enqueue(AISummaryJob{NoteID: "note-1", Title: "Title", Content: "hello", SourceHash: "hash"})
 	queue.Enqueue(AISummaryJob{NoteID: "note-1", Title: "Title", Content: "hello", SourceHash: "hash"})
 
@@ -64,6 +65,7 @@ func TestAISummaryQueue_GenerateIncludesTitleInPrompt(t *testing.T) {
 	defer server.Close()
 
 	queue := NewAISummaryQueue(store, "openai-compatible", server.URL, "token", "model", "prompt", 1, 1, 1000, 2000)
+	defer queue.Close()
 	queue.Enqueue(AISummaryJob{
 		NoteID:     "note-1",
 		Title:      "My Article",
@@ -83,3 +85,14 @@ func TestAISummaryQueue_GenerateIncludesTitleInPrompt(t *testing.T) {
 		t.Fatalf("timed out waiting for AI summary request")
 	}
 }
+
+func TestAISummaryQueue_DefaultsToBuiltInPrompt(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", "http://127.0.0.1:1", "token", "model", "", 1, 1, 1000, 2000)
+	defer queue.Close()
+
+	if got := queue.jobPrompt(&ClaimedJob{AISummaryJob: AISummaryJob{NoteID: "n1"}}); got != fallbackSummaryPrompt {
+		t.Fatalf("expected the built-in prompt without a configured one, got %q", got)
+	}
+}
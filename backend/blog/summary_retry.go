@@ -0,0 +1,117 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxSummaryHistory bounds the attempt history kept per summary.
+const maxSummaryHistory = 10
+
+// AIRetryPolicy decides whether and when a failed AI summary is tried again.
+type AIRetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func defaultAIRetryPolicy() AIRetryPolicy {
+	return AIRetryPolicy{MaxAttempts: 4, BaseDelay: 30 * time.Second, MaxDelay: 30 * time.Minute}
+}
+
+// SummaryAttempt is one entry of a summary's generation history.
+type SummaryAttempt struct {
+	Attempt   int    `json:"attempt"`
+	At        string `json:"at"`
+	Status    string `json:"status"`
+	Kind      string `json:"kind,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+}
+
+// Error kinds recorded in SummaryAttempt.Kind.
+const (
+	aiErrorRateLimited     = "rate_limited"
+	aiErrorServer          = "server_error"
+	aiErrorTimeout         = "timeout"
+	aiErrorNetwork         = "network"
+	aiErrorAuth            = "auth"
+	aiErrorClient          = "client_error"
+	aiErrorConfig          = "config"
+	aiErrorInvalidResponse = "invalid_response"
+)
+
+// classifyAIError sorts a generation error into a kind and whether it is
+// worth retrying. Rate limits, 5xx, timeouts and network failures are
+// transient; auth failures, other 4xx and configuration errors are not.
+// Malformed or empty responses are treated as transient provider hiccups.
+func classifyAIError(err error) (kind string, retryable bool) {
+	var configErr *AIConfigError
+	if errors.As(err, &configErr) {
+		return aiErrorConfig, false
+	}
+
+	var statusErr *AIStatusError
+	if errors.As(err, &statusErr) {
+		switch code := statusErr.StatusCode; {
+		case code == http.StatusTooManyRequests:
+			return aiErrorRateLimited, true
+		case code == http.StatusRequestTimeout:
+			return aiErrorTimeout, true
+		case code == http.StatusUnauthorized || code == http.StatusForbidden:
+			return aiErrorAuth, false
+		case code >= 500:
+			return aiErrorServer, true
+		default:
+			return aiErrorClient, false
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return aiErrorTimeout, true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return aiErrorTimeout, true
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return aiErrorNetwork, true
+	}
+	return aiErrorInvalidResponse, true
+}
+
+// nextDelay returns how long to wait before attempt+1, or false when the
+// job should be given up. A Retry-After from the provider is honoured when it
+// is longer than the computed backoff, up to MaxDelay.
+func (p AIRetryPolicy) nextDelay(attempt int, err error) (time.Duration, bool) {
+	if _, retryable := classifyAIError(err); !retryable || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	window := p.BaseDelay << (attempt - 1)
+	if window <= 0 || window > p.MaxDelay {
+		window = p.MaxDelay
+	}
+	// Equal jitter: at least half the window so retries stay spread out.
+	delay := window/2 + time.Duration(rand.Int63n(int64(window/2)+1))
+
+	var statusErr *AIStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+		delay = min(statusErr.RetryAfter, p.MaxDelay)
+	}
+	return delay, true
+}
+
+func appendSummaryAttempt(history []SummaryAttempt, attempt SummaryAttempt) []SummaryAttempt {
+	history = append(history, attempt)
+	if len(history) > maxSummaryHistory {
+		history = history[len(history)-maxSummaryHistory:]
+	}
+	return history
+}
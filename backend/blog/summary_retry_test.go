@@ -0,0 +1,173 @@
+package blog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClassifyAIError(t *testing.T) {
+	tests := []struct {
+		err       error
+		kind      string
+		retryable bool
+	}{
+		{&AIStatusError{StatusCode: 429}, aiErrorRateLimited, true},
+		{&AIStatusError{StatusCode: 503}, aiErrorServer, true},
+		{&AIStatusError{StatusCode: 401}, aiErrorAuth, false},
+		{&AIStatusError{StatusCode: 400}, aiErrorClient, false},
+		{&AIConfigError{Message: "no model"}, aiErrorConfig, false},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), aiErrorTimeout, true},
+		{errors.New("ai provider returned no choices"), aiErrorInvalidResponse, true},
+	}
+	for _, tt := range tests {
+		kind, retryable := classifyAIError(tt.err)
+		if kind != tt.kind || retryable != tt.retryable {
+			t.Fatalf("classifyAIError(%v) = %s/%v, want %s/%v", tt.err, kind, retryable, tt.kind, tt.retryable)
+		}
+	}
+}
+
+func TestAIRetryPolicyNextDelay(t *testing.T) {
+	p := AIRetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	transient := &AIStatusError{StatusCode: 503}
+
+	if d, ok := p.nextDelay(1, transient); !ok || d < 500*time.Millisecond || d > time.Second {
+		t.Fatalf("expected first retry within [0.5s, 1s], got %s (%v)", d, ok)
+	}
+	if d, ok := p.nextDelay(2, transient); !ok || d < time.Second || d > 2*time.Second {
+		t.Fatalf("expected second retry within [1s, 2s], got %s (%v)", d, ok)
+	}
+	if _, ok := p.nextDelay(3, transient); ok {
+		t.Fatalf("expected no retry once attempts are exhausted")
+	}
+	if _, ok := p.nextDelay(1, &AIStatusError{StatusCode: 403}); ok {
+		t.Fatalf("expected auth errors not to be retried")
+	}
+	if d, _ := p.nextDelay(1, &AIStatusError{StatusCode: 429, RetryAfter: 5 * time.Second}); d != 5*time.Second {
+		t.Fatalf("expected Retry-After to win over a shorter backoff, got %s", d)
+	}
+	if d, _ := p.nextDelay(1, &AIStatusError{StatusCode: 429, RetryAfter: time.Hour}); d != 10*time.Second {
+		t.Fatalf("expected Retry-After to be capped at MaxDelay, got %s", d)
+	}
+}
+
+func waitForSummaryStatus(t *testing.T, store *SummaryStoreDB, noteID, status string) *StoredSummary {
+	t.Helper()
+	return waitForSummaryType(t, store, noteID, "ai", status)
+}
+
+func waitForSummaryType(t *testing.T, store *SummaryStoreDB, noteID, summaryType, status string) *StoredSummary {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		item, err := store.GetSummary(noteID, summaryType)
+		if err != nil {
+			t.Fatalf("get summary failed: %v", err)
+		}
+		if item != nil && item.Status == status {
+			return item
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for status %q, last %#v", status, item)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAISummaryQueueRetriesTransientFailures(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"third time lucky"}}]}`))
+	}))
+	defer server.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", server.URL, "token", "model", "prompt", 1, 1, 1000, 2000,
+		WithAIRetryPolicy(3, time.Millisecond, 5*time.Millisecond))
+	defer queue.Close()
+
+	queue.Enqueue(AISummaryJob{NoteID: "n1", Title: "T", Content: "body", SourceHash: "h"})
+
+	item := waitForSummaryStatus(t, store, "n1", "ready")
+	if item.Content != "third time lucky" || item.Attempts != 3 {
+		t.Fatalf("unexpected summary %#v", item)
+	}
+	if len(item.History) != 3 || item.History[0].Kind != aiErrorServer || item.History[2].Status != "ready" {
+		t.Fatalf("unexpected history %#v", item.History)
+	}
+	if item.NextRetryAt != "" {
+		t.Fatalf("expected next retry to be cleared, got %q", item.NextRetryAt)
+	}
+}
+
+func TestAISummaryQueueGivesUpOnAuthErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", server.URL, "token", "model", "prompt", 1, 1, 1000, 2000,
+		WithAIRetryPolicy(5, time.Millisecond, 5*time.Millisecond))
+	defer queue.Close()
+
+	queue.Enqueue(AISummaryJob{NoteID: "n1", Title: "T", Content: "body", SourceHash: "h"})
+
+	item := waitForSummaryStatus(t, store, "n1", "failed")
+	time.Sleep(30 * time.Millisecond)
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected a single attempt for 401, got %d", got)
+	}
+	if len(item.History) != 1 || item.History[0].Kind != aiErrorAuth || item.History[0].Retryable {
+		t.Fatalf("unexpected history %#v", item.History)
+	}
+	if n, _ := store.CountJobs(); n != 0 {
+		t.Fatalf("expected job to be removed after giving up, got %d queued", n)
+	}
+}
+
+func TestAISummaryQueueSchedulesRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", server.URL, "token", "model", "prompt", 1, 1, 1000, 2000,
+		WithAIRetryPolicy(3, time.Hour, 2*time.Hour))
+	defer queue.Close()
+
+	queue.Enqueue(AISummaryJob{NoteID: "n1", Title: "T", Content: "body", SourceHash: "h"})
+
+	item := waitForSummaryStatus(t, store, "n1", "pending")
+	for item.Attempts == 0 {
+		item = waitForSummaryStatus(t, store, "n1", "pending")
+	}
+	next, err := time.Parse(time.RFC3339, item.NextRetryAt)
+	if err != nil {
+		t.Fatalf("expected next retry time, got %q", item.NextRetryAt)
+	}
+	if until := time.Until(next); until < 29*time.Minute {
+		t.Fatalf("expected retry to be scheduled with backoff, got %s", until)
+	}
+	if item.Error == "" || len(item.History) != 1 || item.History[0].Kind != aiErrorRateLimited {
+		t.Fatalf("unexpected retry state %#v", item)
+	}
+}
@@ -0,0 +1,203 @@
+package blog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+// seoSummaryTypes are the summary rows written by an "seo" job.
+var seoSummaryTypes = []string{"tags", "keywords", "description"}
+
+const (
+	seoDescriptionMaxRunes = 160
+	seoMaxTags             = 8
+	seoMaxKeywords         = 12
+)
+
+// defaultSEOPrompt asks for the JSON that parseSEOResult reads.
+const defaultSEOPrompt = `You help publish a blog article. Read it and answer with a single JSON object and nothing else:
+{"tags": [...], "keywords": [...], "description": "..."}
+- tags: 3 to 6 short topic tags for the article
+- keywords: 5 to 10 search keywords or key phrases
+- description: a meta description for search engines, at most 160 characters, one sentence, no quotes or line breaks`
+
+// seoPrompt returns the SEO prompt in the language of the note.
+func (s *Service) seoPrompt(noteID string, attrs []etapi.Attribute) string {
+	locale := noteSummaryLocale(noteID, normalizeSummaryLocale(s.locale), attrs)
+	return fmt.Sprintf("%s\nWrite the tags, keywords and description in %s.", defaultSEOPrompt, summaryLanguageNames[locale])
+}
+
+// parseSEOResult reads the JSON answer to an SEO prompt. Tags and keywords
+// are stored comma-separated; the description is cut to 160 characters.
+func parseSEOResult(text string) (map[string]string, error) {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("SEO answer is not a JSON object: %q", clampSummaryInput(text, 80))
+	}
+	var result struct {
+		Tags        []string `json:"tags"`
+		Keywords    []string `json:"keywords"`
+		Description string   `json:"description"`
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("parsing SEO answer: %w", err)
+	}
+	description := strings.Join(strings.Fields(result.Description), " ")
+	contents := map[string]string{
+		"tags":        joinSEOList(result.Tags, seoMaxTags),
+		"keywords":    joinSEOList(result.Keywords, seoMaxKeywords),
+		"description": clampSummaryInput(description, seoDescriptionMaxRunes),
+	}
+	if contents["tags"] == "" && contents["keywords"] == "" && contents["description"] == "" {
+		return nil, fmt.Errorf("SEO answer is empty")
+	}
+	return contents, nil
+}
+
+// joinSEOList cleans and de-duplicates items and joins at most limit of them
+// with ", ".
+func joinSEOList(items []string, limit int) string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, item := range items {
+		item = strings.TrimSpace(strings.TrimLeft(strings.ReplaceAll(item, ",", " "), "#"))
+		item = strings.Join(strings.Fields(item), " ")
+		key := strings.ToLower(item)
+		if item == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, item)
+		if len(out) == limit {
+			break
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+func splitSEOList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// hasSEOLabels reports whether the author set tags, keywords and a
+// description, in which case there is nothing for the AI to suggest.
+func hasSEOLabels(attrs []etapi.Attribute) bool {
+	return len(noteTags(attrs)) > 0 && labelValue(attrs, "keywords") != "" && labelValue(attrs, "description") != ""
+}
+
+// ensureSEO returns the stored SEO rows of a note by type, queueing an "seo"
+// job when they are missing or were generated from other content.
+func (s *Service) ensureSEO(noteID, title string, attrs []etapi.Attribute, content, hash string) (map[string]*StoredSummary, error) {
+	prompt := s.seoPrompt(noteID, attrs)
+	seoHash := summaryPromptHash(hash, prompt)
+	rows := make(map[string]*StoredSummary)
+	stale := false
+	for _, summaryType := range seoSummaryTypes {
+		item, err := s.summaryStore.GetSummary(noteID, summaryType)
+		if err != nil {
+			return nil, err
+		}
+		rows[summaryType] = item
+		if item == nil || (!item.Pinned && (item.SourceHash != seoHash || item.Status == "")) {
+			stale = true
+		}
+	}
+	if !stale || hasSEOLabels(attrs) {
+		return rows, nil
+	}
+
+	for _, summaryType := range seoSummaryTypes {
+		if rows[summaryType] != nil && rows[summaryType].Pinned {
+			continue
+		}
+		_ = s.summaryStore.UpsertSummary(StoredSummary{
+			NoteID:     noteID,
+			Type:       summaryType,
+			Status:     "pending",
+			SourceHash: seoHash,
+		})
+	}
+	s.aiQueue.Enqueue(AISummaryJob{
+		NoteID:     noteID,
+		Type:       "seo",
+		Title:      title,
+		Content:    extractSummaryText(sanitizeContentForSummary(content)),
+		Prompt:     prompt,
+		SourceHash: seoHash,
+	})
+	for _, summaryType := range seoSummaryTypes {
+		rows[summaryType], _ = s.summaryStore.GetSummary(noteID, summaryType)
+	}
+	return rows, nil
+}
+
+// applyPostSEO fills the tags, keywords and description of a post from the
+// author's #tag, #keywords and #description labels, falling back to the AI
+// suggestions that are ready.
+func applyPostSEO(post *Post, attrs []etapi.Attribute, summaries *Summaries) {
+	ready := func(entry *SummaryEntry) string {
+		if entry == nil || entry.Status != "ready" {
+			return ""
+		}
+		return strings.TrimSpace(entry.Text)
+	}
+	var tags, keywords, description *SummaryEntry
+	if summaries != nil {
+		tags, keywords, description = summaries.Tags, summaries.Keywords, summaries.Description
+	}
+
+	post.Tags = noteTags(attrs)
+	if len(post.Tags) == 0 {
+		post.Tags = splitSEOList(ready(tags))
+	}
+	post.Keywords = splitSEOList(labelValue(attrs, "keywords"))
+	if len(post.Keywords) == 0 {
+		post.Keywords = splitSEOList(ready(keywords))
+	}
+	post.Description = labelValue(attrs, "description")
+	if post.Description == "" {
+		post.Description = ready(description)
+	}
+}
+
+// GetPostSEOContext returns a post with only its title, tags, keywords and
+// description set, for rendering the page head. It reads the AI suggestions
+// already stored; unlike GetPostContext it neither renders the post nor
+// queues jobs.
+func (s *Service) GetPostSEOContext(ctx context.Context, noteID string) (*Post, error) {
+	note, err := s.getCachedNote(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if !isPost(*note) {
+		return nil, ErrNotBlogPost
+	}
+
+	var summaries *Summaries
+	if s.summaryStore != nil && s.aiEnabled && s.aiSEO && !hasSEOLabels(note.Attributes) {
+		summaries = &Summaries{}
+		for summaryType, entry := range map[string]**SummaryEntry{
+			"tags":        &summaries.Tags,
+			"keywords":    &summaries.Keywords,
+			"description": &summaries.Description,
+		} {
+			if item, err := s.summaryStore.GetSummary(noteID, summaryType); err == nil && item != nil {
+				*entry = summaryEntryFromStored(item)
+			}
+		}
+	}
+
+	post := &Post{NoteID: note.NoteID, Title: note.Title}
+	applyPostSEO(post, note.Attributes, summaries)
+	return post, nil
+}
@@ -0,0 +1,124 @@
+package blog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestParseSEOResult(t *testing.T) {
+	answer := "```json\n" + `{"tags": ["Go", "#go", "concurrency"], "keywords": ["go channels", "goroutines, sync"], "description": "` +
+		strings.Repeat("word ", 40) + `"}` + "\n```"
+
+	got, err := parseSEOResult(answer)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if got["tags"] != "Go, concurrency" {
+		t.Fatalf("unexpected tags %q", got["tags"])
+	}
+	if got["keywords"] != "go channels, goroutines sync" {
+		t.Fatalf("unexpected keywords %q", got["keywords"])
+	}
+	if n := len([]rune(got["description"])); n == 0 || n > seoDescriptionMaxRunes {
+		t.Fatalf("expected a description of at most %d characters, got %d", seoDescriptionMaxRunes, n)
+	}
+
+	for _, bad := range []string{"no json here", `{"tags": "oops"}`, `{}`} {
+		if _, err := parseSEOResult(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestApplyPostSEOPrefersLabels(t *testing.T) {
+	summaries := &Summaries{
+		Tags:        &SummaryEntry{Type: "tags", Status: "ready", Text: "ai-tag"},
+		Keywords:    &SummaryEntry{Type: "keywords", Status: "ready", Text: "one, two"},
+		Description: &SummaryEntry{Type: "description", Status: "pending"},
+	}
+	var post Post
+	applyPostSEO(&post, []etapi.Attribute{
+		etapitest.Label("tag", "mine"),
+		etapitest.Label("description", "Written by hand."),
+	}, summaries)
+
+	if strings.Join(post.Tags, ",") != "mine" || post.Description != "Written by hand." {
+		t.Fatalf("expected labels to win, got %#v", post)
+	}
+	if strings.Join(post.Keywords, ",") != "one,two" {
+		t.Fatalf("expected AI keywords without a label, got %#v", post.Keywords)
+	}
+}
+
+func TestGetPostFillsSEOFromAISuggestions(t *testing.T) {
+	noteID := "note-seo"
+	blogServer := newBlogTestServer(t, noteID, "<h2>Channels</h2><p>How Go channels work.</p>")
+	defer blogServer.Close()
+
+	aiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"tags\":[\"go\"],\"keywords\":[\"channels\"],\"description\":\"How channels work.\"}"}}]}`))
+	}))
+	defer aiServer.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", aiServer.URL, "token", "model", "prompt", 1, 1, 30000, 2000)
+	defer queue.Close()
+	service := NewService(etapi.NewClient(blogServer.URL, "token"), &NoopStore{},
+		WithSummaryStore(store), WithAISummaryQueue(queue), WithAISummaryEnabled(true), WithAISEOEnabled(true))
+
+	post, err := service.GetPost(noteID)
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if post.Summaries.Description == nil || post.Summaries.Description.Status != "pending" {
+		t.Fatalf("expected a pending SEO description, got %#v", post.Summaries.Description)
+	}
+
+	for _, summaryType := range seoSummaryTypes {
+		item := waitForSummaryType(t, store, noteID, summaryType, "ready")
+		if item.Content == "" {
+			t.Fatalf("expected %s content, got %#v", summaryType, item)
+		}
+	}
+	post, err = service.GetPost(noteID)
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if post.Description != "How channels work." || strings.Join(post.Tags, ",") != "go" || strings.Join(post.Keywords, ",") != "channels" {
+		t.Fatalf("unexpected SEO fields %#v %#v %q", post.Tags, post.Keywords, post.Description)
+	}
+}
+
+func TestGetPostSEOContextReadsStoredSuggestions(t *testing.T) {
+	noteID := "note-seo-meta"
+	blogServer := newBlogTestServer(t, noteID, "<p>How Go channels work.</p>")
+	defer blogServer.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	_ = store.UpsertSummary(StoredSummary{NoteID: noteID, Type: "description", Status: "ready", Content: "How channels work."})
+	_ = store.UpsertSummary(StoredSummary{NoteID: noteID, Type: "keywords", Status: "pending"})
+	queue := NewAISummaryQueue(store, "openai-compatible", "http://127.0.0.1:1", "token", "model", "prompt", 1, 1, 1000, 2000)
+	defer queue.Close()
+	service := NewService(etapi.NewClient(blogServer.URL, "token"), &NoopStore{},
+		WithSummaryStore(store), WithAISummaryQueue(queue), WithAISummaryEnabled(true), WithAISEOEnabled(true))
+
+	post, err := service.GetPostSEOContext(t.Context(), noteID)
+	if err != nil {
+		t.Fatalf("get post seo failed: %v", err)
+	}
+	if post.Description != "How channels work." || len(post.Keywords) != 0 {
+		t.Fatalf("expected only the ready description, got %#v", post)
+	}
+	if n, _ := store.CountJobs(); n != 0 {
+		t.Fatalf("expected no jobs to be queued, got %d", n)
+	}
+}
@@ -2,6 +2,7 @@ package blog
 
 import (
 	"database/sql"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"time"
@@ -14,13 +15,22 @@ type SummaryStoreDB struct {
 }
 
 type StoredSummary struct {
-	NoteID     string
-	Type       string
-	Status     string
-	Content    string
-	SourceHash string
-	UpdatedAt  string
-	Error      string
+	NoteID      string           `json:"noteId"`
+	Type        string           `json:"type"`
+	Status      string           `json:"status"`
+	Content     string           `json:"content"`
+	SourceHash  string           `json:"sourceHash"`
+	UpdatedAt   string           `json:"updatedAt"`
+	Error       string           `json:"error,omitempty"`
+	Attempts    int              `json:"attempts"`
+	NextRetryAt string           `json:"nextRetryAt,omitempty"`
+	History     []SummaryAttempt `json:"history,omitempty"`
+	// Pinned summaries were written by an admin; generation leaves them alone.
+	Pinned bool `json:"pinned"`
+	// ChunksDone and ChunksTotal report progress of a long article summarized
+	// in parts; both are zero otherwise.
+	ChunksDone  int `json:"chunksDone,omitempty"`
+	ChunksTotal int `json:"chunksTotal,omitempty"`
 }
 
 func NewSummaryStoreDB(path string) (*SummaryStoreDB, error) {
@@ -65,6 +75,48 @@ func (s *SummaryStoreDB) init() error {
 			PRIMARY KEY (note_id, type)
 		)
 	`)
+	if err != nil {
+		return err
+	}
+	for _, col := range []struct{ name, ddl string }{
+		{"attempts", "attempts INTEGER NOT NULL DEFAULT 0"},
+		{"next_retry_at", "next_retry_at TEXT NOT NULL DEFAULT ''"},
+		{"history", "history TEXT NOT NULL DEFAULT ''"},
+		{"pinned", "pinned INTEGER NOT NULL DEFAULT 0"},
+		{"chunks_done", "chunks_done INTEGER NOT NULL DEFAULT 0"},
+		{"chunks_total", "chunks_total INTEGER NOT NULL DEFAULT 0"},
+	} {
+		if err := s.ensureColumn("summaries", col.name, col.ddl); err != nil {
+			return err
+		}
+	}
+	if err := s.initJobs(); err != nil {
+		return err
+	}
+	return s.initUsage()
+}
+
+// ensureColumn adds a column to a table created by an older version.
+func (s *SummaryStoreDB) ensureColumn(table, name, ddl string) error {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return err
+		}
+		if col == name {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	_, err = s.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + ddl)
 	return err
 }
 
@@ -77,34 +129,68 @@ func (s *SummaryStoreDB) Close() error {
 
 func (s *SummaryStoreDB) GetSummary(noteID, summaryType string) (*StoredSummary, error) {
 	row := s.db.QueryRow(`
-		SELECT note_id, type, status, content, source_hash, updated_at, error
+		SELECT note_id, type, status, content, source_hash, updated_at, error, attempts, next_retry_at, history, pinned, chunks_done, chunks_total
 		FROM summaries
 		WHERE note_id = ? AND type = ?
 	`, noteID, summaryType)
 
+	result, err := scanSummary(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scanSummary reads the columns selected by GetSummary and ListSummaries.
+func scanSummary(row interface{ Scan(...any) error }) (*StoredSummary, error) {
 	var result StoredSummary
-	if err := row.Scan(&result.NoteID, &result.Type, &result.Status, &result.Content, &result.SourceHash, &result.UpdatedAt, &result.Error); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
+	var history string
+	if err := row.Scan(&result.NoteID, &result.Type, &result.Status, &result.Content, &result.SourceHash, &result.UpdatedAt, &result.Error,
+		&result.Attempts, &result.NextRetryAt, &history, &result.Pinned, &result.ChunksDone, &result.ChunksTotal); err != nil {
 		return nil, err
 	}
+	if history != "" {
+		// A corrupt history is not worth failing the summary over.
+		_ = json.Unmarshal([]byte(history), &result.History)
+	}
 	return &result, nil
 }
 
+// UpsertSummary writes item. A pinned row is only replaced by another pinned
+// write, so a generation finishing after an admin pinned the summary is
+// dropped.
 func (s *SummaryStoreDB) UpsertSummary(item StoredSummary) error {
 	if item.UpdatedAt == "" {
 		item.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
 	}
+	history := ""
+	if len(item.History) > 0 {
+		encoded, err := json.Marshal(item.History)
+		if err != nil {
+			return err
+		}
+		history = string(encoded)
+	}
 	_, err := s.db.Exec(`
-		INSERT INTO summaries (note_id, type, status, content, source_hash, updated_at, error)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO summaries (note_id, type, status, content, source_hash, updated_at, error, attempts, next_retry_at, history, pinned, chunks_done, chunks_total)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(note_id, type) DO UPDATE SET
 			status = excluded.status,
 			content = excluded.content,
 			source_hash = excluded.source_hash,
 			updated_at = excluded.updated_at,
-			error = excluded.error
-	`, item.NoteID, item.Type, item.Status, item.Content, item.SourceHash, item.UpdatedAt, item.Error)
+			error = excluded.error,
+			attempts = excluded.attempts,
+			next_retry_at = excluded.next_retry_at,
+			history = excluded.history,
+			pinned = excluded.pinned,
+			chunks_done = excluded.chunks_done,
+			chunks_total = excluded.chunks_total
+		WHERE summaries.pinned = 0 OR excluded.pinned = 1
+	`, item.NoteID, item.Type, item.Status, item.Content, item.SourceHash, item.UpdatedAt, item.Error,
+		item.Attempts, item.NextRetryAt, history, item.Pinned, item.ChunksDone, item.ChunksTotal)
 	return err
 }
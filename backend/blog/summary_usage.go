@@ -0,0 +1,151 @@
+package blog
+
+import (
+	"fmt"
+	"time"
+)
+
+// AIPricing is what the provider charges, in currency units per million
+// tokens. Zero prices make every call cost nothing, which also disables the
+// budget cap.
+type AIPricing struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+func (p AIPricing) Cost(usage AIUsage) float64 {
+	return (float64(usage.InputTokens)*p.InputPerMTok + float64(usage.OutputTokens)*p.OutputPerMTok) / 1e6
+}
+
+// AIUsageRecord is one call to the AI provider.
+type AIUsageRecord struct {
+	NoteID       string  `json:"noteId"`
+	Type         string  `json:"type"`
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	LatencyMs    int64   `json:"latencyMs"`
+	Cost         float64 `json:"cost"`
+	Success      bool    `json:"success"`
+	CreatedAt    string  `json:"createdAt"`
+}
+
+// AIUsageAggregate sums the calls of one day ("2006-01-02") or month
+// ("2006-01").
+type AIUsageAggregate struct {
+	Period       string  `json:"period"`
+	Calls        int     `json:"calls"`
+	Failures     int     `json:"failures"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	Cost         float64 `json:"cost"`
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+}
+
+// AIUsageReport is what the admin API shows about spending.
+type AIUsageReport struct {
+	Granularity  string             `json:"granularity"`
+	Items        []AIUsageAggregate `json:"items"`
+	MonthToDate  float64            `json:"monthToDate"`
+	MonthlyLimit float64            `json:"monthlyBudget"`
+	Paused       bool               `json:"paused"`
+}
+
+// SummaryUsageStore keeps a row per AI call for cost accounting.
+type SummaryUsageStore interface {
+	RecordUsage(record AIUsageRecord) error
+	UsageCostSince(since time.Time) (float64, error)
+	UsageAggregates(granularity string, limit int) ([]AIUsageAggregate, error)
+}
+
+func (s *SummaryStoreDB) initUsage() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS summary_usage (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			note_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			provider TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			input_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			latency_ms INTEGER NOT NULL DEFAULT 0,
+			cost REAL NOT NULL DEFAULT 0,
+			success INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS summary_usage_created ON summary_usage (created_at)`)
+	return err
+}
+
+func (s *SummaryStoreDB) RecordUsage(record AIUsageRecord) error {
+	if record.CreatedAt == "" {
+		record.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO summary_usage (note_id, type, provider, model, input_tokens, output_tokens, latency_ms, cost, success, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, record.NoteID, record.Type, record.Provider, record.Model, record.InputTokens, record.OutputTokens,
+		record.LatencyMs, record.Cost, record.Success, record.CreatedAt)
+	return err
+}
+
+// UsageCostSince sums the estimated cost of calls made at or after since.
+func (s *SummaryStoreDB) UsageCostSince(since time.Time) (float64, error) {
+	var cost float64
+	err := s.db.QueryRow(`SELECT COALESCE(SUM(cost), 0) FROM summary_usage WHERE created_at >= ?`,
+		since.UTC().Format(time.RFC3339)).Scan(&cost)
+	return cost, err
+}
+
+// UsageAggregates returns the most recent limit days or months (UTC), newest
+// first. granularity is "day" or "month".
+func (s *SummaryStoreDB) UsageAggregates(granularity string, limit int) ([]AIUsageAggregate, error) {
+	width := 10
+	switch granularity {
+	case "day":
+	case "month":
+		width = 7
+	default:
+		return nil, fmt.Errorf("unknown usage granularity %q", granularity)
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.Query(`
+		SELECT substr(created_at, 1, ?) AS period,
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN success = 0 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(input_tokens), 0),
+			COALESCE(SUM(output_tokens), 0),
+			COALESCE(SUM(cost), 0),
+			COALESCE(AVG(latency_ms), 0)
+		FROM summary_usage
+		GROUP BY period
+		ORDER BY period DESC
+		LIMIT ?
+	`, width, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []AIUsageAggregate
+	for rows.Next() {
+		var item AIUsageAggregate
+		if err := rows.Scan(&item.Period, &item.Calls, &item.Failures, &item.InputTokens, &item.OutputTokens, &item.Cost, &item.AvgLatencyMs); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
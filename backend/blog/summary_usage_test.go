@@ -0,0 +1,135 @@
+package blog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSummaryStoreDB_UsageAggregates(t *testing.T) {
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+
+	for _, r := range []AIUsageRecord{
+		{NoteID: "a", Type: "ai", InputTokens: 100, OutputTokens: 10, LatencyMs: 100, Cost: 0.5, Success: true, CreatedAt: "2026-03-01T10:00:00Z"},
+		{NoteID: "b", Type: "ai", InputTokens: 200, OutputTokens: 20, LatencyMs: 300, Cost: 1, Success: true, CreatedAt: "2026-03-01T11:00:00Z"},
+		{NoteID: "c", Type: "ai", LatencyMs: 50, Success: false, CreatedAt: "2026-03-02T09:00:00Z"},
+		{NoteID: "d", Type: "ai", InputTokens: 10, OutputTokens: 1, Cost: 2, Success: true, CreatedAt: "2026-02-28T09:00:00Z"},
+	} {
+		if err := store.RecordUsage(r); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+
+	days, err := store.UsageAggregates("day", 0)
+	if err != nil {
+		t.Fatalf("daily aggregates failed: %v", err)
+	}
+	if len(days) != 3 || days[0].Period != "2026-03-02" || days[0].Failures != 1 {
+		t.Fatalf("unexpected daily aggregates %#v", days)
+	}
+	if d := days[1]; d.Period != "2026-03-01" || d.Calls != 2 || d.InputTokens != 300 || d.OutputTokens != 30 || d.Cost != 1.5 || d.AvgLatencyMs != 200 {
+		t.Fatalf("unexpected aggregate for 2026-03-01: %#v", d)
+	}
+
+	months, err := store.UsageAggregates("month", 1)
+	if err != nil || len(months) != 1 || months[0].Period != "2026-03" || months[0].Calls != 3 {
+		t.Fatalf("unexpected monthly aggregates %#v (%v)", months, err)
+	}
+
+	cost, err := store.UsageCostSince(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || cost != 1.5 {
+		t.Fatalf("expected March cost 1.5, got %v (%v)", cost, err)
+	}
+	if _, err := store.UsageAggregates("week", 0); err == nil {
+		t.Fatalf("expected unknown granularity to be rejected")
+	}
+}
+
+func TestAIPricingCost(t *testing.T) {
+	p := AIPricing{InputPerMTok: 3, OutputPerMTok: 15}
+	if got := p.Cost(AIUsage{InputTokens: 1_000_000, OutputTokens: 100_000}); got != 4.5 {
+		t.Fatalf("expected cost 4.5, got %v", got)
+	}
+}
+
+func TestAISummaryQueueRecordsUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":2000,"completion_tokens":500}}`))
+	}))
+	defer server.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", server.URL, "token", "small-model", "prompt", 1, 1, 1000, 2000,
+		WithAIPricing(1, 2))
+	defer queue.Close()
+
+	queue.Enqueue(AISummaryJob{NoteID: "n1", Title: "T", Content: "body", SourceHash: "h"})
+	waitForSummaryStatus(t, store, "n1", "ready")
+
+	var report *AIUsageReport
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var err error
+		report, err = queue.UsageReport("day", 0)
+		if err != nil {
+			t.Fatalf("usage report failed: %v", err)
+		}
+		if len(report.Items) > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(report.Items) != 1 {
+		t.Fatalf("expected one day of usage, got %#v", report)
+	}
+	day := report.Items[0]
+	if day.Calls != 1 || day.InputTokens != 2000 || day.OutputTokens != 500 || day.Cost != 0.003 {
+		t.Fatalf("unexpected usage %#v", day)
+	}
+	if report.MonthToDate != 0.003 || report.Paused {
+		t.Fatalf("unexpected month-to-date %#v", report)
+	}
+}
+
+func TestAISummaryQueueDefersJobsOverBudget(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	if err := store.RecordUsage(AIUsageRecord{NoteID: "old", Type: "ai", Cost: 5, Success: true}); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	queue := NewAISummaryQueue(store, "openai-compatible", server.URL, "token", "model", "prompt", 1, 1, 1000, 2000,
+		WithAIPricing(1, 1), WithAIMonthlyBudget(5))
+	defer queue.Close()
+
+	queue.Enqueue(AISummaryJob{NoteID: "n1", Title: "T", Content: "body", SourceHash: "h"})
+
+	item, err := store.GetSummary("n1", "ai")
+	if err != nil || item == nil || item.Status != "deferred" {
+		t.Fatalf("expected deferred summary, got %#v (%v)", item, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Fatalf("expected no provider calls over budget, got %d", got)
+	}
+	if n, _ := store.CountJobs(); n != 1 {
+		t.Fatalf("expected the job to stay queued, got %d", n)
+	}
+	report, err := queue.UsageReport("month", 0)
+	if err != nil || !report.Paused || report.MonthlyLimit != 5 {
+		t.Fatalf("expected paused report, got %#v (%v)", report, err)
+	}
+}
@@ -0,0 +1,149 @@
+package blog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"golang.org/x/net/html"
+)
+
+const (
+	// defaultTOCDepth is the deepest heading level in the TOC unless the
+	// note sets #tocDepth.
+	defaultTOCDepth = 3
+	maxTOCDepth     = 6
+)
+
+const headingSelector = "h1, h2, h3, h4, h5, h6"
+
+var (
+	idUnsafeRe = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+	// headingAnchorRe matches an explicit anchor such as "{#setup}" at the
+	// end of a heading, which pins its id across edits to the text.
+	headingAnchorRe = regexp.MustCompile(`\s*\{#([\p{L}\p{N}_-]+)\}\s*$`)
+)
+
+// tocDepth returns the note's #tocDepth, clamped to 1-6.
+func tocDepth(attrs []etapi.Attribute) int {
+	depth, err := strconv.Atoi(labelValue(attrs, "tocDepth"))
+	if err != nil {
+		return defaultTOCDepth
+	}
+	return min(max(depth, 1), maxTOCDepth)
+}
+
+func (s *Service) extractTOC(html string) ([]TOCItem, string) {
+	return s.extractTOCDepth(html, defaultTOCDepth)
+}
+
+// extractTOCDepth gives every heading a unique id and lists those down to
+// level depth. Ids are lowercase slugs of the heading text, so edits to case
+// or punctuation keep them; repeated slugs get "-2", "-3" suffixes in
+// document order. Headings inside code are left alone.
+func (s *Service) extractTOCDepth(content string, depth int) ([]TOCItem, string) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return nil, content
+	}
+
+	used := make(map[string]bool)
+	doc.Find("[id]").Not(headingSelector).Each(func(_ int, sel *goquery.Selection) {
+		used[sel.AttrOr("id", "")] = true
+	})
+
+	var toc []TOCItem
+	doc.Find(headingSelector).Each(func(_ int, sel *goquery.Selection) {
+		if sel.Closest("pre, code").Length() > 0 {
+			return
+		}
+		anchor := takeHeadingAnchor(sel.Nodes[0])
+		text := strings.TrimSpace(sel.Text())
+		if text == "" {
+			return
+		}
+		id := anchor
+		if id == "" {
+			id = sel.AttrOr("id", "")
+		}
+		if id == "" {
+			id = generateID(text)
+		}
+		id = uniqueID(id, used)
+		sel.SetAttr("id", id)
+
+		if level := int(sel.Nodes[0].Data[1] - '0'); level <= depth {
+			toc = append(toc, TOCItem{ID: id, Title: text, Level: level})
+		}
+	})
+
+	result, _ := doc.Find("body").Html()
+	if result == "" {
+		return toc, content
+	}
+	return toc, strings.TrimSpace(result)
+}
+
+// takeHeadingAnchor removes an explicit "{#anchor}" from the end of a
+// heading and returns it.
+func takeHeadingAnchor(heading *html.Node) string {
+	last := lastTextNode(heading)
+	if last == nil {
+		return ""
+	}
+	m := headingAnchorRe.FindStringSubmatchIndex(last.Data)
+	if m == nil {
+		return ""
+	}
+	anchor := strings.ToLower(last.Data[m[2]:m[3]])
+	last.Data = last.Data[:m[0]]
+	return anchor
+}
+
+func lastTextNode(n *html.Node) *html.Node {
+	for c := n.LastChild; c != nil; c = c.PrevSibling {
+		if c.Type == html.TextNode && strings.TrimSpace(c.Data) != "" {
+			return c
+		}
+		if found := lastTextNode(c); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func uniqueID(id string, used map[string]bool) string {
+	candidate := id
+	for n := 2; used[candidate]; n++ {
+		candidate = id + "-" + strconv.Itoa(n)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+func generateID(text string) string {
+	id := strings.Trim(idUnsafeRe.ReplaceAllString(text, "-"), "-")
+	if id == "" {
+		return "section"
+	}
+	return id
+}
+
+// buildTOCTree nests each entry under the closest earlier entry of a
+// higher level.
+func buildTOCTree(items []TOCItem) []TOCItem {
+	var nodes []TOCItem
+	for i := 0; i < len(items); {
+		j := i + 1
+		for j < len(items) && items[j].Level > items[i].Level {
+			j++
+		}
+		node := items[i]
+		node.Children = buildTOCTree(items[i+1 : j])
+		nodes = append(nodes, node)
+		i = j
+	}
+	return nodes
+}
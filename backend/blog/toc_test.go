@@ -0,0 +1,100 @@
+package blog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestExtractTOCDepthUniqueIDs(t *testing.T) {
+	svc := NewService(nil, &NoopStore{})
+	content := `<h2>Setup</h2><p id="Usage">Intro</p><h3>Usage</h3><h2>Setup!</h2>` +
+		`<h4>Deep <em>dive</em></h4><h2>Install {#get-it}</h2><pre><code>&lt;h2&gt;Not a heading&lt;/h2&gt;</code></pre>` +
+		`<div class="include-note"><h2>Setup</h2></div>`
+
+	toc, processed := svc.extractTOCDepth(content, 4)
+	want := []TOCItem{
+		{ID: "Setup", Title: "Setup", Level: 2},
+		{ID: "Usage-2", Title: "Usage", Level: 3},
+		{ID: "Setup-2", Title: "Setup!", Level: 2},
+		{ID: "Deep-dive", Title: "Deep dive", Level: 4},
+		{ID: "get-it", Title: "Install", Level: 2},
+		{ID: "Setup-3", Title: "Setup", Level: 2},
+	}
+	if !reflect.DeepEqual(toc, want) {
+		t.Fatalf("expected %+v, got %+v", want, toc)
+	}
+	for _, id := range []string{`id="Setup"`, `id="Setup-2"`, `id="Setup-3"`, `id="get-it"`, `id="Usage"`} {
+		if !strings.Contains(processed, id) {
+			t.Fatalf("expected %s in %s", id, processed)
+		}
+	}
+	if strings.Contains(processed, "{#get-it}") {
+		t.Fatalf("expected the explicit anchor to be removed from the text, got %s", processed)
+	}
+
+	shallow, _ := svc.extractTOC(content)
+	if len(shallow) != 5 {
+		t.Fatalf("expected h4 to be left out at the default depth, got %+v", shallow)
+	}
+}
+
+func TestGenerateIDIsStableAcrossSmallEdits(t *testing.T) {
+	for _, text := range []string{"Getting Started", "Getting Started!", "  Getting—Started "} {
+		if id := generateID(text); id != "Getting-Started" {
+			t.Fatalf("expected Getting-Started for %q, got %q", text, id)
+		}
+	}
+	if id := generateID("?!"); id != "section" {
+		t.Fatalf("expected a fallback id, got %q", id)
+	}
+}
+
+func TestBuildTOCTree(t *testing.T) {
+	items := []TOCItem{
+		{ID: "a", Level: 1},
+		{ID: "b", Level: 3},
+		{ID: "c", Level: 2},
+		{ID: "d", Level: 3},
+		{ID: "e", Level: 1},
+	}
+	want := []TOCItem{
+		{ID: "a", Level: 1, Children: []TOCItem{
+			{ID: "b", Level: 3},
+			{ID: "c", Level: 2, Children: []TOCItem{{ID: "d", Level: 3}}},
+		}},
+		{ID: "e", Level: 1},
+	}
+	if got := buildTOCTree(items); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestGetPostUsesTOCDepthLabel(t *testing.T) {
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:     "post",
+		Title:      "Post",
+		Attributes: []etapi.Attribute{etapitest.Label("blog", "true"), etapitest.Label("tocDepth", "5")},
+	}, `<h2>One</h2><h3>Two</h3><h5>Five</h5><h6>Six</h6>`)
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+	service := NewService(etapi.NewClient(trilium.URL, "token"), &NoopStore{})
+
+	post, err := service.GetPostContext(t.Context(), "post")
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if len(post.TOC) != 3 || post.TOC[2].ID != "Five" {
+		t.Fatalf("expected headings down to h5, got %+v", post.TOC)
+	}
+	if len(post.TOCTree) != 1 || len(post.TOCTree[0].Children) != 1 || post.TOCTree[0].Children[0].Children[0].ID != "Five" {
+		t.Fatalf("unexpected TOC tree %+v", post.TOCTree)
+	}
+	if !strings.Contains(post.ContentHTML, `<h6 id="Six">`) {
+		t.Fatalf("expected headings below the TOC depth to get ids too, got %s", post.ContentHTML)
+	}
+}
@@ -0,0 +1,279 @@
+package blog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// translationTypePrefix starts the summary type of a translation, which
+	// ends with the target locale, e.g. "translation-en".
+	translationTypePrefix = "translation-"
+	// translationChunkRunes is the most HTML sent in one translation call;
+	// the answer is about as long, so it must fit the output limit too.
+	translationChunkRunes = 3000
+	// translationMaxTokens is the output limit of one translation call.
+	translationMaxTokens = 4096
+)
+
+const translationPrompt = `Translate the HTML fragment below into %s for a blog reader.
+- Keep every HTML tag and attribute exactly as it is; translate only the text between tags.
+- Leave every [[keep-N]] marker unchanged and in place; they stand for code and images.
+- Keep product names, commands and identifiers in their original form.
+- Answer with the translated HTML only: no explanations and no Markdown code fence.`
+
+// translationTitleAttr marks the heading that carries the post title through
+// translation; it is taken out again when the translation is served.
+const translationTitleAttr = "data-translation-title"
+
+func translationType(locale string) string {
+	return translationTypePrefix + locale
+}
+
+// translationLocale returns the target locale of a translation job type.
+func translationLocale(jobType string) (string, bool) {
+	locale, ok := strings.CutPrefix(jobType, translationTypePrefix)
+	if !ok {
+		return "", false
+	}
+	_, known := summaryLanguageNames[locale]
+	return locale, known
+}
+
+func translationJobKind(summaryType, locale string) aiJobKind {
+	return aiJobKind{
+		types:     []string{summaryType},
+		translate: true,
+		prompt:    fmt.Sprintf(translationPrompt, summaryLanguageNames[locale]),
+		parse: func(text string) (map[string]string, error) {
+			return map[string]string{summaryType: text}, nil
+		},
+	}
+}
+
+// normalizeTranslationLocale maps a lang parameter or label to a locale
+// posts can be translated into.
+func normalizeTranslationLocale(lang string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(lang)) {
+	case "en", "en-us", "en-gb", "english":
+		return "en", true
+	case "zh", "zh-cn", "zh-hans", "chinese":
+		return "zh-CN", true
+	}
+	return "", false
+}
+
+// postLocale is the language a post is written in: its #lang label, or the
+// site locale.
+func (s *Service) postLocale(attrs []etapi.Attribute) string {
+	if locale, ok := normalizeTranslationLocale(labelValue(attrs, "lang")); ok {
+		return locale
+	}
+	return normalizeSummaryLocale(s.locale)
+}
+
+// WithAITranslationEnabled lets readers request posts in the other
+// supported language, translated by the AI queue.
+func WithAITranslationEnabled(enabled bool) ServiceOption {
+	return func(s *Service) { s.aiTranslation = enabled }
+}
+
+// translatePost returns the content and title to serve for a request in
+// lang, with the translation metadata. Posts already in lang, or requests
+// for an unsupported lang, are served as they are with no metadata. While a
+// translation is generated the original is served and the metadata reports
+// its status.
+func (s *Service) translatePost(note *etapi.Note, content, lang string) (string, string, *PostTranslation) {
+	target, ok := normalizeTranslationLocale(lang)
+	source := s.postLocale(note.Attributes)
+	if !ok || target == source {
+		return content, note.Title, nil
+	}
+	translation := &PostTranslation{Lang: target, SourceLang: source, Status: "unavailable"}
+	if s.aiQueue == nil || !s.aiEnabled || !s.aiTranslation || s.summaryStore == nil || !hasReadableText(note.Type) {
+		return content, note.Title, translation
+	}
+
+	summaryType := translationType(target)
+	// The title is translated too, so renaming the post retranslates it.
+	hash := summaryPromptHash(contentHash(note.Title+"\x00"+content), summaryType)
+	item, err := s.summaryStore.GetSummary(note.NoteID, summaryType)
+	if err != nil {
+		return content, note.Title, translation
+	}
+	if item == nil || (!item.Pinned && (item.SourceHash != hash || item.Status == "")) {
+		_ = s.summaryStore.UpsertSummary(StoredSummary{
+			NoteID:     note.NoteID,
+			Type:       summaryType,
+			Status:     "pending",
+			SourceHash: hash,
+		})
+		s.aiQueue.Enqueue(AISummaryJob{
+			NoteID:     note.NoteID,
+			Type:       summaryType,
+			Title:      note.Title,
+			Content:    sanitizeContentForSummary(content),
+			SourceHash: hash,
+		})
+		item, _ = s.summaryStore.GetSummary(note.NoteID, summaryType)
+	}
+	if item == nil {
+		return content, note.Title, translation
+	}
+
+	translation.Status = item.Status
+	translation.UpdatedAt = item.UpdatedAt
+	if item.ChunksTotal > 0 && item.Status == "processing" {
+		translation.Progress = &SummaryProgress{Done: item.ChunksDone, Total: item.ChunksTotal}
+	}
+	if item.Status != "ready" || strings.TrimSpace(item.Content) == "" {
+		return content, note.Title, translation
+	}
+	translation.MachineTranslated = true
+	title, body := splitTranslatedTitle(item.Content)
+	if title == "" {
+		title = note.Title
+	}
+	return body, title, translation
+}
+
+// translate translates the HTML of a job part by part. Code and images are
+// swapped for markers first, so that they come back unchanged.
+func (q *AISummaryQueue) translate(ctx context.Context, job *ClaimedJob, progress func(done, total int)) (AIResult, error) {
+	protected, kept := protectTranslationHTML(job.Title, job.Content)
+	chunks := splitTranslationChunks(protected, min(q.maxInputRunes, translationChunkRunes))
+	if len(chunks) > 1 {
+		progress(0, len(chunks))
+	}
+
+	prompt := q.jobPrompt(job)
+	var usage AIUsage
+	parts := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		result, err := q.summarize(ctx, job, AIRequest{Prompt: prompt, Content: chunk, MaxTokens: translationMaxTokens})
+		usage.InputTokens += result.Usage.InputTokens
+		usage.OutputTokens += result.Usage.OutputTokens
+		if err != nil {
+			return AIResult{Usage: usage}, fmt.Errorf("translating part %d of %d: %w", i+1, len(chunks), err)
+		}
+		parts = append(parts, stripCodeFence(result.Text))
+		if len(chunks) > 1 {
+			progress(i+1, len(chunks))
+		}
+	}
+
+	translated, err := restoreTranslationHTML(strings.Join(parts, "\n"), kept)
+	return AIResult{Text: translated, Usage: usage}, err
+}
+
+// protectTranslationHTML puts the title in a marked heading before the
+// content and replaces code blocks, inline code and images by [[keep-N]]
+// markers. It returns the HTML to translate and the replaced elements.
+func protectTranslationHTML(title, content string) (string, []string) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return content, nil
+	}
+	var kept []string
+	for _, selector := range []string{"pre", "code", "img", "svg", "video", "audio", "iframe"} {
+		doc.Find(selector).Each(func(_ int, sel *goquery.Selection) {
+			outer, err := goquery.OuterHtml(sel)
+			if err != nil {
+				return
+			}
+			sel.ReplaceWithHtml(html.EscapeString(fmt.Sprintf("[[keep-%d]]", len(kept))))
+			kept = append(kept, outer)
+		})
+	}
+	body, _ := doc.Find("body").Html()
+	if title = strings.TrimSpace(title); title != "" {
+		body = fmt.Sprintf("<h1 %s>%s</h1>\n%s", translationTitleAttr, html.EscapeString(title), body)
+	}
+	return body, kept
+}
+
+// restoreTranslationHTML puts the replaced elements back. A marker the
+// provider dropped or repeated makes the answer invalid.
+func restoreTranslationHTML(translated string, kept []string) (string, error) {
+	for i, outer := range kept {
+		marker := fmt.Sprintf("[[keep-%d]]", i)
+		if n := strings.Count(translated, marker); n != 1 {
+			return "", fmt.Errorf("translation has %d copies of %s, want 1", n, marker)
+		}
+		translated = strings.Replace(translated, marker, outer, 1)
+	}
+	return strings.TrimSpace(translated), nil
+}
+
+// splitTranslationChunks groups the top-level elements of an HTML fragment
+// into chunks of at most maxRunes. An element longer than that is sent on
+// its own.
+func splitTranslationChunks(fragment string, maxRunes int) []string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil || maxRunes <= 0 {
+		return []string{fragment}
+	}
+	var chunks []string
+	var current strings.Builder
+	size := 0
+	for _, n := range nodes {
+		var b strings.Builder
+		if err := html.Render(&b, n); err != nil {
+			continue
+		}
+		part := b.String()
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		n := len([]rune(part))
+		if size > 0 && size+n > maxRunes {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			size = 0
+		}
+		current.WriteString(part)
+		size += n
+	}
+	if size > 0 {
+		chunks = append(chunks, current.String())
+	}
+	if len(chunks) == 0 {
+		return []string{fragment}
+	}
+	return chunks
+}
+
+// splitTranslatedTitle takes the marked title heading out of a stored
+// translation.
+func splitTranslatedTitle(translated string) (string, string) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(translated))
+	if err != nil {
+		return "", translated
+	}
+	heading := doc.Find("h1[" + translationTitleAttr + "]").First()
+	if heading.Length() == 0 {
+		return "", translated
+	}
+	title := strings.TrimSpace(heading.Text())
+	heading.Remove()
+	body, _ := doc.Find("body").Html()
+	return title, strings.TrimSpace(body)
+}
+
+// stripCodeFence removes a Markdown fence some models put around HTML.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(text, "```"))
+}
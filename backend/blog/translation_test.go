@@ -0,0 +1,152 @@
+package blog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestProtectTranslationHTMLKeepsCodeAndImages(t *testing.T) {
+	content := `<p>Run <code>go test</code> first.</p><pre><code class="language-go">fmt.Println("hi")</code></pre><p><img src="/a.png" alt="chart"></p>`
+	protected, kept := protectTranslationHTML("Testing in Go", content)
+
+	if len(kept) != 3 {
+		t.Fatalf("expected code block, inline code and image to be kept, got %#v", kept)
+	}
+	for _, leaked := range []string{"fmt.Println", "go test", "a.png"} {
+		if strings.Contains(protected, leaked) {
+			t.Fatalf("expected %q to be hidden from the provider, got %s", leaked, protected)
+		}
+	}
+	if !strings.HasPrefix(protected, `<h1 data-translation-title>Testing in Go</h1>`) {
+		t.Fatalf("expected the title heading first, got %s", protected)
+	}
+
+	translated := strings.NewReplacer("Run", "先运行", "first.", "。", "Testing in Go", "Go 测试").Replace(protected)
+	restored, err := restoreTranslationHTML(translated, kept)
+	if err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	title, body := splitTranslatedTitle(restored)
+	if title != "Go 测试" {
+		t.Fatalf("unexpected title %q", title)
+	}
+	for _, want := range []string{"<code>go test</code>", `<pre><code class="language-go">`, `src="/a.png"`, "先运行"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in %s", want, body)
+		}
+	}
+
+	if _, err := restoreTranslationHTML(strings.Replace(translated, "[[keep-1]]", "", 1), kept); err == nil {
+		t.Fatalf("expected a dropped marker to be rejected")
+	}
+}
+
+func TestSplitTranslationChunks(t *testing.T) {
+	fragment := strings.Repeat("<p>"+strings.Repeat("word ", 20)+"</p>", 10)
+	chunks := splitTranslationChunks(fragment, 300)
+	if len(chunks) < 4 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	if strings.Join(chunks, "") != fragment {
+		t.Fatalf("expected the chunks to add up to the fragment")
+	}
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "<p>") || !strings.HasSuffix(chunk, "</p>") {
+			t.Fatalf("expected chunks to split between elements, got %q", chunk)
+		}
+	}
+}
+
+func TestGetPostServesMachineTranslation(t *testing.T) {
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:       "note-lang",
+		Title:        "你好",
+		DateModified: "2026-04-13T12:00:00Z",
+		Attributes:   []etapi.Attribute{etapitest.Label("blog", "true"), etapitest.Label("lang", "zh-CN")},
+	}, `<h2>世界</h2><p>运行 <code>go run .</code></p>`)
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+
+	aiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		// The post's AI summary is queued too; only translations are checked.
+		translated := "A summary."
+		if strings.HasPrefix(body.Messages[0].Content, "Translate") {
+			if !strings.Contains(body.Messages[0].Content, "into English") {
+				t.Errorf("unexpected prompt %q", body.Messages[0].Content)
+			}
+			translated = strings.NewReplacer("你好", "Hello", "世界", "World", "运行", "Run").Replace(body.Messages[1].Content)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": "```html\n" + translated + "\n```"}}},
+		})
+	}))
+	defer aiServer.Close()
+
+	store := newTestSummaryStore(t, filepath.Join(t.TempDir(), "summaries.db"))
+	defer store.Close()
+	queue := NewAISummaryQueue(store, "openai-compatible", aiServer.URL, "token", "model", "prompt", 1, 1, 30000, 2000)
+	defer queue.Close()
+	service := NewService(etapi.NewClient(trilium.URL, "token"), &NoopStore{}, WithLocale("en"),
+		WithSummaryStore(store), WithAISummaryQueue(queue), WithAISummaryEnabled(true), WithAITranslationEnabled(true))
+
+	original, err := service.GetPostLangContext(t.Context(), "note-lang", "zh-CN")
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if original.Translation != nil || original.Lang != "zh-CN" {
+		t.Fatalf("expected the original for its own language, got %#v", original.Translation)
+	}
+
+	pending, err := service.GetPostLangContext(t.Context(), "note-lang", "en")
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if pending.Translation == nil || pending.Translation.MachineTranslated || pending.Title != "你好" {
+		t.Fatalf("expected the original while the translation is queued, got %#v", pending.Translation)
+	}
+
+	waitForSummaryType(t, store, "note-lang", "translation-en", "ready")
+	post, err := service.GetPostLangContext(t.Context(), "note-lang", "en")
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if post.Translation == nil || !post.Translation.MachineTranslated || post.Translation.SourceLang != "zh-CN" || post.Lang != "en" {
+		t.Fatalf("expected a machine translation, got %#v", post.Translation)
+	}
+	if post.Title != "Hello" || !strings.Contains(post.ContentHTML, "World") || !strings.Contains(post.ContentHTML, "Run") {
+		t.Fatalf("unexpected translated post %q %s", post.Title, post.ContentHTML)
+	}
+	if !strings.Contains(post.ContentHTML, "go run .") || len(post.TOC) != 1 || post.TOC[0].Title != "World" {
+		t.Fatalf("expected code kept and the TOC translated, got %s %#v", post.ContentHTML, post.TOC)
+	}
+}
+
+func TestGetPostTranslationDisabled(t *testing.T) {
+	blogServer := newBlogTestServer(t, "note-1", "<p>Hello.</p>")
+	defer blogServer.Close()
+	service := NewService(etapi.NewClient(blogServer.URL, "token"), &NoopStore{}, WithLocale("en"))
+
+	post, err := service.GetPostLangContext(t.Context(), "note-1", "zh-CN")
+	if err != nil {
+		t.Fatalf("get post failed: %v", err)
+	}
+	if post.Translation == nil || post.Translation.Status != "unavailable" || !strings.Contains(post.ContentHTML, "Hello.") {
+		t.Fatalf("expected the original marked unavailable, got %#v", post.Translation)
+	}
+}
@@ -0,0 +1,70 @@
+// Command fake-trilium serves the etapitest in-memory Trilium over HTTP so the
+// blog (and its frontend) can run without a real Trilium instance:
+//
+//	go run ./cmd/fake-trilium -addr :37840 -token dev
+//	TRILIUM_API_URL=http://localhost:37840 TRILIUM_TOKEN=dev go run .
+//
+// Without -fixture a small demo blog is loaded.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+)
+
+func main() {
+	addr := flag.String("addr", ":37840", "listen address")
+	token := flag.String("token", "", "required ETAPI token (empty accepts any)")
+	fixture := flag.String("fixture", "", "JSON fixture file (default: built-in demo blog)")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	failStatus := flag.Int("fail-status", 0, "answer every request under -fail-path with this status")
+	failPath := flag.String("fail-path", "", "path prefix for -fail-status, e.g. /etapi/notes")
+	flag.Parse()
+
+	logger.Init("info")
+
+	var opts []etapitest.Option
+	if *token != "" {
+		opts = append(opts, etapitest.WithToken(*token))
+	}
+	if *latency > 0 {
+		opts = append(opts, etapitest.WithLatency(*latency))
+	}
+	fake := etapitest.New(opts...)
+
+	fx := etapitest.Demo()
+	if *fixture != "" {
+		f, err := os.Open(*fixture)
+		if err != nil {
+			logger.Fatal("Failed to open fixture", err)
+		}
+		fx, err = etapitest.ReadFixture(f)
+		f.Close()
+		if err != nil {
+			logger.Fatal("Failed to read fixture", err)
+		}
+	}
+	if err := fake.Load(fx); err != nil {
+		logger.Fatal("Failed to load fixture", err)
+	}
+
+	if *failStatus != 0 {
+		fake.InjectFault(etapitest.Fault{PathPrefix: *failPath, Status: *failStatus})
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           fake,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logger.Info(fmt.Sprintf("Fake Trilium ETAPI listening on %s (%d notes)", *addr, fake.NoteCount()))
+	if err := srv.ListenAndServe(); err != nil {
+		logger.Fatal("Fake Trilium server failed", err)
+	}
+}
@@ -13,23 +13,81 @@ type ImageProxyConfig struct {
 	BaseURL string
 }
 
+// CodeHighlightConfig selects where code blocks are highlighted. Themes
+// are Chroma style names.
+type CodeHighlightConfig struct {
+	Server    bool
+	Theme     string
+	DarkTheme string
+}
+
+type TriliumRetryConfig struct {
+	MaxRetries        int
+	BaseDelayMs       int
+	MaxDelayMs        int
+	BreakerThreshold  int
+	BreakerCooldownMs int
+}
+
+type MetricsConfig struct {
+	Enabled bool
+	Addr    string
+}
+
 type AISummaryConfig struct {
-	Enabled       bool
-	Provider      string
-	BaseURL       string
-	APIKey        string
-	Model         string
+	Enabled  bool
+	Provider string
+	BaseURL  string
+	APIKey   string
+	Model    string
+	// Prompt applies to every locale without its own template; empty uses
+	// the built-in prompts. All prompts are text/template templates.
 	Prompt        string
+	PromptZhCN    string
+	PromptEn      string
+	TargetLength  int
 	Mode          string
 	Concurrency   int
 	RateLimitMs   int
 	TimeoutMs     int
 	MaxInputChars int
+	MaxAttempts   int
+	RetryBaseMs   int
+	RetryMaxMs    int
+	// Prices are per million tokens; MonthlyBudget is in the same unit.
+	InputPricePerMTok  float64
+	OutputPricePerMTok float64
+	MonthlyBudget      float64
+	// LongDocument summarizes articles longer than MaxInputChars in up to
+	// MaxChunks parts instead of truncating them.
+	LongDocument bool
+	MaxChunks    int
+	// SEO also asks for tags, keywords and a meta description per post.
+	SEO bool
+	// Translation lets readers ask for posts in the other supported
+	// language, machine translated by the queue.
+	Translation bool
+	// Ask enables /api/ask; AskRateLimit is the questions allowed per
+	// client address per minute.
+	Ask          bool
+	AskRateLimit int
+	// EmbeddingModel enables embedding-based related posts; the endpoint
+	// must be OpenAI-compatible and defaults to the summary one.
+	EmbeddingModel   string
+	EmbeddingBaseURL string
+	EmbeddingAPIKey  string
+	// EmbeddingMinScore is the cosine similarity a post needs to match
+	// a semantic search.
+	EmbeddingMinScore float64
+	// SearchRateLimit is the semantic and hybrid searches allowed per
+	// client address per minute, as each new query is embedded.
+	SearchRateLimit int
 }
 
 type AppConfig struct {
 	TriliumApiUrl   string
 	TriliumToken    string
+	TriliumRetry    TriliumRetryConfig
 	ArticlesPerPage int
 	BlogTitle       string
 	BlogSubtitle    string
@@ -38,15 +96,27 @@ type AppConfig struct {
 	AdminToken      string
 	LogLevel        string
 	ImageProxy      ImageProxyConfig
+	CodeHighlight   CodeHighlightConfig
 	AISummary       AISummaryConfig
+	Metrics         MetricsConfig
+	// TrustedProxies are the proxy addresses or CIDRs whose X-Forwarded-For
+	// is believed when finding the client address; empty trusts none.
+	TrustedProxies []string
 }
 
 var Config AppConfig
 
 func LoadConfig() {
 	Config = AppConfig{
-		TriliumApiUrl:   getEnv("TRILIUM_API_URL", ""),
-		TriliumToken:    getEnv("TRILIUM_TOKEN", ""),
+		TriliumApiUrl: getEnv("TRILIUM_API_URL", ""),
+		TriliumToken:  getEnv("TRILIUM_TOKEN", ""),
+		TriliumRetry: TriliumRetryConfig{
+			MaxRetries:        getEnvInt("TRILIUM_RETRY_MAX", 2),
+			BaseDelayMs:       getEnvInt("TRILIUM_RETRY_BASE_MS", 200),
+			MaxDelayMs:        getEnvInt("TRILIUM_RETRY_MAX_MS", 5000),
+			BreakerThreshold:  getEnvInt("TRILIUM_BREAKER_THRESHOLD", 5),
+			BreakerCooldownMs: getEnvInt("TRILIUM_BREAKER_COOLDOWN_MS", 30000),
+		},
 		ArticlesPerPage: getEnvInt("ARTICLES_PER_PAGE", 9),
 		BlogTitle:       getEnv("BLOG_TITLE", ""),
 		BlogSubtitle:    getEnv("BLOG_SUBTITLE", ""),
@@ -54,22 +124,52 @@ func LoadConfig() {
 		Locale:          normalizeLocale(getEnv("LOCALE", "zh-CN")),
 		AdminToken:      getEnv("ADMIN_TOKEN", ""),
 		LogLevel:        normalizeLogLevel(getEnv("LOG_LEVEL", "info")),
+		TrustedProxies:  getEnvList("TRUSTED_PROXIES"),
 		ImageProxy: ImageProxyConfig{
 			Enabled: getEnvBool("IMAGE_PROXY_ENABLED", false),
 			BaseURL: getEnv("IMAGE_PROXY_BASE_URL", ""),
 		},
+		CodeHighlight: CodeHighlightConfig{
+			Server:    getEnvBool("CODE_HIGHLIGHT_SERVER", false),
+			Theme:     getEnv("CODE_HIGHLIGHT_THEME", "github"),
+			DarkTheme: getEnv("CODE_HIGHLIGHT_DARK_THEME", "github-dark"),
+		},
 		AISummary: AISummaryConfig{
-			Enabled:       getEnvBool("AI_SUMMARY_ENABLED", false),
-			Provider:      normalizeAISummaryProvider(getEnv("AI_SUMMARY_PROVIDER", "openai-compatible")),
-			BaseURL:       getEnv("AI_SUMMARY_BASE_URL", ""),
-			APIKey:        getEnv("AI_SUMMARY_API_KEY", ""),
-			Model:         getEnv("AI_SUMMARY_MODEL", ""),
-			Prompt:        getEnv("AI_SUMMARY_PROMPT", "Summarize the article for a blog reader in concise Chinese."),
-			Mode:          normalizeAISummaryMode(getEnv("AI_SUMMARY_MODE", "code")),
-			Concurrency:   getEnvInt("AI_SUMMARY_CONCURRENCY", 2),
-			RateLimitMs:   getEnvInt("AI_SUMMARY_RATE_LIMIT_MS", 1200),
-			TimeoutMs:     getEnvInt("AI_SUMMARY_TIMEOUT_MS", 60000),
-			MaxInputChars: getEnvInt("AI_SUMMARY_MAX_INPUT_CHARS", 12000),
+			Enabled:            getEnvBool("AI_SUMMARY_ENABLED", false),
+			Provider:           normalizeAISummaryProvider(getEnv("AI_SUMMARY_PROVIDER", "openai-compatible")),
+			BaseURL:            getEnv("AI_SUMMARY_BASE_URL", ""),
+			APIKey:             getEnv("AI_SUMMARY_API_KEY", ""),
+			Model:              getEnv("AI_SUMMARY_MODEL", ""),
+			Prompt:             getEnv("AI_SUMMARY_PROMPT", ""),
+			PromptZhCN:         getEnv("AI_SUMMARY_PROMPT_ZH_CN", ""),
+			PromptEn:           getEnv("AI_SUMMARY_PROMPT_EN", ""),
+			TargetLength:       getEnvInt("AI_SUMMARY_TARGET_LENGTH", 100),
+			Mode:               normalizeAISummaryMode(getEnv("AI_SUMMARY_MODE", "code")),
+			Concurrency:        getEnvInt("AI_SUMMARY_CONCURRENCY", 2),
+			RateLimitMs:        getEnvInt("AI_SUMMARY_RATE_LIMIT_MS", 1200),
+			TimeoutMs:          getEnvInt("AI_SUMMARY_TIMEOUT_MS", 60000),
+			MaxInputChars:      getEnvInt("AI_SUMMARY_MAX_INPUT_CHARS", 12000),
+			MaxAttempts:        getEnvInt("AI_SUMMARY_MAX_ATTEMPTS", 4),
+			RetryBaseMs:        getEnvInt("AI_SUMMARY_RETRY_BASE_MS", 30000),
+			RetryMaxMs:         getEnvInt("AI_SUMMARY_RETRY_MAX_MS", 1800000),
+			InputPricePerMTok:  getEnvFloat("AI_SUMMARY_INPUT_PRICE_PER_MTOK", 0),
+			OutputPricePerMTok: getEnvFloat("AI_SUMMARY_OUTPUT_PRICE_PER_MTOK", 0),
+			MonthlyBudget:      getEnvFloat("AI_SUMMARY_MONTHLY_BUDGET", 0),
+			LongDocument:       getEnvBool("AI_SUMMARY_LONG_DOC", false),
+			MaxChunks:          getEnvInt("AI_SUMMARY_MAX_CHUNKS", 6),
+			SEO:                getEnvBool("AI_SUMMARY_SEO", false),
+			Translation:        getEnvBool("AI_TRANSLATION_ENABLED", false),
+			Ask:                getEnvBool("AI_ASK_ENABLED", false),
+			AskRateLimit:       getEnvInt("AI_ASK_RATE_LIMIT", 10),
+			EmbeddingModel:     getEnv("AI_EMBEDDING_MODEL", ""),
+			EmbeddingBaseURL:   getEnv("AI_EMBEDDING_BASE_URL", getEnv("AI_SUMMARY_BASE_URL", "")),
+			EmbeddingAPIKey:    getEnv("AI_EMBEDDING_API_KEY", getEnv("AI_SUMMARY_API_KEY", "")),
+			EmbeddingMinScore:  getEnvFloat("AI_EMBEDDING_SEARCH_MIN_SCORE", 0.3),
+			SearchRateLimit:    getEnvInt("AI_SEARCH_RATE_LIMIT", 30),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getEnvBool("METRICS_ENABLED", false),
+			Addr:    getEnv("METRICS_ADDR", ""),
 		},
 	}
 
@@ -88,6 +188,17 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// getEnvList reads a comma-separated list, dropping empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getEnvInt(key string, fallback int) int {
 	v := os.Getenv(key)
 	if v == "" {
@@ -100,6 +211,18 @@ func getEnvInt(key string, fallback int) int {
 	return n
 }
 
+func getEnvFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
+
 func getEnvBool(key string, fallback bool) bool {
 	v := os.Getenv(key)
 	if v == "" {
@@ -125,6 +248,12 @@ func normalizeAISummaryProvider(provider string) string {
 	switch strings.ToLower(strings.TrimSpace(provider)) {
 	case "", "openai", "openai-compatible":
 		return "openai-compatible"
+	case "anthropic", "claude":
+		return "anthropic"
+	case "ollama":
+		return "ollama"
+	case "gemini", "google":
+		return "gemini"
 	default:
 		return provider
 	}
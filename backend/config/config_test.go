@@ -10,6 +10,15 @@ func TestAISummaryConfigNormalization(t *testing.T) {
 	t.Setenv("AI_SUMMARY_MODE", "AI")
 	t.Setenv("AI_SUMMARY_TIMEOUT_MS", "45000")
 	t.Setenv("AI_SUMMARY_MAX_INPUT_CHARS", "9000")
+	t.Setenv("AI_SUMMARY_INPUT_PRICE_PER_MTOK", "0.15")
+	t.Setenv("AI_SUMMARY_MONTHLY_BUDGET", "12.5")
+	t.Setenv("AI_SUMMARY_LONG_DOC", "true")
+	t.Setenv("AI_SUMMARY_SEO", "true")
+	t.Setenv("AI_TRANSLATION_ENABLED", "true")
+	t.Setenv("AI_ASK_ENABLED", "true")
+	t.Setenv("AI_SUMMARY_PROMPT_EN", "Summarize {{.Title}}")
+	t.Setenv("AI_SUMMARY_BASE_URL", "https://llm.example.com/v1")
+	t.Setenv("AI_EMBEDDING_MODEL", "text-embedding-3-small")
 
 	LoadConfig()
 
@@ -28,6 +37,20 @@ func TestAISummaryConfigNormalization(t *testing.T) {
 	if Config.AISummary.MaxInputChars != 9000 {
 		t.Fatalf("expected max input chars to be loaded, got %d", Config.AISummary.MaxInputChars)
 	}
+	if Config.AISummary.InputPricePerMTok != 0.15 || Config.AISummary.OutputPricePerMTok != 0 || Config.AISummary.MonthlyBudget != 12.5 {
+		t.Fatalf("expected pricing to be loaded, got %+v", Config.AISummary)
+	}
+	if !Config.AISummary.LongDocument || Config.AISummary.MaxChunks != 6 || !Config.AISummary.SEO || !Config.AISummary.Translation ||
+		!Config.AISummary.Ask || Config.AISummary.AskRateLimit != 10 || Config.AISummary.SearchRateLimit != 30 {
+		t.Fatalf("expected long document mode with default chunk limit, got %+v", Config.AISummary)
+	}
+	if Config.AISummary.Prompt != "" || Config.AISummary.PromptEn != "Summarize {{.Title}}" || Config.AISummary.TargetLength != 100 {
+		t.Fatalf("expected prompt templates to be loaded, got %+v", Config.AISummary)
+	}
+	if Config.AISummary.EmbeddingModel != "text-embedding-3-small" || Config.AISummary.EmbeddingBaseURL != "https://llm.example.com/v1" ||
+		Config.AISummary.EmbeddingMinScore != 0.3 {
+		t.Fatalf("expected embedding endpoint to default to the summary one, got %+v", Config.AISummary)
+	}
 }
 
 func TestAISummaryModeDefaultsToCode(t *testing.T) {
@@ -45,3 +68,33 @@ func TestAISummaryModeDefaultsToCode(t *testing.T) {
 		t.Fatalf("expected AI requests to stay disabled in code mode")
 	}
 }
+
+func TestAISummaryProviderAliases(t *testing.T) {
+	for input, want := range map[string]string{
+		"Claude":    "anthropic",
+		"anthropic": "anthropic",
+		"OLLAMA":    "ollama",
+		"google":    "gemini",
+		"":          "openai-compatible",
+	} {
+		if got := normalizeAISummaryProvider(input); got != want {
+			t.Fatalf("normalizeAISummaryProvider(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestTrustedProxiesList(t *testing.T) {
+	t.Setenv("TRILIUM_API_URL", "https://trilium.example.com")
+	t.Setenv("TRILIUM_TOKEN", "token")
+	t.Setenv("TRUSTED_PROXIES", " 10.0.0.1, 172.16.0.0/12,,")
+	LoadConfig()
+	if got := Config.TrustedProxies; len(got) != 2 || got[0] != "10.0.0.1" || got[1] != "172.16.0.0/12" {
+		t.Fatalf("unexpected trusted proxies %#v", got)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "")
+	LoadConfig()
+	if Config.TrustedProxies != nil {
+		t.Fatalf("expected no trusted proxies by default, got %#v", Config.TrustedProxies)
+	}
+}
@@ -1,111 +1,273 @@
 package etapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
+
+	"github.com/harveyTon/trilium-blog/backend/pkg/metrics"
 )
 
 type Client struct {
 	baseURL    string
 	token      string
 	httpClient *http.Client
+	retry      retryPolicy
+	breaker    *circuitBreaker
+}
+
+type ClientOption func(*Client)
+
+// WithRetry retries failed GETs up to maxRetries times on connection errors
+// and 429/502/503/504 responses, waiting a jittered exponential backoff
+// between baseDelay and maxDelay, or the server's Retry-After when present.
+func WithRetry(maxRetries int, baseDelay, maxDelay time.Duration) ClientOption {
+	return func(c *Client) {
+		if maxRetries < 0 {
+			maxRetries = 0
+		}
+		if maxDelay < baseDelay {
+			maxDelay = baseDelay
+		}
+		c.retry = retryPolicy{maxRetries: maxRetries, baseDelay: baseDelay, maxDelay: maxDelay}
+	}
 }
 
-func NewClient(baseURL, token string) *Client {
-	return &Client{
+// WithCircuitBreaker makes the client fail fast after threshold consecutive
+// failed calls, probing again once cooldown has passed. A threshold of zero
+// or less leaves the breaker disabled.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		if threshold <= 0 {
+			c.breaker = nil
+			return
+		}
+		c.breaker = newCircuitBreaker(threshold, cooldown)
+		c.breaker.onChange = metrics.SetETAPIBreakerState
+	}
+}
+
+func NewClient(baseURL, token string, opts ...ClientOption) *Client {
+	c := &Client{
 		baseURL: baseURL,
 		token:   token,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BreakerStatus reports the circuit breaker state. A client without a
+// breaker always reports closed.
+func (c *Client) BreakerStatus() BreakerStatus {
+	if c.breaker == nil {
+		return BreakerStatus{State: BreakerClosed}
+	}
+	return c.breaker.status()
 }
 
 type Note struct {
-	NoteID       string      `json:"noteId"`
-	Title        string      `json:"title"`
-	DateModified string      `json:"dateModified"`
-	Type         string      `json:"type"`
-	Mime         string      `json:"mime"`
-	Attributes   []Attribute `json:"attributes"`
+	NoteID          string      `json:"noteId"`
+	Title           string      `json:"title"`
+	DateCreated     string      `json:"dateCreated,omitempty"`
+	DateModified    string      `json:"dateModified"`
+	UtcDateCreated  string      `json:"utcDateCreated,omitempty"`
+	UtcDateModified string      `json:"utcDateModified,omitempty"`
+	Type            string      `json:"type"`
+	Mime            string      `json:"mime"`
+	IsProtected     bool        `json:"isProtected,omitempty"`
+	BlobID          string      `json:"blobId,omitempty"`
+	Attributes      []Attribute `json:"attributes"`
+	ParentNoteIDs   []string    `json:"parentNoteIds,omitempty"`
+	ChildNoteIDs    []string    `json:"childNoteIds,omitempty"`
+	ParentBranchIDs []string    `json:"parentBranchIds,omitempty"`
+	ChildBranchIDs  []string    `json:"childBranchIds,omitempty"`
 }
 
 type Attribute struct {
-	Type  string `json:"type"`
-	Name  string `json:"name"`
-	Value string `json:"value"`
+	AttributeID     string `json:"attributeId,omitempty"`
+	NoteID          string `json:"noteId,omitempty"`
+	Type            string `json:"type"`
+	Name            string `json:"name"`
+	Value           string `json:"value"`
+	Position        int    `json:"position,omitempty"`
+	IsInheritable   bool   `json:"isInheritable,omitempty"`
+	UtcDateModified string `json:"utcDateModified,omitempty"`
 }
 
 type NotesResponse struct {
 	Results []Note `json:"results"`
 }
 
+// SearchOptions are the optional query parameters of GET /etapi/notes.
+// Zero values are left out of the request so Trilium's defaults apply.
+type SearchOptions struct {
+	OrderBy              string
+	OrderDirection       string
+	Limit                int
+	AncestorNoteID       string
+	AncestorDepth        string
+	FastSearch           bool
+	IncludeArchivedNotes bool
+}
+
+func (o SearchOptions) values(search string) url.Values {
+	v := url.Values{}
+	v.Set("search", search)
+	if o.OrderBy != "" {
+		v.Set("orderBy", o.OrderBy)
+	}
+	if o.OrderDirection != "" {
+		v.Set("orderDirection", o.OrderDirection)
+	}
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.AncestorNoteID != "" {
+		v.Set("ancestorNoteId", o.AncestorNoteID)
+	}
+	if o.AncestorDepth != "" {
+		v.Set("ancestorDepth", o.AncestorDepth)
+	}
+	if o.FastSearch {
+		v.Set("fastSearch", "true")
+	}
+	if o.IncludeArchivedNotes {
+		v.Set("includeArchivedNotes", "true")
+	}
+	return v
+}
+
 func (c *Client) GetNotes(search string) ([]Note, error) {
-	encoded := url.QueryEscape(search)
-	reqURL := fmt.Sprintf("%s/etapi/notes?search=%s&orderBy=utcDateModified", c.baseURL, encoded)
+	return c.GetNotesContext(context.Background(), search)
+}
+
+func (c *Client) GetNotesContext(ctx context.Context, search string) ([]Note, error) {
+	return c.SearchNotes(ctx, search, SearchOptions{OrderBy: "utcDateModified"})
+}
+
+func (c *Client) SearchNotes(ctx context.Context, search string, opts SearchOptions) ([]Note, error) {
+	reqURL := fmt.Sprintf("%s/etapi/notes?%s", c.baseURL, opts.values(search).Encode())
 	var resp NotesResponse
-	if err := c.doRequest(reqURL, &resp); err != nil {
+	if err := c.doRequest(ctx, "notes.search", reqURL, &resp); err != nil {
 		return nil, err
 	}
 	return resp.Results, nil
 }
 
 func (c *Client) GetNote(noteID string) (*Note, error) {
+	return c.GetNoteContext(context.Background(), noteID)
+}
+
+func (c *Client) GetNoteContext(ctx context.Context, noteID string) (*Note, error) {
 	url := fmt.Sprintf("%s/etapi/notes/%s", c.baseURL, noteID)
 	var note Note
-	if err := c.doRequest(url, &note); err != nil {
+	if err := c.doRequest(ctx, "notes.get", url, &note); err != nil {
 		return nil, err
 	}
 	return &note, nil
 }
 
 func (c *Client) GetNoteContent(noteID string) (string, error) {
-	url := fmt.Sprintf("%s/etapi/notes/%s/content", c.baseURL, noteID)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Add("Authorization", c.token)
+	return c.GetNoteContentContext(context.Background(), noteID)
+}
 
-	resp, err := c.httpClient.Do(req)
+func (c *Client) GetNoteContentContext(ctx context.Context, noteID string) (string, error) {
+	url := fmt.Sprintf("%s/etapi/notes/%s/content", c.baseURL, noteID)
+	body, err := c.getBytes(ctx, "notes.content", url, "note content")
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	return string(body), nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get note content: status %d", resp.StatusCode)
+// GetNoteAttachments lists the attachments owned by a note.
+func (c *Client) GetNoteAttachments(ctx context.Context, noteID string) ([]Attachment, error) {
+	url := fmt.Sprintf("%s/etapi/notes/%s/attachments", c.baseURL, noteID)
+	var atts []Attachment
+	if err := c.doRequest(ctx, "notes.attachments", url, &atts); err != nil {
+		return nil, err
 	}
+	return atts, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+func (c *Client) GetAttribute(ctx context.Context, attributeID string) (*Attribute, error) {
+	url := fmt.Sprintf("%s/etapi/attributes/%s", c.baseURL, attributeID)
+	var attr Attribute
+	if err := c.doRequest(ctx, "attributes.get", url, &attr); err != nil {
+		return nil, err
 	}
-	return string(body), nil
+	return &attr, nil
+}
+
+type AppInfo struct {
+	AppVersion             string `json:"appVersion"`
+	DBVersion              int    `json:"dbVersion"`
+	SyncVersion            int    `json:"syncVersion"`
+	BuildDate              string `json:"buildDate"`
+	BuildRevision          string `json:"buildRevision"`
+	DataDirectory          string `json:"dataDirectory"`
+	ClipperProtocolVersion string `json:"clipperProtocolVersion"`
+	UtcDateTime            string `json:"utcDateTime"`
+}
+
+func (c *Client) GetAppInfo(ctx context.Context) (*AppInfo, error) {
+	url := fmt.Sprintf("%s/etapi/app-info", c.baseURL)
+	var info AppInfo
+	if err := c.doRequest(ctx, "app-info", url, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
 }
 
 type Attachment struct {
-	OwnerID string `json:"ownerId"`
-	Mime    string `json:"mime"`
+	AttachmentID    string `json:"attachmentId,omitempty"`
+	OwnerID         string `json:"ownerId"`
+	Role            string `json:"role,omitempty"`
+	Mime            string `json:"mime"`
+	Title           string `json:"title,omitempty"`
+	Position        int    `json:"position,omitempty"`
+	BlobID          string `json:"blobId,omitempty"`
+	DateModified    string `json:"dateModified,omitempty"`
+	UtcDateModified string `json:"utcDateModified,omitempty"`
+	ContentLength   int    `json:"contentLength,omitempty"`
 }
 
 func (c *Client) GetAttachment(attachmentID string) (*Attachment, error) {
+	return c.GetAttachmentContext(context.Background(), attachmentID)
+}
+
+func (c *Client) GetAttachmentContext(ctx context.Context, attachmentID string) (*Attachment, error) {
 	url := fmt.Sprintf("%s/etapi/attachments/%s", c.baseURL, attachmentID)
 	var att Attachment
-	if err := c.doRequest(url, &att); err != nil {
+	if err := c.doRequest(ctx, "attachments.get", url, &att); err != nil {
 		return nil, err
 	}
 	return &att, nil
 }
 
 func (c *Client) GetAttachmentContent(attachmentID string) ([]byte, string, error) {
-	att, err := c.GetAttachment(attachmentID)
+	return c.GetAttachmentContentContext(context.Background(), attachmentID)
+}
+
+func (c *Client) GetAttachmentContentContext(ctx context.Context, attachmentID string) ([]byte, string, error) {
+	att, err := c.GetAttachmentContext(ctx, attachmentID)
 	if err != nil {
 		return nil, "", err
 	}
 
-	body, err := c.GetAttachmentContentBytes(attachmentID)
+	body, err := c.GetAttachmentContentBytesContext(ctx, attachmentID)
 	if err != nil {
 		return nil, "", err
 	}
@@ -113,33 +275,105 @@ func (c *Client) GetAttachmentContent(attachmentID string) ([]byte, string, erro
 }
 
 func (c *Client) GetAttachmentContentBytes(attachmentID string) ([]byte, error) {
+	return c.GetAttachmentContentBytesContext(context.Background(), attachmentID)
+}
+
+func (c *Client) GetAttachmentContentBytesContext(ctx context.Context, attachmentID string) ([]byte, error) {
 	url := fmt.Sprintf("%s/etapi/attachments/%s/content", c.baseURL, attachmentID)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Add("Authorization", c.token)
+	return c.getBytes(ctx, "attachments.content", url, "attachment content")
+}
 
-	resp, err := c.httpClient.Do(req)
+// getBytes fetches a raw (non-JSON) body such as note or attachment content.
+func (c *Client) getBytes(ctx context.Context, endpoint, url, what string) ([]byte, error) {
+	resp, err := c.get(ctx, endpoint, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get attachment content: status %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to get %s: status %d", what, resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	return io.ReadAll(resp.Body)
+}
+
+// get issues an authenticated GET through the circuit breaker and retry
+// policy. Every attempt is recorded under the given endpoint name in the
+// ETAPI metrics.
+func (c *Client) get(ctx context.Context, endpoint, url string) (*http.Response, error) {
+	if c.breaker == nil {
+		return c.getWithRetry(ctx, endpoint, url)
+	}
+	if err := c.breaker.allow(time.Now()); err != nil {
 		return nil, err
 	}
-	return body, nil
+	resp, err := c.getWithRetry(ctx, endpoint, url)
+	if ctx.Err() != nil {
+		// The caller gave up; that says nothing about Trilium's health.
+		c.breaker.release()
+		return resp, err
+	}
+	// Throttling counts against Trilium's health like a server error does.
+	c.breaker.record(err == nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests, time.Now())
+	return resp, err
 }
 
-func (c *Client) doRequest(url string, target interface{}) error {
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Add("Authorization", c.token)
+func (c *Client) getWithRetry(ctx context.Context, endpoint, url string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Authorization", c.token)
 
-	resp, err := c.httpClient.Do(req)
+		start := time.Now()
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			metrics.ObserveETAPIRequest(endpoint, 0, time.Since(start))
+		} else {
+			metrics.ObserveETAPIRequest(endpoint, resp.StatusCode, time.Since(start))
+		}
+
+		if attempt >= c.retry.maxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil, err
+			}
+			wait = c.retry.delay(attempt + 1)
+		case retryableStatus(resp.StatusCode):
+			wait = c.retry.delay(attempt + 1)
+			if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if d > c.retry.maxDelay {
+					return resp, nil
+				}
+				wait = d
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) doRequest(ctx context.Context, endpoint, url string, target interface{}) error {
+	resp, err := c.get(ctx, endpoint, url)
 	if err != nil {
+		var openErr *CircuitOpenError
+		if errors.As(err, &openErr) {
+			return err
+		}
 		return &RequestError{Err: err}
 	}
 	defer resp.Body.Close()
@@ -163,6 +397,10 @@ func (e *RequestError) Error() string {
 	return fmt.Sprintf("request failed: %v", e.Err)
 }
 
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
 type AuthError struct{}
 
 func (e *AuthError) Error() string {
@@ -0,0 +1,239 @@
+package etapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetNoteRetriesTransientStatus(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"noteId":"n1","title":"Recovered"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", WithRetry(2, time.Millisecond, 5*time.Millisecond))
+	note, err := client.GetNote("n1")
+	if err != nil {
+		t.Fatalf("expected retries to recover, got %v", err)
+	}
+	if note.Title != "Recovered" {
+		t.Fatalf("unexpected note: %#v", note)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestGetNoteDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", WithRetry(3, time.Millisecond, 5*time.Millisecond))
+	_, err := client.GetNote("missing")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 status error, got %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected a single attempt for 404, got %d", got)
+	}
+}
+
+func TestGetNoteGivesUpWhenRetryAfterExceedsCap(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", WithRetry(3, time.Millisecond, 50*time.Millisecond))
+	if _, err := client.GetNote("n1"); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected no retry past the Retry-After cap, got %d attempts", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 4, 13, 12, 0, 0, 0, time.UTC)
+	if d, ok := parseRetryAfter("3", now); !ok || d != 3*time.Second {
+		t.Fatalf("expected 3s, got %s (%v)", d, ok)
+	}
+	if d, ok := parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now); !ok || d != 10*time.Second {
+		t.Fatalf("expected 10s from HTTP date, got %s (%v)", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Fatalf("expected garbage header to be ignored")
+	}
+}
+
+func TestCircuitBreakerOpensAndHalfOpens(t *testing.T) {
+	var healthy atomic.Bool
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"noteId":"n1"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", WithCircuitBreaker(2, 30*time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetNote("n1"); err == nil {
+			t.Fatalf("expected upstream failure %d", i)
+		}
+	}
+	if st := client.BreakerStatus(); st.State != BreakerOpen {
+		t.Fatalf("expected breaker to open, got %#v", st)
+	}
+
+	_, err := client.GetNote("n1")
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("expected fail-fast circuit error, got %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expected open breaker to skip the upstream call, got %d calls", got)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	healthy.Store(true)
+	if _, err := client.GetNote("n1"); err != nil {
+		t.Fatalf("expected half-open probe to succeed, got %v", err)
+	}
+	if st := client.BreakerStatus(); st.State != BreakerClosed || st.ConsecutiveFailures != 0 {
+		t.Fatalf("expected breaker to close after probe, got %#v", st)
+	}
+}
+
+func TestCircuitBreakerCountsThrottlingAsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", WithCircuitBreaker(2, time.Minute))
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetNote("n1"); err == nil {
+			t.Fatalf("expected throttled request %d to fail", i)
+		}
+	}
+	if st := client.BreakerStatus(); st.State != BreakerOpen {
+		t.Fatalf("expected repeated 429s to open the breaker, got %#v", st)
+	}
+}
+
+func TestSearchNotesEncodesOptions(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		fmt.Fprint(w, `{"results":[{"noteId":"n1","parentNoteIds":["root"],"childNoteIds":["c1"],"isProtected":true}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token")
+	notes, err := client.SearchNotes(context.Background(), "#blog=true", SearchOptions{
+		OrderBy:        "title",
+		OrderDirection: "desc",
+		Limit:          5,
+		AncestorNoteID: "root",
+		FastSearch:     true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"search":         "#blog=true",
+		"orderBy":        "title",
+		"orderDirection": "desc",
+		"limit":          "5",
+		"ancestorNoteId": "root",
+		"fastSearch":     "true",
+	}
+	for k, v := range want {
+		if got := query.Get(k); got != v {
+			t.Fatalf("expected %s=%q, got %q", k, v, got)
+		}
+	}
+	if query.Has("includeArchivedNotes") || query.Has("ancestorDepth") {
+		t.Fatalf("expected unset options to be omitted, got %v", query)
+	}
+	if len(notes) != 1 || !notes[0].IsProtected || notes[0].ChildNoteIDs[0] != "c1" || notes[0].ParentNoteIDs[0] != "root" {
+		t.Fatalf("unexpected notes: %#v", notes)
+	}
+}
+
+func TestGetChildNotesFollowsNotePosition(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/etapi/notes/parent", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"noteId":"parent","childBranchIds":["b-late","b-early"]}`)
+	})
+	mux.HandleFunc("/etapi/branches/b-late", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"branchId":"b-late","noteId":"late","parentNoteId":"parent","notePosition":20}`)
+	})
+	mux.HandleFunc("/etapi/branches/b-early", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"branchId":"b-early","noteId":"early","parentNoteId":"parent","notePosition":10}`)
+	})
+	mux.HandleFunc("/etapi/notes/late", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"noteId":"late","title":"Late"}`)
+	})
+	mux.HandleFunc("/etapi/notes/early", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"noteId":"early","title":"Early"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(server.URL, "token")
+	notes, err := client.GetChildNotes(context.Background(), "parent")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(notes) != 2 || notes[0].NoteID != "early" || notes[1].NoteID != "late" {
+		t.Fatalf("expected children in notePosition order, got %#v", notes)
+	}
+}
+
+func TestGetNoteRevisionsAndContent(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/etapi/notes/n1/revisions", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"revisionId":"r1","noteId":"n1","title":"Old","contentLength":5}]`)
+	})
+	mux.HandleFunc("/etapi/revisions/r1/content", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<p>x</p>")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(server.URL, "token")
+	revs, err := client.GetNoteRevisions(context.Background(), "n1")
+	if err != nil || len(revs) != 1 || revs[0].Title != "Old" || revs[0].ContentLength != 5 {
+		t.Fatalf("unexpected revisions %#v (%v)", revs, err)
+	}
+	content, err := client.GetRevisionContent(context.Background(), "r1")
+	if err != nil || content != "<p>x</p>" {
+		t.Fatalf("unexpected revision content %q (%v)", content, err)
+	}
+}
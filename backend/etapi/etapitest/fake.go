@@ -0,0 +1,531 @@
+// Package etapitest provides an in-memory fake of the Trilium ETAPI for tests
+// and local development.
+//
+// The fake implements the read-only endpoints used by etapi.Client: notes and
+// their content, attachments, branches, revisions, attributes, app-info and
+// search (see Search for the supported query syntax). Faults such as latency,
+// 401s and 5xx responses can be injected at runtime.
+package etapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+// RootNoteID is the id of the tree root, as in a real Trilium instance.
+const RootNoteID = "root"
+
+const timeLayout = "2006-01-02 15:04:05.000-0700"
+
+// Fake is an in-memory Trilium. It is safe for concurrent use and implements
+// http.Handler, so it can be served by httptest.NewServer or http.Server.
+type Fake struct {
+	token string
+
+	mu          sync.Mutex
+	notes       map[string]*etapi.Note
+	content     map[string][]byte
+	branches    map[string]*etapi.Branch
+	attachments map[string]*attachment
+	revisions   map[string]*revision
+	latency     time.Duration
+	faults      []*Fault
+	requests    map[string]int
+	seq         int
+}
+
+type attachment struct {
+	meta etapi.Attachment
+	data []byte
+}
+
+type revision struct {
+	meta    etapi.Revision
+	content []byte
+}
+
+type Option func(*Fake)
+
+// WithToken makes the fake reject requests whose Authorization header is not
+// token. Without it every request is accepted.
+func WithToken(token string) Option {
+	return func(f *Fake) {
+		f.token = token
+	}
+}
+
+// WithLatency delays every response by d.
+func WithLatency(d time.Duration) Option {
+	return func(f *Fake) {
+		f.latency = d
+	}
+}
+
+func New(opts ...Option) *Fake {
+	f := &Fake{
+		notes:       make(map[string]*etapi.Note),
+		content:     make(map[string][]byte),
+		branches:    make(map[string]*etapi.Branch),
+		attachments: make(map[string]*attachment),
+		revisions:   make(map[string]*revision),
+		requests:    make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	f.notes[RootNoteID] = &etapi.Note{
+		NoteID: RootNoteID,
+		Title:  "root",
+		Type:   "text",
+		Mime:   "text/html",
+	}
+	return f
+}
+
+// NewServer starts an httptest.Server backed by f. Callers must Close it.
+func NewServer(f *Fake) *httptest.Server {
+	return httptest.NewServer(f)
+}
+
+// Label builds a label attribute for use in Note.Attributes.
+func Label(name, value string) etapi.Attribute {
+	return etapi.Attribute{Type: "label", Name: name, Value: value}
+}
+
+// Relation builds a relation attribute pointing at targetNoteID.
+func Relation(name, targetNoteID string) etapi.Attribute {
+	return etapi.Attribute{Type: "relation", Name: name, Value: targetNoteID}
+}
+
+// AddNote stores note with the given HTML content and places it under root,
+// or under each of note.ParentNoteIDs when set. Missing type, mime and dates
+// are filled in. Adding a note with an existing id replaces its metadata and
+// content but keeps its place in the tree.
+func (f *Fake) AddNote(note etapi.Note, content string) *etapi.Note {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if note.NoteID == "" {
+		note.NoteID = f.nextID("note")
+	}
+	if note.Type == "" {
+		note.Type = "text"
+	}
+	if note.Mime == "" {
+		note.Mime = "text/html"
+	}
+	now := time.Now()
+	if note.DateModified == "" {
+		note.DateModified = now.Format(timeLayout)
+	}
+	if note.UtcDateModified == "" {
+		note.UtcDateModified = utcDate(note.DateModified, now)
+	}
+	if note.DateCreated == "" {
+		note.DateCreated = note.DateModified
+	}
+	if note.UtcDateCreated == "" {
+		note.UtcDateCreated = utcDate(note.DateCreated, now)
+	}
+	for i := range note.Attributes {
+		attr := &note.Attributes[i]
+		attr.NoteID = note.NoteID
+		if attr.AttributeID == "" {
+			attr.AttributeID = f.nextID("attr")
+		}
+		attr.Position = (i + 1) * 10
+	}
+
+	parents := note.ParentNoteIDs
+	note.ParentNoteIDs, note.ParentBranchIDs = nil, nil
+	if existing, ok := f.notes[note.NoteID]; ok {
+		note.ParentNoteIDs = existing.ParentNoteIDs
+		note.ParentBranchIDs = existing.ParentBranchIDs
+		note.ChildNoteIDs = existing.ChildNoteIDs
+		note.ChildBranchIDs = existing.ChildBranchIDs
+		parents = nil
+	} else {
+		note.ChildNoteIDs, note.ChildBranchIDs = nil, nil
+		if len(parents) == 0 {
+			parents = []string{RootNoteID}
+		}
+	}
+
+	stored := note
+	f.notes[note.NoteID] = &stored
+	f.content[note.NoteID] = []byte(content)
+	for _, parentID := range parents {
+		f.addBranchLocked(parentID, note.NoteID)
+	}
+	return cloneNote(&stored)
+}
+
+// AddChild clones an existing note under another parent and returns the new
+// branch id. Children are ordered by insertion.
+func (f *Fake) AddChild(parentID, childID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.notes[parentID]; !ok {
+		return "", fmt.Errorf("etapitest: unknown parent note %q", parentID)
+	}
+	if _, ok := f.notes[childID]; !ok {
+		return "", fmt.Errorf("etapitest: unknown child note %q", childID)
+	}
+	return f.addBranchLocked(parentID, childID), nil
+}
+
+func (f *Fake) addBranchLocked(parentID, childID string) string {
+	parent, ok := f.notes[parentID]
+	if !ok {
+		parent = &etapi.Note{NoteID: parentID, Title: parentID, Type: "text", Mime: "text/html"}
+		f.notes[parentID] = parent
+		f.content[parentID] = nil
+	}
+	child := f.notes[childID]
+
+	branchID := parentID + "_" + childID
+	f.branches[branchID] = &etapi.Branch{
+		BranchID:        branchID,
+		NoteID:          childID,
+		ParentNoteID:    parentID,
+		NotePosition:    (len(parent.ChildBranchIDs) + 1) * 10,
+		UtcDateModified: child.UtcDateModified,
+	}
+	parent.ChildNoteIDs = append(parent.ChildNoteIDs, childID)
+	parent.ChildBranchIDs = append(parent.ChildBranchIDs, branchID)
+	child.ParentNoteIDs = append(child.ParentNoteIDs, parentID)
+	child.ParentBranchIDs = append(child.ParentBranchIDs, branchID)
+	return branchID
+}
+
+// SetContent replaces a note's content without touching its metadata.
+func (f *Fake) SetContent(noteID, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.content[noteID] = []byte(content)
+}
+
+// AddAttachment stores an attachment owned by ownerID and returns its id.
+func (f *Fake) AddAttachment(ownerID, title, mime string, data []byte) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := f.nextID("att")
+	f.attachments[id] = &attachment{
+		meta: etapi.Attachment{
+			AttachmentID:    id,
+			OwnerID:         ownerID,
+			Role:            "image",
+			Mime:            mime,
+			Title:           title,
+			Position:        (len(f.attachmentsOfLocked(ownerID)) + 1) * 10,
+			UtcDateModified: time.Now().UTC().Format(timeLayout),
+			ContentLength:   len(data),
+		},
+		data: append([]byte(nil), data...),
+	}
+	if !strings.HasPrefix(mime, "image/") {
+		f.attachments[id].meta.Role = "file"
+	}
+	return id
+}
+
+// AddRevision snapshots a note's current title and content as a revision and
+// returns its id.
+func (f *Fake) AddRevision(noteID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	note, ok := f.notes[noteID]
+	if !ok {
+		return "", fmt.Errorf("etapitest: unknown note %q", noteID)
+	}
+	id := f.nextID("rev")
+	content := append([]byte(nil), f.content[noteID]...)
+	f.revisions[id] = &revision{
+		meta: etapi.Revision{
+			RevisionID:        id,
+			NoteID:            noteID,
+			Type:              note.Type,
+			Mime:              note.Mime,
+			Title:             note.Title,
+			DateLastEdited:    note.DateModified,
+			UtcDateLastEdited: note.UtcDateModified,
+			UtcDateCreated:    time.Now().UTC().Format(timeLayout),
+			ContentLength:     len(content),
+		},
+		content: content,
+	}
+	return id, nil
+}
+
+// Requests returns how many requests reached path, e.g.
+// "/etapi/notes/abc/content". Requests rejected by auth or faults count too.
+func (f *Fake) Requests(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+// NoteCount returns the number of stored notes, not counting root.
+func (f *Fake) NoteCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.notes) - 1
+}
+
+// ResetRequests clears the request counters.
+func (f *Fake) ResetRequests() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = make(map[string]int)
+}
+
+func (f *Fake) nextID(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s%d", prefix, f.seq)
+}
+
+func (f *Fake) attachmentsOfLocked(ownerID string) []etapi.Attachment {
+	var out []etapi.Attachment
+	for _, a := range f.attachments {
+		if a.meta.OwnerID == ownerID {
+			out = append(out, a.meta)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Position < out[j].Position
+	})
+	return out
+}
+
+func (f *Fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests[r.URL.Path]++
+	latency := f.latency
+	fault := f.matchFaultLocked(r.URL.Path)
+	f.mu.Unlock()
+
+	if fault != nil && fault.Latency > 0 {
+		latency = fault.Latency
+	}
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fault != nil && fault.Status != 0 {
+		if fault.RetryAfter != "" {
+			w.Header().Set("Retry-After", fault.RetryAfter)
+		}
+		writeError(w, fault.Status, "FAULT_INJECTED", "injected by etapitest")
+		return
+	}
+	if f.token != "" && r.Header.Get("Authorization") != f.token {
+		writeError(w, http.StatusUnauthorized, "NOT_AUTHENTICATED", "Not authenticated")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "etapitest only serves GET requests")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/etapi")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "notes":
+		f.serveSearch(w, r)
+	case len(parts) == 1 && parts[0] == "app-info":
+		writeJSON(w, etapi.AppInfo{
+			AppVersion:             "0.0.0-etapitest",
+			DBVersion:              1,
+			SyncVersion:            1,
+			ClipperProtocolVersion: "1.0",
+			UtcDateTime:            time.Now().UTC().Format(time.RFC3339),
+		})
+	case len(parts) >= 2 && parts[0] == "notes":
+		f.serveNote(w, parts[1], parts[2:])
+	case len(parts) >= 2 && parts[0] == "attachments":
+		f.serveAttachment(w, parts[1], parts[2:])
+	case len(parts) == 2 && parts[0] == "branches":
+		f.mu.Lock()
+		branch, ok := f.branches[parts[1]]
+		var out etapi.Branch
+		if ok {
+			out = *branch
+		}
+		f.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "BRANCH_NOT_FOUND", fmt.Sprintf("Branch '%s' not found.", parts[1]))
+			return
+		}
+		writeJSON(w, out)
+	case len(parts) >= 2 && parts[0] == "revisions":
+		f.serveRevision(w, parts[1], parts[2:])
+	case len(parts) == 2 && parts[0] == "attributes":
+		f.serveAttribute(w, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "Not found")
+	}
+}
+
+func (f *Fake) serveNote(w http.ResponseWriter, noteID string, rest []string) {
+	f.mu.Lock()
+	note, ok := f.notes[noteID]
+	var (
+		out         *etapi.Note
+		content     []byte
+		attachments []etapi.Attachment
+		revisions   []etapi.Revision
+	)
+	if ok {
+		out = cloneNote(note)
+		content = f.content[noteID]
+		attachments = f.attachmentsOfLocked(noteID)
+		for _, rev := range f.revisions {
+			if rev.meta.NoteID == noteID {
+				revisions = append(revisions, rev.meta)
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOTE_NOT_FOUND", fmt.Sprintf("Note '%s' not found.", noteID))
+		return
+	}
+	switch strings.Join(rest, "/") {
+	case "":
+		writeJSON(w, out)
+	case "content":
+		w.Header().Set("Content-Type", out.Mime+"; charset=utf-8")
+		_, _ = w.Write(content)
+	case "attachments":
+		if attachments == nil {
+			attachments = []etapi.Attachment{}
+		}
+		writeJSON(w, attachments)
+	case "revisions":
+		sort.Slice(revisions, func(i, j int) bool {
+			return revisions[i].RevisionID < revisions[j].RevisionID
+		})
+		if revisions == nil {
+			revisions = []etapi.Revision{}
+		}
+		writeJSON(w, revisions)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "Not found")
+	}
+}
+
+func (f *Fake) serveAttachment(w http.ResponseWriter, attachmentID string, rest []string) {
+	f.mu.Lock()
+	att, ok := f.attachments[attachmentID]
+	var meta etapi.Attachment
+	var data []byte
+	if ok {
+		meta, data = att.meta, att.data
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "ATTACHMENT_NOT_FOUND", fmt.Sprintf("Attachment '%s' not found.", attachmentID))
+		return
+	}
+	switch strings.Join(rest, "/") {
+	case "":
+		writeJSON(w, meta)
+	case "content":
+		w.Header().Set("Content-Type", meta.Mime)
+		_, _ = w.Write(data)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "Not found")
+	}
+}
+
+func (f *Fake) serveRevision(w http.ResponseWriter, revisionID string, rest []string) {
+	f.mu.Lock()
+	rev, ok := f.revisions[revisionID]
+	var meta etapi.Revision
+	var content []byte
+	if ok {
+		meta, content = rev.meta, rev.content
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "REVISION_NOT_FOUND", fmt.Sprintf("Revision '%s' not found.", revisionID))
+		return
+	}
+	switch strings.Join(rest, "/") {
+	case "":
+		writeJSON(w, meta)
+	case "content":
+		w.Header().Set("Content-Type", meta.Mime+"; charset=utf-8")
+		_, _ = w.Write(content)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "Not found")
+	}
+}
+
+func (f *Fake) serveAttribute(w http.ResponseWriter, attributeID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, note := range f.notes {
+		for _, attr := range note.Attributes {
+			if attr.AttributeID == attributeID {
+				writeJSON(w, attr)
+				return
+			}
+		}
+	}
+	writeError(w, http.StatusNotFound, "ATTRIBUTE_NOT_FOUND", fmt.Sprintf("Attribute '%s' not found.", attributeID))
+}
+
+func cloneNote(n *etapi.Note) *etapi.Note {
+	out := *n
+	out.Attributes = append([]etapi.Attribute(nil), n.Attributes...)
+	out.ParentNoteIDs = append([]string(nil), n.ParentNoteIDs...)
+	out.ParentBranchIDs = append([]string(nil), n.ParentBranchIDs...)
+	out.ChildNoteIDs = append([]string(nil), n.ChildNoteIDs...)
+	out.ChildBranchIDs = append([]string(nil), n.ChildBranchIDs...)
+	return &out
+}
+
+// utcDate converts a Trilium local timestamp (or RFC 3339) to the UTC form
+// Trilium reports in utcDate* fields, falling back to now.
+func utcDate(value string, now time.Time) string {
+	for _, layout := range []string{timeLayout, time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format("2006-01-02 15:04:05.000Z")
+		}
+	}
+	return now.UTC().Format("2006-01-02 15:04:05.000Z")
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError mirrors the error body Trilium returns from ETAPI.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":  status,
+		"code":    code,
+		"message": message,
+	})
+}
@@ -0,0 +1,151 @@
+package etapitest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+func newDemoClient(t *testing.T, opts ...Option) (*Fake, *etapi.Client) {
+	t.Helper()
+	fake := New(opts...)
+	if err := fake.Load(Demo()); err != nil {
+		t.Fatalf("load demo: %v", err)
+	}
+	server := NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, etapi.NewClient(server.URL, "dev")
+}
+
+func noteIDs(notes []etapi.Note) []string {
+	ids := make([]string, 0, len(notes))
+	for _, n := range notes {
+		ids = append(ids, n.NoteID)
+	}
+	return ids
+}
+
+func TestSearchSyntax(t *testing.T) {
+	fake := New()
+	fake.AddNote(etapi.Note{NoteID: "a", Title: "Alpha", Attributes: []etapi.Attribute{Label("blog", "true"), Label("lang", "en")}}, "<p>golang tips</p>")
+	fake.AddNote(etapi.Note{NoteID: "b", Title: "Beta", Attributes: []etapi.Attribute{Label("blog", "false")}}, "<p>rust</p>")
+	fake.AddNote(etapi.Note{NoteID: "c", Title: "Gamma", Attributes: []etapi.Attribute{Label("blog", "true"), Label("archived", "")}}, "")
+	fake.AddNote(etapi.Note{NoteID: "d", Title: "Delta"}, "<p>golang</p>")
+
+	tests := []struct {
+		query string
+		opts  etapi.SearchOptions
+		want  []string
+	}{
+		{query: "#blog=true", want: []string{"a"}},
+		{query: "#blog=true", opts: etapi.SearchOptions{IncludeArchivedNotes: true}, want: []string{"a", "c"}},
+		{query: "#blog", want: []string{"a", "b"}},
+		{query: "#!blog", want: []string{"d"}},
+		{query: "#blog!=true", want: []string{"b"}},
+		{query: `#lang="en"`, want: []string{"a"}},
+		{query: "#blog=*tr", want: []string{"a"}},
+		{query: "golang", want: []string{"a", "d"}},
+		{query: "golang", opts: etapi.SearchOptions{FastSearch: true}, want: nil},
+		{query: "golang #blog", want: []string{"a"}},
+		{query: "#blog", opts: etapi.SearchOptions{OrderBy: "title", OrderDirection: "desc", Limit: 1}, want: []string{"b"}},
+	}
+	for _, tt := range tests {
+		got := noteIDs(fake.Search(tt.query, tt.opts))
+		if len(got) != len(tt.want) {
+			t.Fatalf("%q %+v: expected %v, got %v", tt.query, tt.opts, tt.want, got)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%q %+v: expected %v, got %v", tt.query, tt.opts, tt.want, got)
+			}
+		}
+	}
+}
+
+func TestClientAgainstDemo(t *testing.T) {
+	fake, client := newDemoClient(t)
+	ctx := context.Background()
+
+	posts, err := client.GetNotes("#blog=true")
+	if err != nil {
+		t.Fatalf("GetNotes: %v", err)
+	}
+	if len(posts) != 5 {
+		t.Fatalf("expected 5 demo posts, got %v", noteIDs(posts))
+	}
+
+	children, err := client.GetChildNotes(ctx, "series")
+	if err != nil {
+		t.Fatalf("GetChildNotes: %v", err)
+	}
+	if ids := noteIDs(children); len(ids) != 2 || ids[0] != "series-1" || ids[1] != "series-2" {
+		t.Fatalf("unexpected children %v", ids)
+	}
+
+	scoped, err := client.SearchNotes(ctx, "#blog", etapi.SearchOptions{AncestorNoteID: "series"})
+	if err != nil || len(scoped) != 2 {
+		t.Fatalf("expected ancestor-scoped search to return the chapters, got %v (%v)", noteIDs(scoped), err)
+	}
+
+	att, err := client.GetAttachment("demo-pixel")
+	if err != nil || att.OwnerID != "code-and-images" || att.Mime != "image/png" {
+		t.Fatalf("unexpected attachment %#v (%v)", att, err)
+	}
+	data, err := client.GetAttachmentContentBytes("demo-pixel")
+	if err != nil || len(data) == 0 {
+		t.Fatalf("expected attachment bytes, got %d (%v)", len(data), err)
+	}
+
+	if _, err := client.GetNote("missing"); err == nil {
+		t.Fatalf("expected 404 for unknown note")
+	}
+	if got := fake.Requests("/etapi/notes/missing"); got != 1 {
+		t.Fatalf("expected request to be counted, got %d", got)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	fake, client := newDemoClient(t, WithToken("dev"))
+
+	fake.FailNext(1, http.StatusServiceUnavailable)
+	var statusErr *etapi.StatusError
+	if _, err := client.GetNote("welcome"); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected injected 503, got %v", err)
+	}
+	if _, err := client.GetNote("welcome"); err != nil {
+		t.Fatalf("expected fault to be used up, got %v", err)
+	}
+
+	fake.InjectFault(Fault{PathPrefix: "/etapi/notes/welcome", Status: http.StatusUnauthorized})
+	var authErr *etapi.AuthError
+	if _, err := client.GetNote("welcome"); !errors.As(err, &authErr) {
+		t.Fatalf("expected injected 401, got %v", err)
+	}
+	if _, err := client.GetNote("series"); err != nil {
+		t.Fatalf("expected other paths to be unaffected, got %v", err)
+	}
+	fake.ClearFaults()
+
+	fake.SetLatency(200 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetNoteContext(ctx, "welcome"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected latency to trip the deadline, got %v", err)
+	}
+}
+
+func TestTokenIsEnforced(t *testing.T) {
+	fake := New(WithToken("secret"))
+	server := NewServer(fake)
+	defer server.Close()
+
+	_, err := etapi.NewClient(server.URL, "wrong").GetNotes("#blog")
+	var authErr *etapi.AuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+}
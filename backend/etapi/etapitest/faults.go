@@ -0,0 +1,67 @@
+package etapitest
+
+import (
+	"strings"
+	"time"
+)
+
+// Fault makes matching requests misbehave. A fault with Status set answers
+// with that status instead of the real response; Latency alone only slows the
+// request down.
+type Fault struct {
+	// PathPrefix limits the fault to request paths starting with it, e.g.
+	// "/etapi/notes/abc/content". Empty matches every request.
+	PathPrefix string
+	// Status is the HTTP status to answer with, e.g. 401 or 503.
+	Status int
+	// RetryAfter, when set, is sent as the Retry-After header.
+	RetryAfter string
+	// Latency replaces the fake's default latency for matching requests.
+	Latency time.Duration
+	// Times is how many requests the fault applies to. Zero means until
+	// ClearFaults is called.
+	Times int
+}
+
+// InjectFault adds a fault. When several faults match a request the oldest
+// one wins.
+func (f *Fake) InjectFault(fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = append(f.faults, &fault)
+}
+
+// FailNext answers the next n requests with status.
+func (f *Fake) FailNext(n, status int) {
+	f.InjectFault(Fault{Status: status, Times: n})
+}
+
+// SetLatency changes the delay applied to every response.
+func (f *Fake) SetLatency(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency = d
+}
+
+func (f *Fake) ClearFaults() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = nil
+}
+
+func (f *Fake) matchFaultLocked(path string) *Fault {
+	for i, fault := range f.faults {
+		if !strings.HasPrefix(path, fault.PathPrefix) {
+			continue
+		}
+		matched := *fault
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				f.faults = append(f.faults[:i:i], f.faults[i+1:]...)
+			}
+		}
+		return &matched
+	}
+	return nil
+}
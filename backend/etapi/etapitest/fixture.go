@@ -0,0 +1,176 @@
+package etapitest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+// Fixture describes a note tree in a form that is easy to write by hand:
+//
+//	{"notes": [{
+//	  "noteId": "hello", "title": "Hello", "labels": {"blog": "true"},
+//	  "content": "<p>Hi</p>",
+//	  "attachments": [{"id": "img1", "title": "a.png", "mime": "image/png", "base64": "..."}],
+//	  "children": [...]
+//	}]}
+//
+// Attachments get the given id when set so that content can reference
+// api/attachments/<id>/image/<name>; otherwise one is generated.
+type Fixture struct {
+	Notes []FixtureNote `json:"notes"`
+}
+
+type FixtureNote struct {
+	NoteID       string              `json:"noteId"`
+	Title        string              `json:"title"`
+	Type         string              `json:"type,omitempty"`
+	Mime         string              `json:"mime,omitempty"`
+	DateCreated  string              `json:"dateCreated,omitempty"`
+	DateModified string              `json:"dateModified,omitempty"`
+	Labels       map[string]string   `json:"labels,omitempty"`
+	Relations    map[string]string   `json:"relations,omitempty"`
+	Content      string              `json:"content,omitempty"`
+	Attachments  []FixtureAttachment `json:"attachments,omitempty"`
+	Children     []FixtureNote       `json:"children,omitempty"`
+}
+
+type FixtureAttachment struct {
+	ID     string `json:"id,omitempty"`
+	Title  string `json:"title"`
+	Mime   string `json:"mime"`
+	Text   string `json:"text,omitempty"`
+	Base64 string `json:"base64,omitempty"`
+}
+
+// ReadFixture decodes a JSON fixture.
+func ReadFixture(r io.Reader) (*Fixture, error) {
+	var fx Fixture
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fx); err != nil {
+		return nil, fmt.Errorf("etapitest: decode fixture: %w", err)
+	}
+	return &fx, nil
+}
+
+// Load adds every note in fx under root.
+func (f *Fake) Load(fx *Fixture) error {
+	for _, n := range fx.Notes {
+		if err := f.loadNote(RootNoteID, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fake) loadNote(parentID string, n FixtureNote) error {
+	note := etapi.Note{
+		NoteID:        n.NoteID,
+		Title:         n.Title,
+		Type:          n.Type,
+		Mime:          n.Mime,
+		DateCreated:   n.DateCreated,
+		DateModified:  n.DateModified,
+		ParentNoteIDs: []string{parentID},
+	}
+	for _, name := range sortedKeys(n.Labels) {
+		note.Attributes = append(note.Attributes, Label(name, n.Labels[name]))
+	}
+	for _, name := range sortedKeys(n.Relations) {
+		note.Attributes = append(note.Attributes, Relation(name, n.Relations[name]))
+	}
+	added := f.AddNote(note, n.Content)
+
+	for _, a := range n.Attachments {
+		data := []byte(a.Text)
+		if a.Base64 != "" {
+			decoded, err := base64.StdEncoding.DecodeString(a.Base64)
+			if err != nil {
+				return fmt.Errorf("etapitest: attachment %q of note %q: %w", a.Title, added.NoteID, err)
+			}
+			data = decoded
+		}
+		id := f.AddAttachment(added.NoteID, a.Title, a.Mime, data)
+		if a.ID != "" {
+			f.renameAttachment(id, a.ID)
+		}
+	}
+	for _, child := range n.Children {
+		if err := f.loadNote(added.NoteID, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fake) renameAttachment(from, to string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	att := f.attachments[from]
+	delete(f.attachments, from)
+	att.meta.AttachmentID = to
+	f.attachments[to] = att
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// demoPixel is a 1x1 transparent PNG.
+const demoPixel = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII="
+
+// Demo returns a small blog: a few posts (one featured, one with code and an
+// image, one private) enough to click through the frontend.
+func Demo() *Fixture {
+	return &Fixture{Notes: []FixtureNote{
+		{
+			NoteID:       "welcome",
+			Title:        "Welcome to the demo blog",
+			DateCreated:  "2026-01-05 09:00:00.000+0000",
+			DateModified: "2026-01-05 09:30:00.000+0000",
+			Labels:       map[string]string{"blog": "true", "blogtop": "true"},
+			Content: "<h2>Hello</h2><p>This post is served by the etapitest fake Trilium. " +
+				"Edit <code>etapi/etapitest</code> fixtures to change what you see.</p>" +
+				"<h2>Next steps</h2><p>Open another post from the list.</p>",
+		},
+		{
+			NoteID:       "code-and-images",
+			Title:        "Code blocks and images",
+			DateCreated:  "2026-02-10 18:00:00.000+0000",
+			DateModified: "2026-02-11 08:15:00.000+0000",
+			Labels:       map[string]string{"blog": "true"},
+			Content: "<p>A snippet:</p><pre><code class=\"language-go\">package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n</code></pre>" +
+				"<p>An attachment:</p><p><img src=\"api/attachments/demo-pixel/image/pixel.png\" alt=\"pixel\"></p>",
+			Attachments: []FixtureAttachment{{ID: "demo-pixel", Title: "pixel.png", Mime: "image/png", Base64: demoPixel}},
+		},
+		{
+			NoteID:       "series",
+			Title:        "A series with chapters",
+			DateCreated:  "2026-03-01 12:00:00.000+0000",
+			DateModified: "2026-03-02 12:00:00.000+0000",
+			Labels:       map[string]string{"blog": "true"},
+			Content:      "<p>" + strings.Repeat("Long form text for the summary and search. ", 20) + "</p>",
+			Children: []FixtureNote{
+				{NoteID: "series-1", Title: "Chapter 1", Labels: map[string]string{"blog": "true"}, Content: "<p>First chapter.</p>", DateModified: "2026-03-03 12:00:00.000+0000"},
+				{NoteID: "series-2", Title: "Chapter 2", Labels: map[string]string{"blog": "true"}, Content: "<p>Second chapter.</p>", DateModified: "2026-03-04 12:00:00.000+0000"},
+			},
+		},
+		{
+			NoteID:       "private",
+			Title:        "Private scratchpad",
+			DateModified: "2026-03-05 12:00:00.000+0000",
+			Content:      "<p>Not labelled #blog, so it must never show up.</p>",
+		},
+	}}
+}
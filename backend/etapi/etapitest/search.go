@@ -0,0 +1,288 @@
+package etapitest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+// A subset of Trilium's search syntax, enough for the queries the blog
+// issues. Space separated terms are ANDed:
+//
+//	#name            note has label name
+//	#!name           note does not have label name
+//	#name=value      label equals value (also !=, =* starts with,
+//	                 *= ends with, *=* contains)
+//	~name=noteId     relation name points at noteId
+//	word / "a b"     title or content contains the text (title only with
+//	                 fastSearch)
+//
+// Label names and full-text terms are matched case-insensitively. Notes
+// labelled #archived are skipped unless includeArchivedNotes is set.
+type searchTerm struct {
+	attrType string
+	name     string
+	op       string
+	value    string
+	negate   bool
+	text     string
+}
+
+func parseSearch(query string) []searchTerm {
+	var terms []searchTerm
+	for _, tok := range tokenize(query) {
+		switch {
+		case strings.HasPrefix(tok, "#") || strings.HasPrefix(tok, "~"):
+			term := searchTerm{attrType: "label"}
+			if tok[0] == '~' {
+				term.attrType = "relation"
+			}
+			body := tok[1:]
+			if strings.HasPrefix(body, "!") {
+				term.negate = true
+				body = body[1:]
+			}
+			term.name = body
+			for _, op := range []string{"*=*", "!=", "=*", "*=", "="} {
+				if idx := strings.Index(body, op); idx > 0 {
+					term.name = body[:idx]
+					term.op = op
+					term.value = unquote(body[idx+len(op):])
+					break
+				}
+			}
+			terms = append(terms, term)
+		default:
+			terms = append(terms, searchTerm{text: strings.ToLower(unquote(tok))})
+		}
+	}
+	return terms
+}
+
+// tokenize splits on whitespace outside of double or single quotes.
+func tokenize(query string) []string {
+	var (
+		tokens []string
+		cur    strings.Builder
+		quote  rune
+	)
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			cur.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			cur.WriteRune(r)
+		case r == ' ' || r == '\t' || r == '\n':
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func (t searchTerm) matches(note *etapi.Note, content []byte, fastSearch bool) bool {
+	if t.text != "" {
+		if strings.Contains(strings.ToLower(note.Title), t.text) {
+			return true
+		}
+		return !fastSearch && strings.Contains(strings.ToLower(string(content)), t.text)
+	}
+
+	found := false
+	for _, attr := range note.Attributes {
+		if attr.Type != t.attrType || !strings.EqualFold(attr.Name, t.name) {
+			continue
+		}
+		if t.valueMatches(attr.Value) {
+			found = true
+			break
+		}
+	}
+	if t.op == "!=" {
+		// #name!=value is true for notes with a different value but, as in
+		// Trilium, not for notes without the label at all.
+		return hasAttr(note, t.attrType, t.name) && !found
+	}
+	return found != t.negate
+}
+
+func (t searchTerm) valueMatches(value string) bool {
+	v := strings.ToLower(value)
+	want := strings.ToLower(t.value)
+	switch t.op {
+	case "":
+		return true
+	case "=":
+		return v == want
+	case "!=":
+		return v == want
+	case "=*":
+		return strings.HasPrefix(v, want)
+	case "*=":
+		return strings.HasSuffix(v, want)
+	case "*=*":
+		return strings.Contains(v, want)
+	}
+	return false
+}
+
+func hasAttr(note *etapi.Note, attrType, name string) bool {
+	for _, attr := range note.Attributes {
+		if attr.Type == attrType && strings.EqualFold(attr.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Search runs query against the fake's notes with the same semantics as
+// GET /etapi/notes. It is exported so that tests can check fixtures without
+// going through HTTP.
+func (f *Fake) Search(query string, opts etapi.SearchOptions) []etapi.Note {
+	terms := parseSearch(query)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var scope map[string]bool
+	if opts.AncestorNoteID != "" && opts.AncestorNoteID != RootNoteID {
+		scope = f.descendantsLocked(opts.AncestorNoteID, parseDepth(opts.AncestorDepth))
+	}
+
+	var results []etapi.Note
+	for id, note := range f.notes {
+		if id == RootNoteID {
+			continue
+		}
+		if scope != nil && !scope[id] {
+			continue
+		}
+		if !opts.IncludeArchivedNotes && hasAttr(note, "label", "archived") {
+			continue
+		}
+		ok := true
+		for _, term := range terms {
+			if !term.matches(note, f.content[id], opts.FastSearch) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			results = append(results, *cloneNote(note))
+		}
+	}
+
+	sortNotes(results, opts.OrderBy, opts.OrderDirection)
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	return results
+}
+
+// descendantsLocked returns the notes below ancestorID, at most maxDepth
+// levels down (0 means unlimited).
+func (f *Fake) descendantsLocked(ancestorID string, maxDepth int) map[string]bool {
+	seen := make(map[string]bool)
+	frontier := []string{ancestorID}
+	for depth := 1; len(frontier) > 0 && (maxDepth == 0 || depth <= maxDepth); depth++ {
+		var next []string
+		for _, id := range frontier {
+			note, ok := f.notes[id]
+			if !ok {
+				continue
+			}
+			for _, child := range note.ChildNoteIDs {
+				if !seen[child] {
+					seen[child] = true
+					next = append(next, child)
+				}
+			}
+		}
+		frontier = next
+	}
+	return seen
+}
+
+// parseDepth understands the "ltN" / "eqN" forms Trilium accepts for
+// ancestorDepth; only the upper bound is honoured.
+func parseDepth(value string) int {
+	if len(value) < 3 {
+		return 0
+	}
+	n, err := strconv.Atoi(value[2:])
+	if err != nil || n <= 0 {
+		return 0
+	}
+	if strings.HasPrefix(value, "lt") {
+		return n - 1
+	}
+	return n
+}
+
+func sortNotes(notes []etapi.Note, orderBy, direction string) {
+	key := func(n etapi.Note) string {
+		switch orderBy {
+		case "title":
+			return strings.ToLower(n.Title)
+		case "dateCreated", "utcDateCreated":
+			return n.UtcDateCreated
+		case "dateModified", "utcDateModified":
+			return n.UtcDateModified
+		}
+		return n.NoteID
+	}
+	desc := strings.EqualFold(direction, "desc")
+	sort.SliceStable(notes, func(i, j int) bool {
+		a, b := key(notes[i]), key(notes[j])
+		if a == b {
+			return notes[i].NoteID < notes[j].NoteID
+		}
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+}
+
+func (f *Fake) serveSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !q.Has("search") {
+		writeError(w, http.StatusBadRequest, "SEARCH_QUERY_PARAM_MANDATORY", "'search' query parameter is mandatory.")
+		return
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	results := f.Search(q.Get("search"), etapi.SearchOptions{
+		OrderBy:              q.Get("orderBy"),
+		OrderDirection:       q.Get("orderDirection"),
+		Limit:                limit,
+		AncestorNoteID:       q.Get("ancestorNoteId"),
+		AncestorDepth:        q.Get("ancestorDepth"),
+		FastSearch:           q.Get("fastSearch") == "true",
+		IncludeArchivedNotes: q.Get("includeArchivedNotes") == "true",
+	})
+	if results == nil {
+		results = []etapi.Note{}
+	}
+	writeJSON(w, etapi.NotesResponse{Results: results})
+}
@@ -0,0 +1,202 @@
+package etapi
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// retryableStatus reports whether a response status is worth retrying.
+// These are the codes Trilium or a reverse proxy in front of it return
+// while restarting or shedding load.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay returns the wait before the given retry attempt (1-based), using
+// full jitter over an exponentially growing window capped at maxDelay.
+func (p retryPolicy) delay(attempt int) time.Duration {
+	window := p.baseDelay << (attempt - 1)
+	if window <= 0 || window > p.maxDelay {
+		window = p.maxDelay
+	}
+	if window <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(window) + 1))
+}
+
+// parseRetryAfter understands both forms of the Retry-After header: a number
+// of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerStatus is a snapshot of the circuit breaker for health and admin
+// reporting.
+type BreakerStatus struct {
+	Enabled             bool   `json:"enabled"`
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	Threshold           int    `json:"threshold"`
+	OpenedAt            string `json:"openedAt,omitempty"`
+	RetryAt             string `json:"retryAt,omitempty"`
+}
+
+// circuitBreaker fails calls fast after threshold consecutive failures. Once
+// cooldown has passed it lets a single probe through; the probe's outcome
+// closes the breaker again or restarts the cooldown.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(state string)
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+func (b *circuitBreaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		retryAt := b.openedAt.Add(b.cooldown)
+		if now.Before(retryAt) {
+			return &CircuitOpenError{RetryAfter: retryAt.Sub(now)}
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return &CircuitOpenError{}
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+func (b *circuitBreaker) record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.setState(BreakerClosed)
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		b.setState(BreakerOpen)
+	}
+}
+
+// release gives up a half-open probe slot without recording an outcome.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *circuitBreaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
+
+func (b *circuitBreaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := BreakerStatus{
+		Enabled:             true,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Threshold:           b.threshold,
+	}
+	if b.state != BreakerClosed && !b.openedAt.IsZero() {
+		st.OpenedAt = b.openedAt.UTC().Format(time.RFC3339)
+		st.RetryAt = b.openedAt.Add(b.cooldown).UTC().Format(time.RFC3339)
+	}
+	return st
+}
+
+type CircuitOpenError struct {
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("trilium circuit breaker is open; retrying in %s", e.RetryAfter.Round(time.Second))
+	}
+	return "trilium circuit breaker is open"
+}
@@ -0,0 +1,127 @@
+package etapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Branch places a note under a parent in the note tree. A note with several
+// parents (a clone) has one branch per parent.
+type Branch struct {
+	BranchID        string `json:"branchId"`
+	NoteID          string `json:"noteId"`
+	ParentNoteID    string `json:"parentNoteId"`
+	Prefix          string `json:"prefix,omitempty"`
+	NotePosition    int    `json:"notePosition"`
+	IsExpanded      bool   `json:"isExpanded,omitempty"`
+	UtcDateModified string `json:"utcDateModified,omitempty"`
+}
+
+func (c *Client) GetBranch(ctx context.Context, branchID string) (*Branch, error) {
+	url := fmt.Sprintf("%s/etapi/branches/%s", c.baseURL, branchID)
+	var branch Branch
+	if err := c.doRequest(ctx, "branches.get", url, &branch); err != nil {
+		return nil, err
+	}
+	return &branch, nil
+}
+
+// GetParentBranches returns the branches that place noteID in the tree.
+func (c *Client) GetParentBranches(ctx context.Context, noteID string) ([]Branch, error) {
+	note, err := c.GetNoteContext(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	return c.getBranches(ctx, note.ParentBranchIDs)
+}
+
+// GetChildBranches returns the branches under noteID in tree order.
+func (c *Client) GetChildBranches(ctx context.Context, noteID string) ([]Branch, error) {
+	note, err := c.GetNoteContext(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	branches, err := c.getBranches(ctx, note.ChildBranchIDs)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].NotePosition < branches[j].NotePosition
+	})
+	return branches, nil
+}
+
+// GetChildNotes returns the direct children of noteID in tree order.
+func (c *Client) GetChildNotes(ctx context.Context, noteID string) ([]Note, error) {
+	branches, err := c.GetChildBranches(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	notes := make([]Note, 0, len(branches))
+	for _, b := range branches {
+		note, err := c.GetNoteContext(ctx, b.NoteID)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, *note)
+	}
+	return notes, nil
+}
+
+func (c *Client) getBranches(ctx context.Context, branchIDs []string) ([]Branch, error) {
+	branches := make([]Branch, 0, len(branchIDs))
+	for _, id := range branchIDs {
+		branch, err := c.GetBranch(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, *branch)
+	}
+	return branches, nil
+}
+
+// Revision is a snapshot of a note's title and content at some point in the
+// past.
+type Revision struct {
+	RevisionID        string `json:"revisionId"`
+	NoteID            string `json:"noteId"`
+	Type              string `json:"type"`
+	Mime              string `json:"mime"`
+	IsProtected       bool   `json:"isProtected,omitempty"`
+	Title             string `json:"title"`
+	BlobID            string `json:"blobId,omitempty"`
+	DateLastEdited    string `json:"dateLastEdited,omitempty"`
+	DateCreated       string `json:"dateCreated,omitempty"`
+	UtcDateLastEdited string `json:"utcDateLastEdited,omitempty"`
+	UtcDateCreated    string `json:"utcDateCreated,omitempty"`
+	UtcDateModified   string `json:"utcDateModified,omitempty"`
+	ContentLength     int    `json:"contentLength,omitempty"`
+}
+
+func (c *Client) GetNoteRevisions(ctx context.Context, noteID string) ([]Revision, error) {
+	url := fmt.Sprintf("%s/etapi/notes/%s/revisions", c.baseURL, noteID)
+	var revisions []Revision
+	if err := c.doRequest(ctx, "notes.revisions", url, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (c *Client) GetRevision(ctx context.Context, revisionID string) (*Revision, error) {
+	url := fmt.Sprintf("%s/etapi/revisions/%s", c.baseURL, revisionID)
+	var revision Revision
+	if err := c.doRequest(ctx, "revisions.get", url, &revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (c *Client) GetRevisionContent(ctx context.Context, revisionID string) (string, error) {
+	url := fmt.Sprintf("%s/etapi/revisions/%s/content", c.baseURL, revisionID)
+	body, err := c.getBytes(ctx, "revisions.content", url, "revision content")
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
@@ -20,39 +20,70 @@ func (h *APIHandler) AdminPage(c *gin.Context) {
 type i18nMap map[string]string
 
 var adminI18n = map[string]i18nMap{
-	"title":             {"zh-CN": "缓存管理", "en": "Cache Admin"},
-	"enterToken":        {"zh-CN": "输入管理令牌", "en": "Enter admin token"},
-	"login":             {"zh-CN": "登录", "en": "Login"},
-	"logout":            {"zh-CN": "退出", "en": "Logout"},
-	"status":            {"zh-CN": "状态", "en": "Status"},
-	"connected":         {"zh-CN": "已连接", "en": "Connected"},
-	"disconnected":      {"zh-CN": "未连接", "en": "Disconnected"},
-	"cacheEntries":      {"zh-CN": "缓存条目", "en": "Cache Entries"},
-	"type":              {"zh-CN": "类型", "en": "Type"},
-	"keys":              {"zh-CN": "键数", "en": "Keys"},
-	"minTTL":            {"zh-CN": "最小 TTL", "en": "Min TTL"},
-	"maxTTL":            {"zh-CN": "最大 TTL", "en": "Max TTL"},
-	"defaultTTL":        {"zh-CN": "默认 TTL", "en": "Default TTL"},
-	"action":            {"zh-CN": "操作", "en": "Action"},
-	"clear":             {"zh-CN": "清除", "en": "Clear"},
-	"invalidate":        {"zh-CN": "缓存失效", "en": "Invalidate"},
-	"clearAll":          {"zh-CN": "清除全部缓存", "en": "Clear All Cache"},
-	"triggerPreload":    {"zh-CN": "触发预加载", "en": "Trigger Preload"},
-	"byNoteID":          {"zh-CN": "按笔记 ID 失效", "en": "Invalidate by note ID"},
-	"clearNote":         {"zh-CN": "清除笔记", "en": "Clear Note"},
-	"byAttachmentID":    {"zh-CN": "按附件 ID 失效", "en": "Invalidate by attachment ID"},
-	"clearAttachment":   {"zh-CN": "清除附件", "en": "Clear Attachment"},
-	"invalidToken":      {"zh-CN": "无效令牌", "en": "Invalid token"},
-	"clearAllConfirm":   {"zh-CN": "确认清除全部缓存？", "en": "Clear all cache?"},
-	"clearedKeys":       {"zh-CN": "已清除 %d 个键", "en": "Cleared %d keys"},
-	"clearedKeysFrom":   {"zh-CN": "已从 %s 清除 %d 个键", "en": "Cleared %d keys from %s"},
-	"clearTypeConfirm":  {"zh-CN": "确认清除缓存类型: %s？", "en": "Clear cache type: %s?"},
-	"clearedNote":       {"zh-CN": "已清除笔记: %s", "en": "Cleared note: %s"},
-	"enterNoteID":       {"zh-CN": "请输入笔记 ID", "en": "Enter a note ID"},
-	"clearedAttachment": {"zh-CN": "已清除附件: %s", "en": "Cleared attachment: %s"},
-	"enterAttachID":     {"zh-CN": "请输入附件 ID", "en": "Enter an attachment ID"},
-	"preloadTriggered":  {"zh-CN": "预加载已触发", "en": "Preload triggered"},
-	"preloadInProgress": {"zh-CN": "预加载正在进行中", "en": "Preload already in progress"},
+	"title":               {"zh-CN": "缓存管理", "en": "Cache Admin"},
+	"enterToken":          {"zh-CN": "输入管理令牌", "en": "Enter admin token"},
+	"login":               {"zh-CN": "登录", "en": "Login"},
+	"logout":              {"zh-CN": "退出", "en": "Logout"},
+	"status":              {"zh-CN": "状态", "en": "Status"},
+	"connected":           {"zh-CN": "已连接", "en": "Connected"},
+	"disconnected":        {"zh-CN": "未连接", "en": "Disconnected"},
+	"cacheEntries":        {"zh-CN": "缓存条目", "en": "Cache Entries"},
+	"type":                {"zh-CN": "类型", "en": "Type"},
+	"keys":                {"zh-CN": "键数", "en": "Keys"},
+	"minTTL":              {"zh-CN": "最小 TTL", "en": "Min TTL"},
+	"maxTTL":              {"zh-CN": "最大 TTL", "en": "Max TTL"},
+	"defaultTTL":          {"zh-CN": "默认 TTL", "en": "Default TTL"},
+	"action":              {"zh-CN": "操作", "en": "Action"},
+	"clear":               {"zh-CN": "清除", "en": "Clear"},
+	"invalidate":          {"zh-CN": "缓存失效", "en": "Invalidate"},
+	"clearAll":            {"zh-CN": "清除全部缓存", "en": "Clear All Cache"},
+	"triggerPreload":      {"zh-CN": "触发预加载", "en": "Trigger Preload"},
+	"byNoteID":            {"zh-CN": "按笔记 ID 失效", "en": "Invalidate by note ID"},
+	"clearNote":           {"zh-CN": "清除笔记", "en": "Clear Note"},
+	"byAttachmentID":      {"zh-CN": "按附件 ID 失效", "en": "Invalidate by attachment ID"},
+	"clearAttachment":     {"zh-CN": "清除附件", "en": "Clear Attachment"},
+	"invalidToken":        {"zh-CN": "无效令牌", "en": "Invalid token"},
+	"clearAllConfirm":     {"zh-CN": "确认清除全部缓存？", "en": "Clear all cache?"},
+	"clearedKeys":         {"zh-CN": "已清除 %d 个键", "en": "Cleared %d keys"},
+	"clearedKeysFrom":     {"zh-CN": "已从 %s 清除 %d 个键", "en": "Cleared %d keys from %s"},
+	"clearTypeConfirm":    {"zh-CN": "确认清除缓存类型: %s？", "en": "Clear cache type: %s?"},
+	"clearedNote":         {"zh-CN": "已清除笔记: %s", "en": "Cleared note: %s"},
+	"enterNoteID":         {"zh-CN": "请输入笔记 ID", "en": "Enter a note ID"},
+	"clearedAttachment":   {"zh-CN": "已清除附件: %s", "en": "Cleared attachment: %s"},
+	"enterAttachID":       {"zh-CN": "请输入附件 ID", "en": "Enter an attachment ID"},
+	"preloadTriggered":    {"zh-CN": "预加载已触发", "en": "Preload triggered"},
+	"preloadInProgress":   {"zh-CN": "预加载正在进行中", "en": "Preload already in progress"},
+	"triliumCircuit":      {"zh-CN": "Trilium 熔断器", "en": "Trilium circuit breaker"},
+	"tabCache":            {"zh-CN": "缓存", "en": "Cache"},
+	"tabSummaries":        {"zh-CN": "摘要", "en": "Summaries"},
+	"summaries":           {"zh-CN": "摘要记录", "en": "Summaries"},
+	"allStatuses":         {"zh-CN": "全部状态", "en": "All statuses"},
+	"allTypes":            {"zh-CN": "全部类型", "en": "All types"},
+	"refresh":             {"zh-CN": "刷新", "en": "Refresh"},
+	"regenerateFailed":    {"zh-CN": "重新生成失败项", "en": "Regenerate failed"},
+	"noteID":              {"zh-CN": "笔记 ID", "en": "Note ID"},
+	"updated":             {"zh-CN": "更新时间", "en": "Updated"},
+	"attempts":            {"zh-CN": "尝试次数", "en": "Attempts"},
+	"regenerate":          {"zh-CN": "重新生成", "en": "Regenerate"},
+	"pin":                 {"zh-CN": "编辑并固定", "en": "Edit & pin"},
+	"pinned":              {"zh-CN": "已固定", "en": "Pinned"},
+	"pinPrompt":           {"zh-CN": "输入摘要内容，固定后不会被自动生成覆盖", "en": "Summary text; pinned summaries are not overwritten by generation"},
+	"pinnedNote":          {"zh-CN": "已固定摘要: %s", "en": "Pinned summary: %s"},
+	"clearSummaryConfirm": {"zh-CN": "确认清除摘要: %s？", "en": "Clear summary: %s?"},
+	"clearedSummary":      {"zh-CN": "已清除摘要: %s", "en": "Cleared summary: %s"},
+	"regenerating":        {"zh-CN": "已加入重新生成队列: %s", "en": "Queued regeneration: %s"},
+	"regeneratedFailed":   {"zh-CN": "已重新排队 %d 个失败摘要", "en": "Queued %d failed summaries"},
+	"noSummaries":         {"zh-CN": "暂无摘要", "en": "No summaries"},
+	"requestFailed":       {"zh-CN": "请求失败", "en": "Request failed"},
+	"usage":               {"zh-CN": "AI 用量", "en": "AI Usage"},
+	"monthToDate":         {"zh-CN": "本月费用", "en": "This month"},
+	"budget":              {"zh-CN": "预算", "en": "Budget"},
+	"budgetPaused":        {"zh-CN": "已超出预算，生成已暂停", "en": "Budget reached; generation paused"},
+	"day":                 {"zh-CN": "日期", "en": "Day"},
+	"calls":               {"zh-CN": "调用", "en": "Calls"},
+	"tokens":              {"zh-CN": "Token（入/出）", "en": "Tokens (in/out)"},
+	"cost":                {"zh-CN": "费用", "en": "Cost"},
+	"avgLatency":          {"zh-CN": "平均耗时", "en": "Avg latency"},
 }
 
 func t(locale, key string) string {
@@ -108,6 +139,12 @@ input[type=text]{width:100%%;padding:8px 10px;border:1px solid #ccc;border-radiu
 .msg-err{background:#fef2f2;color:#991b1b;display:block}
 .login-wrap{max-width:360px;margin:80px auto}
 .hidden{display:none!important}
+.tabs{display:flex;gap:4px;border-bottom:1px solid #ddd;margin-bottom:8px}
+.tab{background:none;border:none;border-bottom:2px solid transparent;padding:8px 14px;cursor:pointer;font-size:.9rem;color:#666}
+.tab.active{border-bottom-color:#2563eb;color:#111}
+select{padding:6px 8px;border:1px solid #ccc;border-radius:4px;font-size:.85rem;background:#fff}
+.badge{display:inline-block;padding:1px 6px;border-radius:3px;font-size:.72rem;background:#e0e7ff;color:#3730a3;margin-left:4px}
+.st-ready{color:#166534}.st-failed{color:#991b1b}.st-pending,.st-processing{color:#92400e}
 </style>
 </head>
 <body>
@@ -127,6 +164,12 @@ input[type=text]{width:100%%;padding:8px 10px;border:1px solid #ccc;border-radiu
     <button class="btn btn-secondary btn-sm" onclick="logout()">%s</button>
   </div>
 
+  <div class="tabs">
+    <button class="tab active" id="tab-btn-cache" onclick="showTab('cache')">%s</button>
+    <button class="tab" id="tab-btn-summaries" onclick="showTab('summaries')">%s</button>
+  </div>
+
+  <div id="tab-cache">
   <h2>%s</h2>
   <div id="redis-status" class="card" style="font-size:.9rem"></div>
 
@@ -160,6 +203,49 @@ input[type=text]{width:100%%;padding:8px 10px;border:1px solid #ccc;border-radiu
     </div>
     <div id="action-msg" class="msg"></div>
   </div>
+  </div>
+
+  <div id="tab-summaries" class="hidden">
+  <h2>%s</h2>
+  <div class="card">
+    <div class="actions" style="align-items:center">
+      <select id="summary-status" onchange="loadSummaries()">
+        <option value="">%s</option>
+        <option value="ready">ready</option>
+        <option value="pending">pending</option>
+        <option value="processing">processing</option>
+        <option value="failed">failed</option>
+      </select>
+      <select id="summary-type" onchange="loadSummaries()">
+        <option value="">%s</option>
+        <option value="ai">ai</option>
+        <option value="code">code</option>
+        <option value="tags">tags</option>
+        <option value="keywords">keywords</option>
+        <option value="description">description</option>
+        <option value="embedding">embedding</option>
+        <option value="translation-en">translation-en</option>
+        <option value="translation-zh-CN">translation-zh-CN</option>
+      </select>
+      <button class="btn btn-secondary btn-sm" onclick="loadSummaries()">%s</button>
+      <button class="btn btn-primary btn-sm" onclick="regenerateFailed()">%s</button>
+    </div>
+    <div id="summary-msg" class="msg"></div>
+    <table>
+      <thead><tr><th>%s</th><th>%s</th><th>%s</th><th>%s</th><th>%s</th><th>%s</th></tr></thead>
+      <tbody id="summary-table"></tbody>
+    </table>
+  </div>
+
+  <h2>%s</h2>
+  <div class="card">
+    <div id="usage-summary" style="font-size:.9rem;margin-bottom:8px"></div>
+    <table>
+      <thead><tr><th>%s</th><th>%s</th><th>%s</th><th>%s</th><th>%s</th></tr></thead>
+      <tbody id="usage-table"></tbody>
+    </table>
+  </div>
+  </div>
 </div>
 
 <script>
@@ -179,7 +265,23 @@ const i18n = {
   preloadTriggered: %q,
   preloadInProgress: %q,
   clear: %q,
+  triliumCircuit: %q,
+  regenerate: %q,
+  pin: %q,
+  pinned: %q,
+  pinPrompt: %q,
+  pinnedNote: %q,
+  clearSummaryConfirm: %q,
+  clearedSummary: %q,
+  regenerating: %q,
+  regeneratedFailed: %q,
+  noSummaries: %q,
+  requestFailed: %q,
+  monthToDate: %q,
+  budget: %q,
+  budgetPaused: %q,
 };
+let summaryItems = [];
 let token = localStorage.getItem(LS_KEY) || '';
 
 function api(method, path, body) {
@@ -242,7 +344,15 @@ function loadStats() {
     const dot = s.redisConnected
       ? '<span class="status status-ok"></span>' + i18n.connected
       : '<span class="status status-err"></span>' + i18n.disconnected;
-    document.getElementById('redis-status').innerHTML = dot;
+    let upstream = '';
+    if (s.trilium) {
+      const ok = s.trilium.state === 'closed';
+      upstream = '<div style="margin-top:6px"><span class="status ' + (ok ? 'status-ok' : 'status-err') + '"></span>' +
+        i18n.triliumCircuit + ': ' + s.trilium.state +
+        (s.trilium.consecutiveFailures ? ' (' + s.trilium.consecutiveFailures + '/' + s.trilium.threshold + ')' : '') +
+        (s.trilium.retryAt ? ' \u2192 ' + s.trilium.retryAt : '') + '</div>';
+    }
+    document.getElementById('redis-status').innerHTML = dot + upstream;
     const tbody = document.getElementById('cache-table');
     tbody.innerHTML = '';
     (s.types || []).forEach(t => {
@@ -289,6 +399,103 @@ function invalidateAttachment() {
   }).catch(() => {});
 }
 
+function showTab(name) {
+  ['cache', 'summaries'].forEach(n => {
+    document.getElementById('tab-' + n).classList.toggle('hidden', n !== name);
+    document.getElementById('tab-btn-' + n).classList.toggle('active', n === name);
+  });
+  if (name === 'summaries') { loadSummaries(); loadUsage(); } else loadStats();
+}
+
+function esc(s) {
+  return String(s == null ? '' : s).replace(/[&<>"']/g, c => ({'&':'&amp;','<':'&lt;','>':'&gt;','"':'&quot;',"'":'&#39;'}[c]));
+}
+
+function loadSummaries() {
+  const params = new URLSearchParams({limit: '200'});
+  const status = document.getElementById('summary-status').value;
+  const type = document.getElementById('summary-type').value;
+  if (status) params.set('status', status);
+  if (type) params.set('type', type);
+  api('GET', '/summaries?' + params.toString()).then(r => {
+    summaryItems = r.data.items || [];
+    const tbody = document.getElementById('summary-table');
+    tbody.innerHTML = '';
+    if (!summaryItems.length) {
+      tbody.innerHTML = '<tr><td colspan="6" style="color:#888">' + i18n.noSummaries + '</td></tr>';
+      return;
+    }
+    summaryItems.forEach((s, i) => {
+      const tr = document.createElement('tr');
+      tr.innerHTML = '<td title="' + esc(s.content) + '"><b>' + esc(s.noteId) + '</b>' + (s.pinned ? '<span class="badge">' + i18n.pinned + '</span>' : '') + '</td>' +
+        '<td>' + esc(s.type) + '</td>' +
+        '<td class="st-' + esc(s.status) + '" title="' + esc(s.error) + '">' + esc(s.status || '\u2014') + (s.nextRetryAt ? ' \u2192 ' + esc(s.nextRetryAt) : '') + '</td>' +
+        '<td>' + esc(s.updatedAt) + '</td>' +
+        '<td>' + (s.attempts || '\u2014') + '</td>' +
+        '<td style="white-space:nowrap">' +
+        (s.type === 'ai' ? '<button class="btn btn-primary btn-sm" onclick="regenerateSummary(' + i + ')">' + i18n.regenerate + '</button>' : '') +
+        '<button class="btn btn-secondary btn-sm" onclick="pinSummary(' + i + ')">' + i18n.pin + '</button>' +
+        '<button class="btn btn-danger btn-sm" onclick="clearSummary(' + i + ')">' + i18n.clear + '</button></td>';
+      tbody.appendChild(tr);
+    });
+  }).catch(() => {});
+}
+
+function loadUsage() {
+  api('GET', '/summaries/usage?granularity=day&limit=14').then(r => {
+    if (r.status >= 400) {
+      document.getElementById('usage-summary').textContent = (r.data && r.data.error) || i18n.requestFailed;
+      return;
+    }
+    const u = r.data;
+    let line = i18n.monthToDate + ': ' + u.monthToDate.toFixed(4);
+    if (u.monthlyBudget) line += ' / ' + i18n.budget + ' ' + u.monthlyBudget;
+    document.getElementById('usage-summary').innerHTML = esc(line) +
+      (u.paused ? ' <span class="badge" style="background:#fef2f2;color:#991b1b">' + i18n.budgetPaused + '</span>' : '');
+    const tbody = document.getElementById('usage-table');
+    tbody.innerHTML = '';
+    (u.items || []).forEach(d => {
+      const tr = document.createElement('tr');
+      tr.innerHTML = '<td>' + esc(d.period) + '</td><td>' + d.calls + (d.failures ? ' (' + d.failures + ' \u2717)' : '') + '</td>' +
+        '<td>' + d.inputTokens + ' / ' + d.outputTokens + '</td><td>' + d.cost.toFixed(4) + '</td><td>' + Math.round(d.avgLatencyMs) + 'ms</td>';
+      tbody.appendChild(tr);
+    });
+  }).catch(() => {});
+}
+
+function summaryAction(path, body, text) {
+  return api('POST', path, body).then(r => {
+    if (r.status >= 400) {
+      showMsg('summary-msg', (r.data && r.data.error) || i18n.requestFailed, false);
+      return;
+    }
+    showMsg('summary-msg', typeof text === 'function' ? text(r.data) : text, true);
+    loadSummaries();
+  }).catch(() => {});
+}
+
+function regenerateSummary(i) {
+  const s = summaryItems[i];
+  summaryAction('/summaries/regenerate', {scope: 'note', id: s.noteId}, i18n.regenerating.replace('%%s', s.noteId));
+}
+
+function regenerateFailed() {
+  summaryAction('/summaries/regenerate', {scope: 'failed'}, d => i18n.regeneratedFailed.replace('%%d', d.queued));
+}
+
+function pinSummary(i) {
+  const s = summaryItems[i];
+  const text = prompt(i18n.pinPrompt, s.content || '');
+  if (text === null || !text.trim()) return;
+  summaryAction('/summaries/pin', {id: s.noteId, type: s.type, text: text}, i18n.pinnedNote.replace('%%s', s.noteId));
+}
+
+function clearSummary(i) {
+  const s = summaryItems[i];
+  if (!confirm(i18n.clearSummaryConfirm.replace('%%s', s.noteId + ' (' + s.type + ')'))) return;
+  summaryAction('/summaries/clear', {id: s.noteId, type: s.type}, i18n.clearedSummary.replace('%%s', s.noteId));
+}
+
 function triggerPreload() {
   api('POST', '/cache/preload').then(r => {
     showMsg('action-msg', r.data.status || i18n.preloadTriggered, true);
@@ -314,6 +521,8 @@ if (token) {
 		t(lang, "login"),
 		t(lang, "title"),
 		t(lang, "logout"),
+		t(lang, "tabCache"),
+		t(lang, "tabSummaries"),
 		t(lang, "status"),
 		t(lang, "cacheEntries"),
 		t(lang, "type"),
@@ -329,6 +538,23 @@ if (token) {
 		t(lang, "clearNote"),
 		t(lang, "byAttachmentID"),
 		t(lang, "clearAttachment"),
+		t(lang, "summaries"),
+		t(lang, "allStatuses"),
+		t(lang, "allTypes"),
+		t(lang, "refresh"),
+		t(lang, "regenerateFailed"),
+		t(lang, "noteID"),
+		t(lang, "type"),
+		t(lang, "status"),
+		t(lang, "updated"),
+		t(lang, "attempts"),
+		t(lang, "action"),
+		t(lang, "usage"),
+		t(lang, "day"),
+		t(lang, "calls"),
+		t(lang, "tokens"),
+		t(lang, "cost"),
+		t(lang, "avgLatency"),
 		t(lang, "connected"),
 		t(lang, "disconnected"),
 		t(lang, "invalidToken"),
@@ -343,5 +569,20 @@ if (token) {
 		t(lang, "preloadTriggered"),
 		t(lang, "preloadInProgress"),
 		t(lang, "clear"),
+		t(lang, "triliumCircuit"),
+		t(lang, "regenerate"),
+		t(lang, "pin"),
+		t(lang, "pinned"),
+		t(lang, "pinPrompt"),
+		t(lang, "pinnedNote"),
+		t(lang, "clearSummaryConfirm"),
+		t(lang, "clearedSummary"),
+		t(lang, "regenerating"),
+		t(lang, "regeneratedFailed"),
+		t(lang, "noSummaries"),
+		t(lang, "requestFailed"),
+		t(lang, "monthToDate"),
+		t(lang, "budget"),
+		t(lang, "budgetPaused"),
 	)
 }
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harveyTon/trilium-blog/backend/blog"
+)
+
+const maxAdminSummaryPage = 200
+
+// summaryAdminError answers for errors shared by the summary admin endpoints
+// and reports whether it wrote a response.
+func summaryAdminError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, blog.ErrSummaryAdminUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "summary store not configured"})
+	case errors.Is(err, blog.ErrAISummaryDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "ai summaries are disabled"})
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *APIHandler) ListSummaries(c *gin.Context) {
+	filter := blog.SummaryFilter{
+		Status: c.Query("status"),
+		Type:   c.Query("type"),
+		NoteID: c.Query("noteId"),
+		Limit:  50,
+	}
+	if filter.Type != "" && !blog.ValidSummaryType(filter.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid type, use: " + strings.Join(blog.SummaryTypes(), ", ")})
+		return
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		filter.Limit = min(limit, maxAdminSummaryPage)
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		filter.Offset = offset
+	}
+
+	items, total, err := h.service.ListSummaries(filter)
+	if err != nil {
+		if !summaryAdminError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": err.Error()})
+		}
+		return
+	}
+	if items == nil {
+		items = []blog.StoredSummary{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": filter.Limit, "offset": filter.Offset})
+}
+
+func (h *APIHandler) SummaryUsage(c *gin.Context) {
+	granularity := c.DefaultQuery("granularity", "day")
+	if granularity != "day" && granularity != "month" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid granularity, use: day, month"})
+		return
+	}
+	limit := 30
+	if granularity == "month" {
+		limit = 12
+	}
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = min(n, 366)
+	}
+
+	report, err := h.service.AIUsageReport(granularity, limit)
+	if err != nil {
+		if !summaryAdminError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+type regenerateSummaryRequest struct {
+	Scope string `json:"scope"`
+	ID    string `json:"id"`
+}
+
+func (h *APIHandler) RegenerateSummaries(c *gin.Context) {
+	var req regenerateSummaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	switch req.Scope {
+	case "note":
+		if req.ID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "id is required for note scope"})
+			return
+		}
+		summaries, err := h.service.RegenerateSummaryContext(c.Request.Context(), req.ID)
+		if err != nil {
+			if summaryAdminError(c, err) {
+				return
+			}
+			if _, ok := err.(*blog.BlogError); ok {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+				return
+			}
+			classifyError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"regenerated": "note", "id": req.ID, "summaries": summaries})
+	case "failed":
+		count, err := h.service.RegenerateFailedSummariesContext(c.Request.Context())
+		if err != nil {
+			if !summaryAdminError(c, err) {
+				classifyError(c, err)
+			}
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"regenerated": "failed", "queued": count})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope, use: note, failed"})
+	}
+}
+
+type summaryRequest struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func bindSummaryRequest(c *gin.Context) (summaryRequest, bool) {
+	var req summaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return req, false
+	}
+	if req.Type == "" {
+		req.Type = "ai"
+	}
+	if req.ID == "" || !blog.ValidSummaryType(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id and a type of " + strings.Join(blog.SummaryTypes(), ", ") + " are required"})
+		return req, false
+	}
+	return req, true
+}
+
+func (h *APIHandler) ClearSummary(c *gin.Context) {
+	req, ok := bindSummaryRequest(c)
+	if !ok {
+		return
+	}
+	if err := h.service.ClearSummary(req.ID, req.Type); err != nil {
+		if !summaryAdminError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"cleared": req.Type, "id": req.ID})
+}
+
+func (h *APIHandler) PinSummary(c *gin.Context) {
+	req, ok := bindSummaryRequest(c)
+	if !ok {
+		return
+	}
+	if req.Text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "text is required"})
+		return
+	}
+	if req.Type == "embedding" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "embeddings cannot be pinned"})
+		return
+	}
+	if err := h.service.PinSummary(req.ID, req.Type, req.Text); err != nil {
+		if !summaryAdminError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pinned": req.Type, "id": req.ID})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harveyTon/trilium-blog/backend/blog"
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+)
+
+func newSummaryAdminRouter(t *testing.T) (*gin.Engine, *blog.SummaryStoreDB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	store, err := blog.NewSummaryStoreDB(filepath.Join(t.TempDir(), "summaries.db"))
+	if err != nil {
+		t.Fatalf("failed to create summary store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	service := blog.NewService(etapi.NewClient("http://127.0.0.1:1", "token"), &blog.NoopStore{}, blog.WithSummaryStore(store))
+	h := NewAPIHandler(service, "secret", "en")
+	r := gin.New()
+	admin := r.Group("/api/admin", h.AdminAuthMiddleware)
+	admin.GET("/summaries", h.ListSummaries)
+	admin.POST("/summaries/regenerate", h.RegenerateSummaries)
+	admin.POST("/summaries/clear", h.ClearSummary)
+	admin.POST("/summaries/pin", h.PinSummary)
+	return r, store
+}
+
+func adminRequest(r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminSummariesPinListAndClear(t *testing.T) {
+	r, store := newSummaryAdminRouter(t)
+	_ = store.UpsertSummary(blog.StoredSummary{NoteID: "n2", Type: "ai", Status: "failed", Error: "boom"})
+
+	if w := adminRequest(r, http.MethodPost, "/api/admin/summaries/pin", `{"id":"n1","text":"manual"}`); w.Code != http.StatusOK {
+		t.Fatalf("pin returned %d: %s", w.Code, w.Body.String())
+	}
+
+	w := adminRequest(r, http.MethodGet, "/api/admin/summaries?status=ready&type=ai", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list returned %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Items []blog.StoredSummary `json:"items"`
+		Total int                  `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if body.Total != 1 || len(body.Items) != 1 || body.Items[0].NoteID != "n1" || !body.Items[0].Pinned {
+		t.Fatalf("unexpected list response %s", w.Body.String())
+	}
+
+	if w := adminRequest(r, http.MethodPost, "/api/admin/summaries/clear", `{"id":"n1","type":"ai"}`); w.Code != http.StatusOK {
+		t.Fatalf("clear returned %d: %s", w.Code, w.Body.String())
+	}
+	if item, _ := store.GetSummary("n1", "ai"); item != nil {
+		t.Fatalf("expected summary to be cleared, got %#v", item)
+	}
+}
+
+func TestAdminSummariesValidation(t *testing.T) {
+	r, _ := newSummaryAdminRouter(t)
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/api/admin/summaries?type=bogus", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/admin/summaries?type=translation-fr", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/admin/summaries?type=translation-en", "", http.StatusOK},
+		{http.MethodGet, "/api/admin/summaries?type=embedding", "", http.StatusOK},
+		{http.MethodPost, "/api/admin/summaries/pin", `{"id":"n1"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/admin/summaries/clear", `{"type":"ai"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/admin/summaries/regenerate", `{"scope":"everything"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/admin/summaries/regenerate", `{"scope":"failed"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if w := adminRequest(r, tt.method, tt.path, tt.body); w.Code != tt.want {
+			t.Errorf("%s %s %s: got %d, want %d (%s)", tt.method, tt.path, tt.body, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestRenderAdminHTMLIncludesSummariesTab(t *testing.T) {
+	for _, locale := range []string{"en", "zh-CN"} {
+		html := renderAdminHTML(locale)
+		if strings.Contains(html, "%!") {
+			t.Fatalf("admin page for %s has formatting errors", locale)
+		}
+		if !strings.Contains(html, `id="tab-summaries"`) || !strings.Contains(html, "/summaries/regenerate") {
+			t.Fatalf("admin page for %s is missing the summaries tab", locale)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"io"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
@@ -12,6 +15,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/harveyTon/trilium-blog/backend/blog"
 	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/pkg/metrics"
 )
 
 var blockedIPNets = mustParseCIDRs([]string{
@@ -108,6 +112,15 @@ func (h *APIHandler) TriggerPreload(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "preload started"})
 }
 
+func (h *APIHandler) Health(c *gin.Context) {
+	trilium := h.service.UpstreamStatus()
+	status := "ok"
+	if trilium.State != etapi.BreakerClosed {
+		status = "degraded"
+	}
+	c.JSON(http.StatusOK, gin.H{"status": status, "trilium": trilium})
+}
+
 func (h *APIHandler) GetSite(c *gin.Context) {
 	site := h.service.GetSite()
 	c.JSON(http.StatusOK, site)
@@ -120,7 +133,7 @@ func (h *APIHandler) ListPosts(c *gin.Context) {
 		page = 1
 	}
 
-	posts, err := h.service.ListPosts(page)
+	posts, err := h.service.ListPostsContext(c.Request.Context(), page)
 	if err != nil {
 		classifyError(c, err)
 		return
@@ -129,7 +142,28 @@ func (h *APIHandler) ListPosts(c *gin.Context) {
 	c.JSON(http.StatusOK, posts)
 }
 
+// statusClientClosedRequest is the non-standard status nginx logs when the
+// client goes away before the response is written.
+const statusClientClosedRequest = 499
+
+// abortIfCanceled stops the handler quietly when the request context was
+// canceled or timed out, since nobody is left to read an error body.
+func abortIfCanceled(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled):
+		c.AbortWithStatus(statusClientClosedRequest)
+		return true
+	case errors.Is(err, context.DeadlineExceeded) && c.Request.Context().Err() != nil:
+		c.AbortWithStatus(http.StatusGatewayTimeout)
+		return true
+	}
+	return false
+}
+
 func classifyError(c *gin.Context, err error) {
+	if abortIfCanceled(c, err) {
+		return
+	}
 	if _, ok := err.(*etapi.AuthError); ok {
 		c.JSON(http.StatusBadGateway, gin.H{
 			"error":      "trilium_auth_failed",
@@ -138,6 +172,18 @@ func classifyError(c *gin.Context, err error) {
 		})
 		return
 	}
+	var openErr *etapi.CircuitOpenError
+	if errors.As(err, &openErr) {
+		if openErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(openErr.RetryAfter.Seconds())+1))
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":      "trilium_unavailable",
+			"message":    "Trilium Notes is failing repeatedly; requests are paused briefly.",
+			"suggestion": "Check that Trilium is running. Requests resume automatically once it recovers.",
+		})
+		return
+	}
 	if _, ok := err.(*etapi.RequestError); ok {
 		c.JSON(http.StatusBadGateway, gin.H{
 			"error":      "trilium_unreachable",
@@ -158,8 +204,13 @@ func (h *APIHandler) SearchPosts(c *gin.Context) {
 		limit = 5
 	}
 
-	result, err := h.service.SearchPosts(query, preview, limit)
+	mode := blog.NormalizeSearchMode(c.Query("mode"))
+
+	result, err := h.service.SearchPostsModeContext(c.Request.Context(), query, mode, preview, limit)
 	if err != nil {
+		if abortIfCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
 		return
 	}
@@ -168,8 +219,11 @@ func (h *APIHandler) SearchPosts(c *gin.Context) {
 }
 
 func (h *APIHandler) ListFeaturedPosts(c *gin.Context) {
-	posts, err := h.service.ListFeaturedPosts()
+	posts, err := h.service.ListFeaturedPostsContext(c.Request.Context())
 	if err != nil {
+		if abortIfCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch featured posts"})
 		return
 	}
@@ -180,8 +234,11 @@ func (h *APIHandler) ListFeaturedPosts(c *gin.Context) {
 func (h *APIHandler) GetPost(c *gin.Context) {
 	noteId := c.Param("noteId")
 
-	post, err := h.service.GetPost(noteId)
+	post, err := h.service.GetPostLangContext(c.Request.Context(), noteId, c.Query("lang"))
 	if err != nil {
+		if abortIfCanceled(c, err) {
+			return
+		}
 		if _, ok := err.(*blog.BlogError); ok {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 			return
@@ -196,24 +253,187 @@ func (h *APIHandler) GetPost(c *gin.Context) {
 func (h *APIHandler) GetPostSummary(c *gin.Context) {
 	noteId := c.Param("noteId")
 
-	summaries, err := h.service.GetPostSummaries(noteId)
+	summaries, err := h.service.GetPostSummariesContext(c.Request.Context(), noteId)
 	if err != nil {
+		summaryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+func (h *APIHandler) GetRelatedPosts(c *gin.Context) {
+	noteId := c.Param("noteId")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit < 1 {
+		limit = 5
+	}
+
+	related, err := h.service.RelatedPostsContext(c.Request.Context(), noteId, limit)
+	if err != nil {
+		if abortIfCanceled(c, err) {
+			return
+		}
 		if _, ok := err.(*blog.BlogError); ok {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post summary"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related posts"})
 		return
 	}
 
-	c.JSON(http.StatusOK, summaries)
+	c.JSON(http.StatusOK, related)
+}
+
+func (h *APIHandler) GetBacklinks(c *gin.Context) {
+	backlinks, err := h.service.BacklinksContext(c.Request.Context(), c.Param("noteId"))
+	if err != nil {
+		if abortIfCanceled(c, err) {
+			return
+		}
+		if _, ok := err.(*blog.BlogError); ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch backlinks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, backlinks)
+}
+
+type askRequest struct {
+	Question string `json:"question"`
+}
+
+func (h *APIHandler) Ask(c *gin.Context) {
+	var req askRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "question is required"})
+		return
+	}
+
+	answer, err := h.service.AskContext(c.Request.Context(), req.Question)
+	if err != nil {
+		if abortIfCanceled(c, err) {
+			return
+		}
+		var configErr *blog.AIConfigError
+		switch {
+		case errors.Is(err, blog.ErrAskInvalidQuestion):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, blog.ErrAskDisabled), errors.As(err, &configErr):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "question answering is unavailable"})
+		default:
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to answer the question"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, answer)
+}
+
+func summaryError(c *gin.Context, err error) {
+	if abortIfCanceled(c, err) {
+		return
+	}
+	if _, ok := err.(*blog.BlogError); ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post summary"})
+}
+
+const (
+	// summaryStreamHeartbeat keeps proxies from closing an idle stream.
+	summaryStreamHeartbeat = 15 * time.Second
+	// summaryStreamMaxAge bounds a single stream; EventSource reconnects on
+	// its own and gets a fresh snapshot.
+	summaryStreamMaxAge = 5 * time.Minute
+)
+
+// summaryStreamDone reports whether the AI summary has reached a state that
+// will not change without a new edit, so the stream can end.
+func summaryStreamDone(summaries *blog.Summaries) bool {
+	if summaries == nil || !summaries.AIEnabled || summaries.AI == nil {
+		return true
+	}
+	switch summaries.AI.Status {
+	case "ready", "failed", "deferred":
+		return true
+	}
+	return false
+}
+
+// StreamPostSummary sends the summaries of a post as Server-Sent Events: a
+// "summary" event with the current state, then another one each time the AI
+// summary changes status, until it is ready or failed.
+func (h *APIHandler) StreamPostSummary(c *gin.Context) {
+	noteId := c.Param("noteId")
+
+	// Subscribe before reading the snapshot so no transition is missed.
+	events, cancel, _ := h.service.SubscribeSummary(noteId)
+	defer cancel()
+
+	summaries, err := h.service.GetPostSummariesContext(c.Request.Context(), noteId)
+	if err != nil {
+		summaryError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.SSEvent("summary", summaries)
+	c.Writer.Flush()
+	if summaryStreamDone(summaries) {
+		return
+	}
+
+	heartbeat := time.NewTicker(summaryStreamHeartbeat)
+	defer heartbeat.Stop()
+	maxAge := time.NewTimer(summaryStreamMaxAge)
+	defer maxAge.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-maxAge.C:
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Entry.Type != "ai" {
+				continue
+			}
+			entry := event.Entry
+			summaries.AI = &entry
+			c.SSEvent("summary", summaries)
+			c.Writer.Flush()
+			if summaryStreamDone(summaries) {
+				return
+			}
+		}
+	}
 }
 
 func (h *APIHandler) GetAsset(c *gin.Context) {
 	attachmentId := c.Param("attachmentId")
 
-	content, contentType, err := h.service.GetAsset(attachmentId)
+	content, contentType, err := h.service.GetAssetContext(c.Request.Context(), attachmentId)
 	if err != nil {
+		if abortIfCanceled(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch asset"})
 		return
 	}
@@ -221,9 +441,41 @@ func (h *APIHandler) GetAsset(c *gin.Context) {
 	c.Data(http.StatusOK, contentType, content)
 }
 
+// HighlightCSS serves the stylesheet for code blocks highlighted on the
+// server.
+func (h *APIHandler) HighlightCSS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "text/css; charset=utf-8", []byte(h.service.HighlightCSS()))
+}
+
+// GetNoteFile serves the content of an image or file post. Files are sent
+// as downloads so that uploaded HTML or scripts never run on the site.
+func (h *APIHandler) GetNoteFile(c *gin.Context) {
+	content, contentType, name, err := h.service.GetNoteFile(c.Request.Context(), c.Param("noteId"))
+	if err != nil {
+		if abortIfCanceled(c, err) {
+			return
+		}
+		if _, ok := err.(*blog.BlogError); ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch file"})
+		return
+	}
+
+	disposition := "inline"
+	if !strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "image/svg") {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, contentType, content)
+}
+
 func (h *APIHandler) Sitemap(c *gin.Context) {
 	c.Header("Content-Type", "application/xml")
-	sitemap, err := h.service.GenerateSitemap()
+	sitemap, err := h.service.GenerateSitemapContext(c.Request.Context())
 	if err != nil {
 		c.String(http.StatusInternalServerError, `<?xml version="1.0" encoding="UTF-8"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"></urlset>`)
 		return
@@ -255,6 +507,7 @@ func (h *APIHandler) ImageProxy(c *gin.Context) {
 
 	host := strings.ToLower(parsedURL.Hostname())
 	if isBlockedHost(host) {
+		metrics.ObserveImageProxyFetch("blocked", 0)
 		c.JSON(http.StatusForbidden, gin.H{"error": "proxying this host is not allowed"})
 		return
 	}
@@ -275,7 +528,7 @@ func (h *APIHandler) ImageProxy(c *gin.Context) {
 		},
 	}
 
-	req, err := http.NewRequest("GET", rawURL, nil)
+	req, err := http.NewRequestWithContext(c.Request.Context(), "GET", rawURL, nil)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid url"})
 		return
@@ -284,30 +537,38 @@ func (h *APIHandler) ImageProxy(c *gin.Context) {
 
 	resp, err := client.Do(req)
 	if err != nil {
+		if abortIfCanceled(c, err) {
+			return
+		}
+		metrics.ObserveImageProxyFetch("error", 0)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch image"})
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		metrics.ObserveImageProxyFetch("upstream_status", 0)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "upstream returned non-200 status"})
 		return
 	}
 
 	contentType := resp.Header.Get("Content-Type")
 	if !strings.HasPrefix(contentType, "image/") {
+		metrics.ObserveImageProxyFetch("not_image", 0)
 		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "response is not an image"})
 		return
 	}
 
 	body, err := io.ReadAll(http.MaxBytesReader(nil, resp.Body, 10*1024*1024))
 	if err != nil {
+		metrics.ObserveImageProxyFetch("too_large", 0)
 		c.JSON(http.StatusBadGateway, gin.H{"error": "response too large or read error"})
 		return
 	}
 
 	c.Header("Content-Type", contentType)
 	c.Header("Cache-Control", "public, max-age=86400")
+	metrics.ObserveImageProxyFetch("ok", len(body))
 	c.Data(http.StatusOK, contentType, body)
 }
 
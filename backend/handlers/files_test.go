@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harveyTon/trilium-blog/backend/blog"
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestGetNoteFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	published := []etapi.Attribute{etapitest.Label("blog", "true")}
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{NoteID: "photo", Title: "Sunset", Type: "image", Mime: "image/png", Attributes: published}, "png")
+	fake.AddNote(etapi.Note{NoteID: "page", Title: "Page", Type: "file", Mime: "text/html", Attributes: published}, "<script></script>")
+	fake.AddNote(etapi.Note{NoteID: "private", Title: "Private", Type: "file", Mime: "text/plain"}, "secret")
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+
+	service := blog.NewService(etapi.NewClient(trilium.URL, "token"), &blog.NoopStore{})
+	r := gin.New()
+	r.GET("/api/files/:noteId", NewAPIHandler(service, "", "en").GetNoteFile)
+
+	tests := []struct {
+		noteID      string
+		status      int
+		disposition string
+	}{
+		{"photo", http.StatusOK, `inline; filename=Sunset`},
+		{"page", http.StatusOK, `attachment; filename=Page`},
+		{"private", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/files/"+tt.noteID, nil))
+		if w.Code != tt.status {
+			t.Fatalf("%s: expected %d, got %d", tt.noteID, tt.status, w.Code)
+		}
+		if got := w.Header().Get("Content-Disposition"); got != tt.disposition {
+			t.Errorf("%s: expected disposition %q, got %q", tt.noteID, tt.disposition, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"html"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harveyTon/trilium-blog/backend/blog"
+)
+
+var metaTagPatterns = map[string]*regexp.Regexp{
+	"description": regexp.MustCompile(`(?i)<meta\s+name="description"[^>]*>`),
+	"keywords":    regexp.MustCompile(`(?i)<meta\s+name="keywords"[^>]*>`),
+}
+
+// IndexPage serves the single-page app shell at indexPath. On post pages the
+// description and keywords of the post are written into the shell's head, so
+// crawlers that don't run JavaScript see them too.
+func (h *APIHandler) IndexPage(indexPath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		noteID, ok := strings.CutPrefix(c.Request.URL.Path, "/post/")
+		if !ok || noteID == "" || strings.Contains(noteID, "/") {
+			c.File(indexPath)
+			return
+		}
+		post, err := h.service.GetPostSEOContext(c.Request.Context(), noteID)
+		if err != nil {
+			c.File(indexPath)
+			return
+		}
+		page, err := os.ReadFile(indexPath)
+		if err != nil {
+			c.File(indexPath)
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(injectPostMeta(string(page), post)))
+	}
+}
+
+// injectPostMeta replaces the description and keywords meta tags of page
+// with the post's, adding them to the head when the page has none. Keywords
+// fall back to the tags, as on the client.
+func injectPostMeta(page string, post *blog.Post) string {
+	keywords := post.Keywords
+	if len(keywords) == 0 {
+		keywords = post.Tags
+	}
+	for _, meta := range []struct{ name, content string }{
+		{"description", post.Description},
+		{"keywords", strings.Join(keywords, ",")},
+	} {
+		if meta.content == "" {
+			continue
+		}
+		tag := `<meta name="` + meta.name + `" content="` + html.EscapeString(meta.content) + `">`
+		if pattern := metaTagPatterns[meta.name]; pattern.MatchString(page) {
+			page = pattern.ReplaceAllLiteralString(page, tag)
+		} else {
+			page = strings.Replace(page, "</head>", tag+"\n  </head>", 1)
+		}
+	}
+	return page
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harveyTon/trilium-blog/backend/blog"
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestIndexPageWritesPostMeta(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID: "post",
+		Title:  "Post",
+		Attributes: []etapi.Attribute{
+			etapitest.Label("blog", "true"),
+			etapitest.Label("tag", "go"),
+			etapitest.Label("description", `Channels & "select"`),
+		},
+	}, "<p>Body.</p>")
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+
+	indexPath := filepath.Join(t.TempDir(), "index.html")
+	shell := `<html><head><meta name="description" content="Site"><meta name="keywords" content="blog"></head><body></body></html>`
+	if err := os.WriteFile(indexPath, []byte(shell), 0o644); err != nil {
+		t.Fatalf("write index failed: %v", err)
+	}
+
+	service := blog.NewService(etapi.NewClient(trilium.URL, "token"), &blog.NoopStore{})
+	r := gin.New()
+	r.NoRoute(NewAPIHandler(service, "", "en").IndexPage(indexPath))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/post/post", nil))
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(body, `<meta name="description" content="Channels &amp; &#34;select&#34;">`) ||
+		!strings.Contains(body, `<meta name="keywords" content="go">`) || strings.Contains(body, `content="Site"`) {
+		t.Fatalf("expected the post's meta tags in the shell, got %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/post/missing", nil))
+	if w.Code != http.StatusOK || w.Body.String() != shell {
+		t.Fatalf("expected the plain shell for an unknown post, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harveyTon/trilium-blog/backend/blog"
+	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+)
+
+// maxRateLimitClients bounds the addresses tracked at once; past it, the
+// windows that already ended are dropped.
+const maxRateLimitClients = 10000
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// ipRateLimiter allows each client address limit requests per window.
+type ipRateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	clients map[string]*rateWindow
+	now     func() time.Time
+}
+
+// RateLimitPerIP returns a middleware that answers 429 once a client
+// address has made limit requests in the current window. A limit of 0 or
+// less disables it.
+func RateLimitPerIP(limit int, window time.Duration) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	l := &ipRateLimiter{limit: limit, window: window, clients: make(map[string]*rateWindow), now: time.Now}
+	return l.handle
+}
+
+// RateLimitSemanticSearch applies RateLimitPerIP to semantic and hybrid
+// searches, which embed each new query; keyword searches are not counted.
+func RateLimitSemanticSearch(limit int, window time.Duration) gin.HandlerFunc {
+	limited := RateLimitPerIP(limit, window)
+	return func(c *gin.Context) {
+		if blog.NormalizeSearchMode(c.Query("mode")) == blog.SearchModeKeyword {
+			c.Next()
+			return
+		}
+		limited(c)
+	}
+}
+
+// WarnUntrustedForwardedFor logs once when a request carries
+// X-Forwarded-For from a peer that is not a trusted proxy. Behind a proxy
+// missing from TRUSTED_PROXIES every reader has the proxy's address, so the
+// per-IP limits become one bucket shared by all of them.
+func WarnUntrustedForwardedFor() gin.HandlerFunc {
+	return warnUntrustedForwardedFor(func(remoteIP string) {
+		logger.Warnf("Ignoring X-Forwarded-For from untrusted peer %s; set TRUSTED_PROXIES to the proxy's address so rate limits apply per client", remoteIP)
+	})
+}
+
+func warnUntrustedForwardedFor(warn func(remoteIP string)) gin.HandlerFunc {
+	var once sync.Once
+	return func(c *gin.Context) {
+		if c.GetHeader("X-Forwarded-For") != "" && c.ClientIP() == c.RemoteIP() {
+			once.Do(func() { warn(c.RemoteIP()) })
+		}
+		c.Next()
+	}
+}
+
+func (l *ipRateLimiter) handle(c *gin.Context) {
+	if retry, ok := l.allow(c.ClientIP()); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(retry.Round(time.Second)/time.Second)))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+		return
+	}
+	c.Next()
+}
+
+// allow counts a request from ip and reports whether it is within the
+// limit, or how long until the window ends.
+func (l *ipRateLimiter) allow(ip string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	w, ok := l.clients[ip]
+	if !ok || now.Sub(w.start) >= l.window {
+		if !ok && len(l.clients) >= maxRateLimitClients {
+			l.prune(now)
+		}
+		l.clients[ip] = &rateWindow{start: now, count: 1}
+		return 0, true
+	}
+	if w.count >= l.limit {
+		return max(w.start.Add(l.window).Sub(now), time.Second), false
+	}
+	w.count++
+	return 0, true
+}
+
+func (l *ipRateLimiter) prune(now time.Time) {
+	for ip, w := range l.clients {
+		if now.Sub(w.start) >= l.window {
+			delete(l.clients, ip)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIPRateLimiter(t *testing.T) {
+	now := time.Date(2026, 4, 13, 12, 0, 0, 0, time.UTC)
+	l := &ipRateLimiter{limit: 2, window: time.Minute, clients: make(map[string]*rateWindow), now: func() time.Time { return now }}
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.allow("10.0.0.1"); !ok {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+	}
+	retry, ok := l.allow("10.0.0.1")
+	if ok || retry != time.Minute {
+		t.Fatalf("expected the third request to wait a minute, got %v %v", retry, ok)
+	}
+	if _, ok := l.allow("10.0.0.2"); !ok {
+		t.Fatalf("expected another address to have its own limit")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := l.allow("10.0.0.1"); !ok {
+		t.Fatalf("expected a new window to allow requests again")
+	}
+}
+
+func TestRateLimitPerIPMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/ask", RateLimitPerIP(1, time.Minute), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/ask", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected the first request through, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/ask", nil))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestRateLimitPerIPIgnoresForwardedForFromUntrustedClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatalf("set trusted proxies failed: %v", err)
+	}
+	r.POST("/api/ask", RateLimitPerIP(1, time.Minute), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for i, forwardedFor := range []string{"203.0.113.1", "203.0.113.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/ask", nil)
+		req.RemoteAddr = "198.51.100.7:4000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if want := []int{http.StatusNoContent, http.StatusTooManyRequests}[i]; w.Code != want {
+			t.Fatalf("request %d: expected %d, got %d", i+1, want, w.Code)
+		}
+	}
+}
+
+func TestWarnUntrustedForwardedForWarnsOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var warned []string
+	r := gin.New()
+	if err := r.SetTrustedProxies([]string{"10.0.0.1"}); err != nil {
+		t.Fatalf("set trusted proxies failed: %v", err)
+	}
+	r.Use(warnUntrustedForwardedFor(func(remoteIP string) { warned = append(warned, remoteIP) }))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, remoteAddr := range []string{"10.0.0.1:4000", "198.51.100.7:4000", "198.51.100.8:4000"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.1")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if len(warned) != 1 || warned[0] != "198.51.100.7" {
+		t.Fatalf("expected one warning for the first untrusted peer, got %v", warned)
+	}
+}
+
+func TestRateLimitSemanticSearchSkipsKeywordSearches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/search", RateLimitSemanticSearch(1, time.Minute), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for i, want := range []struct {
+		mode string
+		code int
+	}{
+		{"hybrid", http.StatusNoContent},
+		{"semantic", http.StatusTooManyRequests},
+		{"keyword", http.StatusNoContent},
+		{"", http.StatusNoContent},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search?q=go&mode="+want.mode, nil))
+		if w.Code != want.code {
+			t.Fatalf("request %d (mode %q): expected %d, got %d", i+1, want.mode, want.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harveyTon/trilium-blog/backend/blog"
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestGetRelatedPosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := etapitest.New()
+	for _, id := range []string{"first", "second"} {
+		fake.AddNote(etapi.Note{
+			NoteID:     id,
+			Title:      "Notes on " + id,
+			Attributes: []etapi.Attribute{etapitest.Label("blog", "true"), etapitest.Label("tag", "notes")},
+		}, "<p>Shared words about writing notes.</p>")
+	}
+	fake.AddNote(etapi.Note{NoteID: "draft", Title: "Draft"}, "<p>Not published.</p>")
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+
+	service := blog.NewService(etapi.NewClient(trilium.URL, "token"), &blog.NoopStore{})
+	r := gin.New()
+	r.GET("/api/posts/:noteId/related", NewAPIHandler(service, "", "en").GetRelatedPosts)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/posts/first/related?limit=3", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var related blog.RelatedPosts
+	if err := json.Unmarshal(w.Body.Bytes(), &related); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if related.Method != "overlap" || len(related.Items) != 1 || related.Items[0].NoteID != "second" {
+		t.Fatalf("unexpected related posts %#v", related)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/posts/draft/related", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a note that is not a post, got %d", w.Code)
+	}
+}
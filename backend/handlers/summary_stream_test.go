@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harveyTon/trilium-blog/backend/blog"
+	"github.com/harveyTon/trilium-blog/backend/etapi"
+	"github.com/harveyTon/trilium-blog/backend/etapi/etapitest"
+)
+
+func TestStreamPostSummaryPushesUntilReady(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:     "post",
+		Title:      "Post",
+		Attributes: []etapi.Attribute{etapitest.Label("blog", "true")},
+	}, "<p>A post that is long enough to have a code summary of its own.</p>")
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+
+	release := make(chan struct{})
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"streamed summary"}}]}`))
+	}))
+	defer ai.Close()
+
+	store, err := blog.NewSummaryStoreDB(filepath.Join(t.TempDir(), "summaries.db"))
+	if err != nil {
+		t.Fatalf("failed to create summary store: %v", err)
+	}
+	defer store.Close()
+	queue := blog.NewAISummaryQueue(store, "openai-compatible", ai.URL, "token", "model", "prompt", 1, 1, 5000, 2000,
+		blog.WithSummaryHub(blog.NewSummaryHub()))
+	defer queue.Close()
+	service := blog.NewService(etapi.NewClient(trilium.URL, "token"), &blog.NoopStore{},
+		blog.WithSummaryStore(store), blog.WithAISummaryQueue(queue), blog.WithAISummaryEnabled(true))
+
+	r := gin.New()
+	r.GET("/api/posts/:noteId/summary/stream", NewAPIHandler(service, "", "en").StreamPostSummary)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/posts/post/summary/stream")
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	done := make(chan []string, 1)
+	go func() {
+		var statuses []string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var summaries blog.Summaries
+			if err := json.Unmarshal([]byte(data), &summaries); err != nil {
+				t.Errorf("invalid event payload %q: %v", data, err)
+				break
+			}
+			statuses = append(statuses, summaries.AI.Status)
+			if len(statuses) == 1 {
+				close(release)
+			}
+		}
+		done <- statuses
+	}()
+
+	select {
+	case statuses := <-done:
+		if len(statuses) < 2 || statuses[len(statuses)-1] != "ready" {
+			t.Fatalf("expected the stream to end with ready, got %v", statuses)
+		}
+		if statuses[0] != "pending" && statuses[0] != "processing" {
+			t.Fatalf("expected the first event to be in progress, got %v", statuses)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("stream did not finish")
+	}
+}
+
+func TestStreamPostSummaryEndsImmediatelyWithoutAI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := etapitest.New()
+	fake.AddNote(etapi.Note{
+		NoteID:     "post",
+		Title:      "Post",
+		Attributes: []etapi.Attribute{etapitest.Label("blog", "true")},
+	}, "<p>Plain post.</p>")
+	trilium := etapitest.NewServer(fake)
+	defer trilium.Close()
+
+	h := NewAPIHandler(blog.NewService(etapi.NewClient(trilium.URL, "token"), &blog.NoopStore{}), "", "en")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "noteId", Value: "post"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/posts/post/summary/stream", nil)
+
+	h.StreamPostSummary(c)
+
+	if w.Code != http.StatusOK || strings.Count(w.Body.String(), "event:summary") != 1 {
+		t.Fatalf("expected a single snapshot event, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 	"github.com/harveyTon/trilium-blog/backend/etapi"
 	"github.com/harveyTon/trilium-blog/backend/handlers"
 	"github.com/harveyTon/trilium-blog/backend/pkg/logger"
+	"github.com/harveyTon/trilium-blog/backend/pkg/metrics"
 )
 
 const (
@@ -25,27 +27,37 @@ const (
 	customAssetsDir        = "./custom"
 )
 
-func healthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
-}
-
 func setupRouter(apiHandler *handlers.APIHandler, staticDir string) *gin.Engine {
 	gin.DisableConsoleColor()
 	r := gin.Default()
+	if err := r.SetTrustedProxies(config.Config.TrustedProxies); err != nil {
+		logger.Error("Invalid TRUSTED_PROXIES, trusting no proxy", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+	r.Use(handlers.WarnUntrustedForwardedFor())
 	r.Use(logger.GinLogger())
 	r.Use(gin.Recovery())
+	if config.Config.Metrics.Enabled {
+		r.Use(metrics.GinMiddleware())
+	}
 
 	api := r.Group("/api")
 	{
 		api.GET("/site", apiHandler.GetSite)
 		api.GET("/posts", apiHandler.ListPosts)
 		api.GET("/posts/featured", apiHandler.ListFeaturedPosts)
-		api.GET("/search", apiHandler.SearchPosts)
+		api.GET("/search", handlers.RateLimitSemanticSearch(config.Config.AISummary.SearchRateLimit, time.Minute), apiHandler.SearchPosts)
 		api.GET("/posts/:noteId", apiHandler.GetPost)
 		api.GET("/posts/:noteId/summary", apiHandler.GetPostSummary)
+		api.GET("/posts/:noteId/summary/stream", apiHandler.StreamPostSummary)
+		api.GET("/posts/:noteId/related", apiHandler.GetRelatedPosts)
+		api.GET("/posts/:noteId/backlinks", apiHandler.GetBacklinks)
+		api.POST("/ask", handlers.RateLimitPerIP(config.Config.AISummary.AskRateLimit, time.Minute), apiHandler.Ask)
+		api.GET("/highlight.css", apiHandler.HighlightCSS)
 		api.GET("/assets/:attachmentId", apiHandler.GetAsset)
+		api.GET("/files/:noteId", apiHandler.GetNoteFile)
 		api.GET("/imageproxy", apiHandler.ImageProxy)
-		api.GET("/health", healthCheck)
+		api.GET("/health", apiHandler.Health)
 	}
 	admin := r.Group("/api/admin")
 	admin.Use(apiHandler.AdminAuthMiddleware)
@@ -53,10 +65,18 @@ func setupRouter(apiHandler *handlers.APIHandler, staticDir string) *gin.Engine
 		admin.GET("/cache/stats", apiHandler.CacheStats)
 		admin.POST("/cache/invalidate", apiHandler.InvalidateCache)
 		admin.POST("/cache/preload", apiHandler.TriggerPreload)
+		admin.GET("/summaries", apiHandler.ListSummaries)
+		admin.GET("/summaries/usage", apiHandler.SummaryUsage)
+		admin.POST("/summaries/regenerate", apiHandler.RegenerateSummaries)
+		admin.POST("/summaries/clear", apiHandler.ClearSummary)
+		admin.POST("/summaries/pin", apiHandler.PinSummary)
 	}
 	if config.Config.AdminToken != "" {
 		r.GET("/admin", apiHandler.AdminPage)
 	}
+	if config.Config.Metrics.Enabled && config.Config.Metrics.Addr == "" {
+		r.GET("/metrics", apiHandler.AdminAuthMiddleware, gin.WrapH(metrics.Handler()))
+	}
 	r.GET("/sitemap.xml", apiHandler.Sitemap)
 	r.GET("/robots.txt", apiHandler.Robots)
 
@@ -64,9 +84,10 @@ func setupRouter(apiHandler *handlers.APIHandler, staticDir string) *gin.Engine
 	r.StaticFile("/favicon.ico", resolveStaticFile(staticDir, "favicon.ico"))
 	r.StaticFile("/logo.png", resolveStaticFile(staticDir, "logo.png"))
 
+	indexPage := apiHandler.IndexPage(filepath.Join(staticDir, "index.html"))
 	r.NoRoute(func(c *gin.Context) {
-		if !strings.HasPrefix(c.Request.URL.Path, "/api") && c.Request.URL.Path != "/sitemap.xml" && c.Request.URL.Path != "/robots.txt" {
-			c.File(filepath.Join(staticDir, "index.html"))
+		if !strings.HasPrefix(c.Request.URL.Path, "/api") && c.Request.URL.Path != "/sitemap.xml" && c.Request.URL.Path != "/robots.txt" && c.Request.URL.Path != "/metrics" {
+			indexPage(c)
 		} else {
 			c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
 		}
@@ -75,6 +96,17 @@ func setupRouter(apiHandler *handlers.APIHandler, staticDir string) *gin.Engine
 	return r
 }
 
+// serveMetrics exposes /metrics on its own listener so it can be bound to an
+// internal interface without requiring the admin token.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	logger.Info(fmt.Sprintf("Metrics server started on %s", addr))
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.Error("Metrics server stopped", err)
+	}
+}
+
 func resolveFrontendDist() string {
 	paths := []string{
 		"./frontend/dist",
@@ -108,6 +140,12 @@ func runStartupChecks(etapiClient *etapi.Client, dir, staticDir string) {
 	logger.Info("========== Startup Checks ==========")
 
 	logger.Info(fmt.Sprintf("[Config] TRILIUM_API_URL = %s", config.Config.TriliumApiUrl))
+	logger.Info(fmt.Sprintf("[Config] TRILIUM_RETRY = max=%d, base=%dms, cap=%dms, breaker_threshold=%d, breaker_cooldown=%dms",
+		config.Config.TriliumRetry.MaxRetries,
+		config.Config.TriliumRetry.BaseDelayMs,
+		config.Config.TriliumRetry.MaxDelayMs,
+		config.Config.TriliumRetry.BreakerThreshold,
+		config.Config.TriliumRetry.BreakerCooldownMs))
 	logger.Info(fmt.Sprintf("[Config] BLOG_TITLE = %q", config.Config.BlogTitle))
 	logger.Info(fmt.Sprintf("[Config] BLOG_SUBTITLE = %q", config.Config.BlogSubtitle))
 	logger.Info(fmt.Sprintf("[Config] DOMAIN = %s", config.Config.Domain))
@@ -115,7 +153,20 @@ func runStartupChecks(etapiClient *etapi.Client, dir, staticDir string) {
 	logger.Info(fmt.Sprintf("[Config] ARTICLES_PER_PAGE = %d", config.Config.ArticlesPerPage))
 	logger.Info(fmt.Sprintf("[Config] ADMIN_TOKEN = %s", boolStr(config.Config.AdminToken != "")))
 	logger.Info(fmt.Sprintf("[Config] IMAGE_PROXY = enabled=%v, base_url=%s", config.Config.ImageProxy.Enabled, config.Config.ImageProxy.BaseURL))
-	logger.Info(fmt.Sprintf("[Config] AI_SUMMARY = enabled=%v, mode=%s, provider=%s", config.Config.AISummary.Enabled, config.Config.AISummary.Mode, config.Config.AISummary.Provider))
+	logger.Info(fmt.Sprintf("[Config] CODE_HIGHLIGHT = server=%v, theme=%s, dark_theme=%s",
+		config.Config.CodeHighlight.Server, config.Config.CodeHighlight.Theme, config.Config.CodeHighlight.DarkTheme))
+	for _, theme := range []string{config.Config.CodeHighlight.Theme, config.Config.CodeHighlight.DarkTheme} {
+		if !blog.ValidHighlightTheme(theme) {
+			logger.Warn(fmt.Sprintf("[Config] Unknown Chroma style %q; the default theme is used instead", theme))
+		}
+	}
+	logger.Info(fmt.Sprintf("[Config] AI_SUMMARY = enabled=%v, mode=%s, provider=%s, max_attempts=%d, monthly_budget=%g, long_doc=%v",
+		config.Config.AISummary.Enabled, config.Config.AISummary.Mode, config.Config.AISummary.Provider, config.Config.AISummary.MaxAttempts,
+		config.Config.AISummary.MonthlyBudget, config.Config.AISummary.LongDocument))
+	logger.Info(fmt.Sprintf("[Config] METRICS = enabled=%v, addr=%s", config.Config.Metrics.Enabled, config.Config.Metrics.Addr))
+	if config.Config.Metrics.Enabled && config.Config.Metrics.Addr == "" && config.Config.AdminToken == "" {
+		logger.Warn("[Config] METRICS_ENABLED is set without METRICS_ADDR or ADMIN_TOKEN; /metrics will reject every request")
+	}
 
 	logger.Info(fmt.Sprintf("[Data] Data directory: %s", dir))
 	if info, err := os.Stat(dir); err != nil {
@@ -156,6 +207,11 @@ func runStartupChecks(etapiClient *etapi.Client, dir, staticDir string) {
 		if len(notes) == 0 {
 			logger.Warn("[Trilium] No notes with #blog=true label found. Make sure notes are tagged in Trilium.")
 		}
+		if info, err := etapiClient.GetAppInfo(context.Background()); err != nil {
+			logger.Warn(fmt.Sprintf("[Trilium] Could not read app info: %v", err))
+		} else {
+			logger.Info(fmt.Sprintf("[Trilium] Version %s (db=%d, sync=%d)", info.AppVersion, info.DBVersion, info.SyncVersion))
+		}
 	}
 
 	logger.Info(fmt.Sprintf("[AI Summary] provider=%s base_url=%s model=%s",
@@ -163,15 +219,22 @@ func runStartupChecks(etapiClient *etapi.Client, dir, staticDir string) {
 		maskSecret(config.Config.AISummary.BaseURL),
 		config.Config.AISummary.Model))
 	if config.Config.AISummary.AIRequestsEnabled() {
-		if config.Config.AISummary.BaseURL == "" {
+		// Only the openai-compatible provider has no default endpoint, and
+		// a local Ollama needs no key.
+		provider := config.Config.AISummary.Provider
+		if config.Config.AISummary.BaseURL == "" && provider == blog.AIProviderOpenAICompatible {
 			logger.Warn("[AI Summary] Mode is 'ai' but AI_SUMMARY_BASE_URL is empty")
 		}
-		if config.Config.AISummary.APIKey == "" {
+		if config.Config.AISummary.APIKey == "" && provider != blog.AIProviderOllama {
 			logger.Warn("[AI Summary] Mode is 'ai' but AI_SUMMARY_API_KEY is empty")
 		}
 		if config.Config.AISummary.Model == "" {
 			logger.Warn("[AI Summary] Mode is 'ai' but AI_SUMMARY_MODEL is empty")
 		}
+		if config.Config.AISummary.MonthlyBudget > 0 &&
+			config.Config.AISummary.InputPricePerMTok == 0 && config.Config.AISummary.OutputPricePerMTok == 0 {
+			logger.Warn("[AI Summary] AI_SUMMARY_MONTHLY_BUDGET is set but no token prices are; the budget will never be reached")
+		}
 	}
 
 	logger.Info("========== Startup Checks Complete ==========")
@@ -184,6 +247,28 @@ func boolStr(v bool) string {
 	return "not set"
 }
 
+// embeddingOption configures the embedder for related posts, or nothing when
+// embeddings are disabled or misconfigured.
+func embeddingOption(store *blog.EmbeddingStoreDB) blog.AISummaryQueueOption {
+	if store == nil {
+		return blog.WithAIEmbeddings(nil, nil)
+	}
+	client := &http.Client{Timeout: time.Duration(config.Config.AISummary.TimeoutMs) * time.Millisecond}
+	embedder, err := blog.NewOpenAIEmbedder(
+		config.Config.AISummary.EmbeddingBaseURL,
+		config.Config.AISummary.EmbeddingAPIKey,
+		config.Config.AISummary.EmbeddingModel,
+		client,
+	)
+	if err != nil {
+		logger.Error("AI embeddings unavailable; related posts fall back to tag and term overlap", err)
+		return blog.WithAIEmbeddings(nil, nil)
+	}
+	logger.Info(fmt.Sprintf("[AI Embedding] base_url=%s model=%s",
+		maskSecret(config.Config.AISummary.EmbeddingBaseURL), config.Config.AISummary.EmbeddingModel))
+	return blog.WithAIEmbeddings(embedder, store)
+}
+
 func maskSecret(s string) string {
 	if s == "" {
 		return "(empty)"
@@ -202,7 +287,17 @@ func main() {
 		logger.Error("Failed to create data directory", err)
 	}
 
-	etapiClient := etapi.NewClient(config.Config.TriliumApiUrl, config.Config.TriliumToken)
+	retry := config.Config.TriliumRetry
+	etapiClient := etapi.NewClient(
+		config.Config.TriliumApiUrl,
+		config.Config.TriliumToken,
+		etapi.WithRetry(
+			retry.MaxRetries,
+			time.Duration(retry.BaseDelayMs)*time.Millisecond,
+			time.Duration(retry.MaxDelayMs)*time.Millisecond,
+		),
+		etapi.WithCircuitBreaker(retry.BreakerThreshold, time.Duration(retry.BreakerCooldownMs)*time.Millisecond),
+	)
 	var err error
 
 	cacheStore := blog.Store(&blog.NoopStore{})
@@ -230,9 +325,33 @@ func main() {
 		defer summaryStore.Close()
 	}
 
+	summaryPrompts, err := blog.NewSummaryPrompts(
+		config.Config.Locale,
+		config.Config.AISummary.TargetLength,
+		config.Config.AISummary.Prompt,
+		map[string]string{"zh-CN": config.Config.AISummary.PromptZhCN, "en": config.Config.AISummary.PromptEn},
+	)
+	if err != nil {
+		logger.Fatal("Invalid AI summary prompt template", err)
+	}
+
+	var embeddingStore *blog.EmbeddingStoreDB
+	if config.Config.AISummary.EmbeddingModel != "" && config.Config.AISummary.AIRequestsEnabled() {
+		embeddingStore, err = blog.NewEmbeddingStoreDB(filepath.Join(dir, "embeddings.db"))
+		if err != nil {
+			logger.Error("Failed to initialize embedding store; related posts fall back to tag and term overlap", err)
+		} else {
+			defer embeddingStore.Close()
+		}
+	}
+
 	var aiQueue *blog.AISummaryQueue
 	aiSummaryEnabled := summaryStore != nil && config.Config.AISummary.AIRequestsEnabled()
 	if aiSummaryEnabled {
+		maxChunks := 0
+		if config.Config.AISummary.LongDocument {
+			maxChunks = config.Config.AISummary.MaxChunks
+		}
 		aiQueue = blog.NewAISummaryQueue(
 			summaryStore,
 			config.Config.AISummary.Provider,
@@ -244,6 +363,16 @@ func main() {
 			config.Config.AISummary.RateLimitMs,
 			config.Config.AISummary.TimeoutMs,
 			config.Config.AISummary.MaxInputChars,
+			blog.WithAIRetryPolicy(
+				config.Config.AISummary.MaxAttempts,
+				time.Duration(config.Config.AISummary.RetryBaseMs)*time.Millisecond,
+				time.Duration(config.Config.AISummary.RetryMaxMs)*time.Millisecond,
+			),
+			blog.WithSummaryHub(blog.NewSummaryHub()),
+			blog.WithAIPricing(config.Config.AISummary.InputPricePerMTok, config.Config.AISummary.OutputPricePerMTok),
+			blog.WithAIMonthlyBudget(config.Config.AISummary.MonthlyBudget),
+			blog.WithAILongDocument(maxChunks),
+			embeddingOption(embeddingStore),
 		)
 	}
 
@@ -257,9 +386,16 @@ func main() {
 		blog.WithPageSize(config.Config.ArticlesPerPage),
 		blog.WithImageProxyEnabled(config.Config.ImageProxy.Enabled),
 		blog.WithImageProxyBaseUrl(config.Config.ImageProxy.BaseURL),
+		blog.WithServerHighlight(config.Config.CodeHighlight.Server),
+		blog.WithHighlightThemes(config.Config.CodeHighlight.Theme, config.Config.CodeHighlight.DarkTheme),
 		blog.WithSummaryStore(summaryStore),
 		blog.WithAISummaryQueue(aiQueue),
+		blog.WithSummaryPrompts(summaryPrompts),
 		blog.WithAISummaryEnabled(aiSummaryEnabled),
+		blog.WithAISEOEnabled(config.Config.AISummary.SEO),
+		blog.WithAITranslationEnabled(config.Config.AISummary.Translation),
+		blog.WithAIAskEnabled(config.Config.AISummary.Ask),
+		blog.WithSemanticSearchMinScore(config.Config.AISummary.EmbeddingMinScore),
 	)
 
 	staticDir := resolveFrontendDist()
@@ -268,6 +404,10 @@ func main() {
 	apiHandler := handlers.NewAPIHandler(service, config.Config.AdminToken, config.Config.Locale)
 	r := setupRouter(apiHandler, staticDir)
 
+	if config.Config.Metrics.Enabled && config.Config.Metrics.Addr != "" {
+		go serveMetrics(config.Config.Metrics.Addr)
+	}
+
 	go service.Preload()
 
 	defaultPort := os.Getenv("PORT")
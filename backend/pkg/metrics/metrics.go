@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "trilium_blog"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by Gin route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by Gin route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	etapiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "etapi_requests_total",
+		Help:      "Trilium ETAPI calls, by endpoint and status (HTTP code or \"error\").",
+	}, []string{"endpoint", "status"})
+
+	etapiDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "etapi_request_duration_seconds",
+		Help:      "Trilium ETAPI call latency, by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	etapiBreakerState = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "etapi_circuit_breaker_state",
+		Help:      "ETAPI circuit breaker state: 0 closed, 1 half-open, 2 open.",
+	})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Cache lookups, by cache policy and result (hit or miss).",
+	}, []string{"policy", "result"})
+
+	aiQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ai_summary_queue_depth",
+		Help:      "AI summary jobs waiting for a worker (persisted queue).",
+	})
+
+	aiInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ai_summary_in_flight",
+		Help:      "AI summary jobs currently being generated.",
+	})
+
+	aiJobs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_summary_jobs_total",
+		Help:      "Finished AI summary jobs, by result (ready or failed).",
+	}, []string{"result"})
+
+	aiDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_summary_duration_seconds",
+		Help:      "AI summary generation latency.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	})
+
+	aiTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_summary_tokens_total",
+		Help:      "Tokens reported by the AI provider, by direction (input or output).",
+	}, []string{"direction"})
+
+	aiCost = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_summary_cost_total",
+		Help:      "Estimated cost of AI summary calls, in the configured price unit.",
+	})
+
+	imageProxyFetches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "image_proxy_fetches_total",
+		Help:      "Image proxy fetches, by result.",
+	}, []string{"result"})
+
+	imageProxyBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "image_proxy_bytes_total",
+		Help:      "Bytes served by the image proxy.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		etapiRequests,
+		etapiDuration,
+		etapiBreakerState,
+		cacheLookups,
+		aiQueueDepth,
+		aiInFlight,
+		aiJobs,
+		aiDuration,
+		aiTokens,
+		aiCost,
+		imageProxyFetches,
+		imageProxyBytes,
+	)
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// GinMiddleware records request counts and latencies per matched Gin route.
+// Unmatched requests are grouped under a single "unmatched" route so that
+// arbitrary paths cannot blow up label cardinality.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(route, method, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveETAPIRequest records one ETAPI call. A zero statusCode means the
+// request failed before a response was received.
+func ObserveETAPIRequest(endpoint string, statusCode int, elapsed time.Duration) {
+	status := "error"
+	if statusCode > 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	etapiRequests.WithLabelValues(endpoint, status).Inc()
+	etapiDuration.WithLabelValues(endpoint).Observe(elapsed.Seconds())
+}
+
+func SetETAPIBreakerState(state string) {
+	switch state {
+	case "open":
+		etapiBreakerState.Set(2)
+	case "half-open":
+		etapiBreakerState.Set(1)
+	default:
+		etapiBreakerState.Set(0)
+	}
+}
+
+func ObserveCacheLookup(policy string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(policy, result).Inc()
+}
+
+func SetAIQueueDepth(n int) { aiQueueDepth.Set(float64(n)) }
+func AIInFlightInc()        { aiInFlight.Inc() }
+func AIInFlightDec()        { aiInFlight.Dec() }
+
+func ObserveAIJob(failed bool, elapsed time.Duration) {
+	result := "ready"
+	if failed {
+		result = "failed"
+	}
+	aiJobs.WithLabelValues(result).Inc()
+	aiDuration.Observe(elapsed.Seconds())
+}
+
+func ObserveAIUsage(inputTokens, outputTokens int, cost float64) {
+	aiTokens.WithLabelValues("input").Add(float64(inputTokens))
+	aiTokens.WithLabelValues("output").Add(float64(outputTokens))
+	if cost > 0 {
+		aiCost.Add(cost)
+	}
+}
+
+func ObserveImageProxyFetch(result string, bytes int) {
+	imageProxyFetches.WithLabelValues(result).Inc()
+	if bytes > 0 {
+		imageProxyBytes.Add(float64(bytes))
+	}
+}
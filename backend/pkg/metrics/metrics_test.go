@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGinMiddlewareLabelsByRoutePattern(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/api/posts/:noteId", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, id := range []string{"a", "b"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/posts/"+id, nil))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/nope", nil))
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("/api/posts/:noteId", "GET", "200")); got != 2 {
+		t.Fatalf("expected 2 requests for route pattern, got %v", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("unmatched", "GET", "404")); got != 1 {
+		t.Fatalf("expected unmatched request to be grouped, got %v", got)
+	}
+}
+
+func TestHandlerExposesRecordedMetrics(t *testing.T) {
+	ObserveETAPIRequest("notes.get", 0, 10*time.Millisecond)
+	ObserveCacheLookup("note", true)
+	ObserveImageProxyFetch("ok", 128)
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`trilium_blog_etapi_requests_total{endpoint="notes.get",status="error"} 1`,
+		`trilium_blog_cache_lookups_total{policy="note",result="hit"} 1`,
+		`trilium_blog_image_proxy_bytes_total 128`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected metrics output to contain %q", want)
+		}
+	}
+}
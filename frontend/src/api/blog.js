@@ -30,8 +30,27 @@ export async function fetchFeaturedPosts() {
   return response.data;
 }
 
-export async function fetchPost(noteId) {
-  const response = await api.get(`/posts/${noteId}`);
+export async function fetchPost(noteId, lang = "") {
+  const response = await api.get(`/posts/${noteId}`, {
+    params: lang ? { lang } : {},
+  });
+  return response.data;
+}
+
+export async function askQuestion(question) {
+  const response = await api.post("/ask", { question }, { timeout: 90000 });
+  return response.data;
+}
+
+export async function fetchRelatedPosts(noteId, limit = 5) {
+  const response = await api.get(`/posts/${noteId}/related`, {
+    params: { limit },
+  });
+  return response.data;
+}
+
+export async function fetchBacklinks(noteId) {
+  const response = await api.get(`/posts/${noteId}/backlinks`);
   return response.data;
 }
 
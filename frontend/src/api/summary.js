@@ -5,6 +5,32 @@ export async function fetchPostSummary(noteId) {
   return response.data;
 }
 
+// openSummaryStream follows summary status changes over Server-Sent Events.
+// It returns null when the browser has no EventSource, so callers can fall
+// back to polling, and calls onError once the stream gives up for good. The
+// returned source must be closed by the caller.
+export function openSummaryStream(noteId, { onSummary, onError } = {}) {
+  if (typeof window === "undefined" || typeof window.EventSource !== "function") {
+    return null;
+  }
+  const source = new window.EventSource(`/api/posts/${noteId}/summary/stream`);
+  source.addEventListener("summary", (event) => {
+    try {
+      onSummary?.(JSON.parse(event.data));
+    } catch (error) {
+      console.error("Failed to parse summary event:", error);
+    }
+  });
+  source.onerror = (event) => {
+    // While CONNECTING the browser retries on its own (for example after the
+    // server ends a long-lived stream); only a closed source is a failure.
+    if (source.readyState === window.EventSource.CLOSED) {
+      onError?.(event);
+    }
+  };
+  return source;
+}
+
 export function normalizeSummaryPayload(post) {
   if (!post) {
     return {
@@ -47,6 +47,17 @@ function fallbackCodeBlockMeta(preElement, index) {
   };
 }
 
+// codeText returns the code of a block without the line numbers a
+// server-highlighted block carries.
+function codeText(preElement, codeElement) {
+  if (!preElement.classList.contains("chroma")) {
+    return codeElement.textContent ?? "";
+  }
+  const copy = codeElement.cloneNode(true);
+  copy.querySelectorAll(".ln").forEach((lineNumber) => lineNumber.remove());
+  return copy.textContent ?? "";
+}
+
 function setupGallery(root) {
   root.querySelectorAll("img").forEach((img) => {
     img.loading = "lazy";
@@ -103,18 +114,27 @@ export function useArticleEnhancements() {
       validBlocks.push({ preElement, codeElement, meta });
     });
 
-    const languageIds = validBlocks.map((b) => normalizeLanguageId(b.meta.languageId));
+    const languageIds = validBlocks
+      .filter((b) => !b.meta.highlighted)
+      .map((b) => normalizeLanguageId(b.meta.languageId));
     await preloadLanguages(languageIds);
 
     validBlocks.forEach(({ preElement, codeElement, meta }) => {
       const mountPoint = document.createElement("div");
       mountPoint.className = "article-code-block-host";
 
+      const highlighted = Boolean(meta.highlighted) && preElement.classList.contains("chroma");
       const app = createApp(ArticleCodeBlock, {
-        code: codeElement.textContent ?? "",
+        code: codeText(preElement, codeElement),
         languageId: normalizeLanguageId(meta.languageId),
         languageLabel: meta.languageLabel || friendlyLabelFromId(meta.languageId),
-        showLineNumbers: Boolean(meta.showLineNumbers),
+        // Server-highlighted blocks carry their own line numbers.
+        showLineNumbers: !highlighted && Boolean(meta.showLineNumbers),
+        highlightedHtml: highlighted ? preElement.outerHTML : "",
+        title: meta.title || "",
+        highlightLines: meta.highlightLines || [],
+        startLine: meta.startLine || 1,
+        collapsed: Boolean(meta.collapsed),
       });
 
       preElement.replaceWith(mountPoint);
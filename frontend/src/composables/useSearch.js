@@ -25,7 +25,14 @@ export function useSearch() {
     loading.value = true;
     error.value = "";
     try {
-      result.value = await fetchSearchResults(query.value);
+      // The server falls back to keywords when semantic search is unavailable.
+      result.value = await fetchSearchResults(query.value, { mode: "hybrid" }).catch((err) => {
+        // Searches by meaning are rate limited per client; keywords are not.
+        if (err.response?.status === 429) {
+          return fetchSearchResults(query.value, { mode: "keyword" });
+        }
+        throw err;
+      });
     } catch (err) {
       console.error("Fetch Search Results Error:", err);
       error.value = t('search.fetchError');
@@ -69,6 +69,7 @@ export default {
   summary: {
     readyHint: "Ready for quick reading",
     generatingHint: "Generating...",
+    progressHint: "Generating ({done}/{total})...",
     unavailableHint: "Temporarily unavailable",
     aiPending: "AI summary is being generated and will update automatically.",
     aiFailed: "AI summary generation failed. Will retry automatically later.",
@@ -76,6 +77,35 @@ export default {
   sourceLink: {
     label: "Clipped from: ",
   },
+  code: {
+    expand: "Show code",
+    collapse: "Hide code",
+  },
+  related: {
+    title: "Related posts",
+    backlinks: "Linked from",
+  },
+  ask: {
+    title: "Ask this blog",
+    action: "Ask",
+    asking: "Thinking…",
+    hint: "Answered by AI from the posts above; check the sources.",
+    failed: "The question could not be answered right now.",
+    rateLimited: "Too many questions; please wait a minute.",
+  },
+  translation: {
+    notice: "Machine translated from {lang}; it may contain mistakes.",
+    pending: "Translating this post…",
+    progress: "Translating this post ({done}/{total})…",
+    unavailable: "A translation is not available right now.",
+    offer: "This post can be machine translated.",
+    readIn: "Read in {lang}",
+    showOriginal: "Show original",
+    languages: {
+      en: "English",
+      "zh-CN": "Chinese",
+    },
+  },
   header: {
     searchAria: "Open search",
     logoAlt: " - Back to home",
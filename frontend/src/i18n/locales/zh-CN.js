@@ -69,6 +69,7 @@ export default {
   summary: {
     readyHint: "已生成，可快速浏览文章核心内容",
     generatingHint: "正在异步生成中",
+    progressHint: "正在分段生成（{done}/{total}）",
     unavailableHint: "暂时不可用",
     aiPending: "正在生成更自然的 AI 摘要，生成完成后会自动更新。",
     aiFailed: "AI 摘要暂时生成失败，稍后会自动重试。",
@@ -76,6 +77,35 @@ export default {
   sourceLink: {
     label: "剪贴自：",
   },
+  code: {
+    expand: "展开代码",
+    collapse: "收起代码",
+  },
+  related: {
+    title: "相关文章",
+    backlinks: "引用本文的文章",
+  },
+  ask: {
+    title: "向博客提问",
+    action: "提问",
+    asking: "思考中…",
+    hint: "由 AI 依据博客文章回答，请以原文为准。",
+    failed: "暂时无法回答这个问题。",
+    rateLimited: "提问过于频繁，请稍后再试。",
+  },
+  translation: {
+    notice: "本文由{lang}机器翻译，可能存在错误。",
+    pending: "正在翻译本文…",
+    progress: "正在分段翻译本文（{done}/{total}）…",
+    unavailable: "暂时无法提供译文。",
+    offer: "本文可提供机器翻译。",
+    readIn: "阅读{lang}版",
+    showOriginal: "查看原文",
+    languages: {
+      en: "英文",
+      "zh-CN": "中文",
+    },
+  },
   header: {
     searchAria: "打开搜索",
     logoAlt: " - 返回首页",
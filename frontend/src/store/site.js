@@ -2,6 +2,15 @@ import { defineStore } from "pinia";
 import { fetchSite } from "../api/blog";
 import { setLocale } from "../i18n";
 
+function useHighlightStylesheet(href) {
+  if (!href || document.getElementById("highlight-css")) return;
+  const link = document.createElement("link");
+  link.id = "highlight-css";
+  link.rel = "stylesheet";
+  link.href = href;
+  document.head.appendChild(link);
+}
+
 export const useSiteStore = defineStore("site", {
   state: () => ({
     site: {
@@ -13,6 +22,9 @@ export const useSiteStore = defineStore("site", {
         enabled: false,
         baseUrl: "",
       },
+      translation: false,
+      ask: false,
+      highlightCss: "",
     },
     loaded: false,
   }),
@@ -24,6 +36,7 @@ export const useSiteStore = defineStore("site", {
         if (data.locale) {
           setLocale(data.locale);
         }
+        useHighlightStylesheet(data.highlightCss);
         document.title = [data.title, data.subtitle].filter(Boolean).join(" | ");
         this.loaded = true;
       } catch (error) {